@@ -4,5 +4,5 @@ go 1.21
 
 require github.com/rh-ai-kickstart/ai-architecture-charts/mcp-servers/mcpproxy v0.0.0-20260112200911-3c502cb8d0cf
 
-// For local development, uncomment the replace directive below
-// replace github.com/rh-ai-kickstart/ai-architecture-charts/mcp-servers/mcpproxy => ../../mcpproxy
+// mcpproxy is built from this repository; build images with mcp-servers/ as the context
+replace github.com/rh-ai-kickstart/ai-architecture-charts/mcp-servers/mcpproxy => ../../mcpproxy
@@ -1,19 +1,137 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
 
 	"github.com/rh-ai-kickstart/ai-architecture-charts/mcp-servers/mcpproxy"
 )
 
 func main() {
-	if err := mcpproxy.Run(mcpproxy.Config{
+	if err := mcpproxy.Run(newConfig()); err != nil {
+		log.Fatalf("Failed to run proxy: %v", err)
+	}
+}
+
+// newConfig builds the proxy configuration from the GitHub-specific
+// environment. Invalid settings are added to cfg.Problems, so mcpproxy
+// reports them together with its own.
+func newConfig() mcpproxy.Config {
+	cfg := mcpproxy.Config{
 		ServerName:  "github-mcp",
 		CommandPath: "/server/github-mcp-server",
 		CommandArgs: []string{"stdio"},
 		PathEnvVar:  "GITHUB_MCP_PATH",
 		EnableCORS:  true,
-	}); err != nil {
-		log.Fatalf("Failed to run proxy: %v", err)
+
+		ResponseMiddlewares: []mcpproxy.ResponseMiddleware{annotateRateLimit},
+	}
+
+	// Point github-mcp-server at a GitHub Enterprise Server instance
+	host, err := githubHost()
+	if err != nil {
+		problem(&cfg, fmt.Errorf("invalid GitHub host: %w", err), "set GITHUB_HOST to the server's URL, e.g. https://github.example.com")
+	}
+	if host != "" {
+		mcpproxy.Warnf("Using GitHub host: %s", host)
+		cfg.ExtraEnv = append(cfg.ExtraEnv, "GITHUB_HOST="+host)
 	}
+
+	// Restrict what the server can do without rebuilding the image
+	if envBool(&cfg, "GITHUB_READ_ONLY") {
+		cfg.CommandArgs = append(cfg.CommandArgs, "--read-only")
+	}
+	if toolsets := strings.ReplaceAll(os.Getenv("GITHUB_TOOLSETS"), " ", ""); toolsets != "" {
+		cfg.CommandArgs = append(cfg.CommandArgs, "--toolsets", toolsets)
+	}
+
+	// Wait out secondary rate limits instead of failing the request
+	if envBool(&cfg, "GITHUB_AUTO_RETRY") {
+		cfg.ResponseRetry = retrySecondaryRateLimit
+		if v := os.Getenv("GITHUB_AUTO_RETRY_MAX"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				problem(&cfg, fmt.Errorf("invalid GITHUB_AUTO_RETRY_MAX %q: must be a positive integer", v), "set it to the retries per request, e.g. 3")
+			} else {
+				cfg.MaxResponseRetries = n
+			}
+		}
+	}
+
+	// Run a github-mcp-server per client token so each client acts as itself
+	if envBool(&cfg, "GITHUB_TOKEN_FROM_HEADER") {
+		cfg.SessionFunc = tokenSession
+	}
+
+	return cfg
+}
+
+// problem adds a configuration problem to cfg.Problems.
+func problem(cfg *mcpproxy.Config, err error, hint string) {
+	cfg.Problems = append(cfg.Problems, mcpproxy.Problem{Err: err, Hint: hint})
+}
+
+// envBool reports whether the environment variable name is true. A value
+// strconv.ParseBool doesn't accept is added to cfg.Problems rather than read
+// as false, so a mistyped safety switch fails startup.
+func envBool(cfg *mcpproxy.Config, name string) bool {
+	v := os.Getenv(name)
+	if v == "" {
+		return false
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		problem(cfg, fmt.Errorf("invalid %s %q: not a boolean", name, v), "use true or false")
+	}
+	return b
+}
+
+// githubHost returns the GitHub Enterprise Server URL from GITHUB_HOST (or its
+// GH_HOST alias), or "" when unset. The value must be an http(s) URL with a host,
+// e.g. https://github.example.com, so typos fail at startup rather than as
+// silent requests against github.com.
+func githubHost() (string, error) {
+	host := os.Getenv("GITHUB_HOST")
+	if host == "" {
+		host = os.Getenv("GH_HOST")
+	}
+	if host == "" {
+		return "", nil
+	}
+
+	u, err := url.Parse(host)
+	if err != nil {
+		return "", fmt.Errorf("%q is not a valid URL: %w", host, err)
+	}
+	if (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return "", fmt.Errorf("%q must be an http(s) URL such as https://github.example.com", host)
+	}
+	return strings.TrimSuffix(u.String(), "/"), nil
+}
+
+// tokenSession assigns requests carrying a GitHub token in their Authorization
+// header to a session process running with that token. Requests without one
+// use the shared process and its GITHUB_PERSONAL_ACCESS_TOKEN.
+func tokenSession(r *http.Request) (string, []string) {
+	auth := r.Header.Get("Authorization")
+	token := ""
+	for _, scheme := range []string{"Bearer ", "token "} {
+		if len(auth) > len(scheme) && strings.EqualFold(auth[:len(scheme)], scheme) {
+			token = strings.TrimSpace(auth[len(scheme):])
+		}
+	}
+	if token == "" {
+		return "", nil
+	}
+
+	// Key sessions by a hash so the token itself isn't held as a map key
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:]), []string{"GITHUB_PERSONAL_ACCESS_TOKEN=" + token}
 }
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rh-ai-kickstart/ai-architecture-charts/mcp-servers/mcpproxy"
+)
+
+func TestTokenSession(t *testing.T) {
+	tests := []struct {
+		name      string
+		header    string
+		wantToken string
+	}{
+		{"bearer", "Bearer ghp_alice", "ghp_alice"},
+		{"token scheme", "token ghp_bob", "ghp_bob"},
+		{"case-insensitive scheme", "bearer ghp_carol", "ghp_carol"},
+		{"no header", "", ""},
+		{"basic auth ignored", "Basic dXNlcjpwYXNz", ""},
+		{"empty bearer", "Bearer ", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+
+			key, env := tokenSession(req)
+			if tt.wantToken == "" {
+				if key != "" || env != nil {
+					t.Errorf("Expected shared process, got key %q env %v", key, env)
+				}
+				return
+			}
+
+			if key == "" || strings.Contains(key, tt.wantToken) {
+				t.Errorf("Expected a hashed session key, got %q", key)
+			}
+			if len(env) != 1 || env[0] != "GITHUB_PERSONAL_ACCESS_TOKEN="+tt.wantToken {
+				t.Errorf("Unexpected session env %v", env)
+			}
+		})
+	}
+}
+
+func TestTokenSessionStableKey(t *testing.T) {
+	req := httptest.NewRequest("POST", "/", nil)
+	req.Header.Set("Authorization", "Bearer ghp_alice")
+	first, _ := tokenSession(req)
+	second, _ := tokenSession(req)
+	if first != second {
+		t.Error("Expected the same token to map to the same session")
+	}
+
+	req.Header.Set("Authorization", "Bearer ghp_bob")
+	if other, _ := tokenSession(req); other == first {
+		t.Error("Expected different tokens to map to different sessions")
+	}
+}
+
+func TestGithubHost(t *testing.T) {
+	tests := []struct {
+		name    string
+		host    string
+		ghHost  string
+		want    string
+		wantErr bool
+	}{
+		{"unset", "", "", "", false},
+		{"https", "https://github.example.com", "", "https://github.example.com", false},
+		{"trailing slash", "https://github.example.com/", "", "https://github.example.com", false},
+		{"GH_HOST alias", "", "https://ghe.internal", "https://ghe.internal", false},
+		{"GITHUB_HOST wins", "https://a.example.com", "https://b.example.com", "https://a.example.com", false},
+		{"missing scheme", "github.example.com", "", "", true},
+		{"unsupported scheme", "ftp://github.example.com", "", "", true},
+		{"malformed", "https://%zz", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GITHUB_HOST", tt.host)
+			t.Setenv("GH_HOST", tt.ghHost)
+
+			got, err := githubHost()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("githubHost() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("githubHost() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewConfigCommandLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		readOnly string
+		toolsets string
+		want     []string
+	}{
+		{"defaults", "", "", []string{"stdio"}},
+		{"read-only", "true", "", []string{"stdio", "--read-only"}},
+		{"read-only disabled", "false", "", []string{"stdio"}},
+		{"toolsets", "", "repos, issues", []string{"stdio", "--toolsets", "repos,issues"}},
+		{"both", "1", "repos", []string{"stdio", "--read-only", "--toolsets", "repos"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GITHUB_READ_ONLY", tt.readOnly)
+			t.Setenv("GITHUB_TOOLSETS", tt.toolsets)
+
+			cfg := newConfig()
+			if !reflect.DeepEqual(cfg.CommandArgs, tt.want) {
+				t.Errorf("CommandArgs = %q, want %q", cfg.CommandArgs, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewConfigInvalidReadOnly(t *testing.T) {
+	for _, v := range []string{"yes", "True "} {
+		t.Setenv("GITHUB_READ_ONLY", v)
+		cfg := newConfig()
+		if len(cfg.Problems) != 1 || !strings.Contains(cfg.Problems[0].Err.Error(), "GITHUB_READ_ONLY") {
+			t.Errorf("GITHUB_READ_ONLY=%q: expected a problem, got %v", v, cfg.Problems)
+		}
+	}
+}
+
+func TestNewConfigCollectsProblems(t *testing.T) {
+	t.Setenv("GITHUB_HOST", "github.example.com")
+	t.Setenv("GITHUB_AUTO_RETRY", "true")
+	t.Setenv("GITHUB_AUTO_RETRY_MAX", "never")
+	t.Setenv("GITHUB_TOKEN_FROM_HEADER", "on")
+
+	cfg := newConfig()
+	if len(cfg.Problems) != 3 {
+		t.Fatalf("Expected all 3 problems, got %v", cfg.Problems)
+	}
+	_, err := mcpproxy.NewMCPProxy(cfg)
+	for _, name := range []string{"GITHUB_HOST", "GITHUB_AUTO_RETRY_MAX", "GITHUB_TOKEN_FROM_HEADER"} {
+		if err == nil || !strings.Contains(err.Error(), name) {
+			t.Errorf("Expected NewMCPProxy to report %s, got %v", name, err)
+		}
+	}
+
+	t.Setenv("GITHUB_AUTO_RETRY", "maybe")
+	t.Setenv("GITHUB_AUTO_RETRY_MAX", "")
+	cfg = newConfig()
+	if len(cfg.Problems) != 3 || !strings.Contains(cfg.Problems[1].Err.Error(), "GITHUB_AUTO_RETRY") {
+		t.Errorf("Expected an invalid GITHUB_AUTO_RETRY to be reported, got %v", cfg.Problems)
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rh-ai-kickstart/ai-architecture-charts/mcp-servers/mcpproxy"
+)
+
+var (
+	// go-github appends "[rate reset in 12m30s]" to rate-limit errors
+	rateResetPattern = regexp.MustCompile(`rate reset in ([0-9hms.]+)`)
+	// Remaining quota, e.g. "X-RateLimit-Remaining: 12" or "remaining: 12"
+	rateRemainingPattern = regexp.MustCompile(`(?i)remaining["']?\s*[:=]\s*(\d+)`)
+	// Secondary limits suggest a wait, e.g. "retry after 60 seconds"
+	retryAfterPattern = regexp.MustCompile(`(?i)retry after (\d+)`)
+	// The quota headers GitHub sends with every response
+	quotaRemainingPattern = regexp.MustCompile(`(?i)x-ratelimit-remaining["']?\s*[:=]\s*(\d+)`)
+	quotaLimitPattern     = regexp.MustCompile(`(?i)x-ratelimit-limit["']?\s*[:=]\s*(\d+)`)
+)
+
+// rateLimitWarnThreshold is the remaining quota below which a warning is logged.
+const rateLimitWarnThreshold = 100
+
+// rateLimitInfo is attached to rate-limited responses so clients can back off.
+type rateLimitInfo struct {
+	Remaining         *int   `json:"remaining,omitempty"`
+	Reset             string `json:"reset,omitempty"`
+	RetryAfterSeconds int    `json:"retryAfterSeconds,omitempty"`
+	Secondary         bool   `json:"secondary,omitempty"`
+}
+
+// parseRateLimit extracts rate-limit details from GitHub error text.
+// It returns nil if the text doesn't describe a rate limit.
+func parseRateLimit(text string) *rateLimitInfo {
+	lower := strings.ToLower(text)
+	if !strings.Contains(lower, "rate limit") {
+		return nil
+	}
+
+	info := &rateLimitInfo{Secondary: strings.Contains(lower, "secondary rate limit")}
+	if m := rateRemainingPattern.FindStringSubmatch(text); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		info.Remaining = &n
+	}
+	if m := rateResetPattern.FindStringSubmatch(text); m != nil {
+		if d, err := time.ParseDuration(m[1]); err == nil {
+			info.Reset = time.Now().Add(d).UTC().Format(time.RFC3339)
+			info.RetryAfterSeconds = int(d.Round(time.Second).Seconds())
+		}
+	}
+	if m := retryAfterPattern.FindStringSubmatch(text); m != nil && info.RetryAfterSeconds == 0 {
+		info.RetryAfterSeconds, _ = strconv.Atoi(m[1])
+	}
+	return info
+}
+
+// annotateRateLimit is a response middleware that adds structured rate-limit
+// details to GitHub rate-limit failures. JSON-RPC errors get them in
+// error.data.rateLimit; tool results flagged isError get them in
+// result._meta.rateLimit. Other responses are returned unchanged, after
+// checking the quota they report with warnLowQuota.
+func annotateRateLimit(response []byte) []byte {
+	var resp map[string]json.RawMessage
+	if err := json.Unmarshal(response, &resp); err != nil {
+		return response
+	}
+
+	if raw, ok := resp["error"]; ok {
+		var rpcErr map[string]interface{}
+		if err := json.Unmarshal(raw, &rpcErr); err != nil {
+			return response
+		}
+		msg, _ := rpcErr["message"].(string)
+		info := parseRateLimit(msg)
+		if info == nil {
+			warnLowQuota(msg)
+			return response
+		}
+		data, _ := rpcErr["data"].(map[string]interface{})
+		if data == nil {
+			data = map[string]interface{}{}
+		}
+		data["rateLimit"] = info
+		rpcErr["data"] = data
+		logRateLimit(info)
+		return remarshal(resp, "error", rpcErr, response)
+	}
+
+	if raw, ok := resp["result"]; ok {
+		var result map[string]interface{}
+		if err := json.Unmarshal(raw, &result); err != nil {
+			return response
+		}
+		text := resultText(result)
+		info := parseRateLimit(text)
+		if isErr, _ := result["isError"].(bool); !isErr || info == nil {
+			warnLowQuota(text)
+			return response
+		}
+		meta, _ := result["_meta"].(map[string]interface{})
+		if meta == nil {
+			meta = map[string]interface{}{}
+		}
+		meta["rateLimit"] = info
+		result["_meta"] = meta
+		logRateLimit(info)
+		return remarshal(resp, "result", result, response)
+	}
+
+	return response
+}
+
+// resultText joins the text parts of a tool result's content.
+func resultText(result map[string]interface{}) string {
+	content, _ := result["content"].([]interface{})
+	var parts []string
+	for _, c := range content {
+		part, _ := c.(map[string]interface{})
+		if part["type"] == "text" {
+			if text, ok := part["text"].(string); ok {
+				parts = append(parts, text)
+			}
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// remarshal replaces resp[key] with value and re-encodes the response,
+// falling back to the original bytes on error.
+func remarshal(resp map[string]json.RawMessage, key string, value interface{}, original []byte) []byte {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return original
+	}
+	resp[key] = raw
+	out, err := json.Marshal(resp)
+	if err != nil {
+		return original
+	}
+	return out
+}
+
+// secondaryRetryDelay is how long to wait before retrying after a secondary
+// rate limit that doesn't say, as GitHub's documentation recommends.
+const secondaryRetryDelay = time.Minute
+
+// retrySecondaryRateLimit is a mcpproxy.Config.ResponseRetry that retries
+// requests that hit a secondary rate limit, after the delay GitHub asked for.
+// It relies on annotateRateLimit having run first.
+func retrySecondaryRateLimit(response []byte) (time.Duration, bool) {
+	var resp struct {
+		Error *struct {
+			Data struct {
+				RateLimit *rateLimitInfo `json:"rateLimit"`
+			} `json:"data"`
+		} `json:"error"`
+		Result *struct {
+			Meta struct {
+				RateLimit *rateLimitInfo `json:"rateLimit"`
+			} `json:"_meta"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(response, &resp); err != nil {
+		return 0, false
+	}
+	var info *rateLimitInfo
+	switch {
+	case resp.Error != nil:
+		info = resp.Error.Data.RateLimit
+	case resp.Result != nil:
+		info = resp.Result.Meta.RateLimit
+	}
+	if info == nil || !info.Secondary {
+		return 0, false
+	}
+	if info.RetryAfterSeconds > 0 {
+		return time.Duration(info.RetryAfterSeconds) * time.Second, true
+	}
+	return secondaryRetryDelay, true
+}
+
+// warnLowQuota logs a warning when text reports an X-RateLimit-Remaining
+// below rateLimitWarnThreshold, so operators hear about the limit before
+// requests start failing.
+func warnLowQuota(text string) {
+	m := quotaRemainingPattern.FindStringSubmatch(text)
+	if m == nil {
+		return
+	}
+	remaining, _ := strconv.Atoi(m[1])
+	if remaining >= rateLimitWarnThreshold {
+		return
+	}
+	if m := quotaLimitPattern.FindStringSubmatch(text); m != nil {
+		mcpproxy.Warnf("GitHub rate limit approaching: %d of %s requests remaining", remaining, m[1])
+		return
+	}
+	mcpproxy.Warnf("GitHub rate limit approaching: %d requests remaining", remaining)
+}
+
+// logRateLimit logs a warning about a rate-limit failure.
+func logRateLimit(info *rateLimitInfo) {
+	switch {
+	case info.Remaining != nil && *info.Remaining > 0 && *info.Remaining < rateLimitWarnThreshold:
+		mcpproxy.Warnf("GitHub rate limit approaching: %d requests remaining", *info.Remaining)
+	default:
+		mcpproxy.Warnf("GitHub rate limit hit (secondary: %v), retry after %ds", info.Secondary, info.RetryAfterSeconds)
+	}
+}
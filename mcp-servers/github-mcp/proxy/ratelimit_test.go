@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rh-ai-kickstart/ai-architecture-charts/mcp-servers/mcpproxy"
+	"github.com/rh-ai-kickstart/ai-architecture-charts/mcp-servers/mcpproxy/mcptest"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	tests := []struct {
+		name          string
+		text          string
+		wantNil       bool
+		wantRemaining int
+		wantRetry     int
+		wantSecondary bool
+	}{
+		{
+			name:      "primary limit with reset",
+			text:      "GET https://api.github.com/user: 403 API rate limit exceeded for user ID 1. [rate reset in 12m30s]",
+			wantRetry: 750,
+		},
+		{
+			name:          "remaining header",
+			text:          "rate limit: X-RateLimit-Remaining: 42",
+			wantRemaining: 42,
+		},
+		{
+			name:          "secondary limit",
+			text:          "403 You have exceeded a secondary rate limit. Please retry after 60 seconds.",
+			wantRetry:     60,
+			wantSecondary: true,
+		},
+		{
+			name:    "unrelated error",
+			text:    "404 Not Found",
+			wantNil: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := parseRateLimit(tt.text)
+			if tt.wantNil {
+				if info != nil {
+					t.Errorf("Expected nil, got %+v", info)
+				}
+				return
+			}
+			if info == nil {
+				t.Fatal("Expected rate-limit info")
+			}
+			if tt.wantRemaining != 0 && (info.Remaining == nil || *info.Remaining != tt.wantRemaining) {
+				t.Errorf("Expected remaining %d, got %v", tt.wantRemaining, info.Remaining)
+			}
+			if info.RetryAfterSeconds != tt.wantRetry {
+				t.Errorf("Expected retry after %d, got %d", tt.wantRetry, info.RetryAfterSeconds)
+			}
+			if info.Secondary != tt.wantSecondary {
+				t.Errorf("Expected secondary %v, got %v", tt.wantSecondary, info.Secondary)
+			}
+		})
+	}
+}
+
+func TestAnnotateRateLimitJSONRPCError(t *testing.T) {
+	in := `{"jsonrpc":"2.0","id":1,"error":{"code":-32603,"message":"API rate limit exceeded [rate reset in 1m0s]"}}`
+	var out struct {
+		Error struct {
+			Message string `json:"message"`
+			Data    struct {
+				RateLimit rateLimitInfo `json:"rateLimit"`
+			} `json:"data"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(annotateRateLimit([]byte(in)), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Error.Data.RateLimit.RetryAfterSeconds != 60 || out.Error.Data.RateLimit.Reset == "" {
+		t.Errorf("Unexpected rate-limit data %+v", out.Error.Data.RateLimit)
+	}
+	if out.Error.Message == "" {
+		t.Error("Expected original message to be kept")
+	}
+}
+
+func TestAnnotateRateLimitToolResult(t *testing.T) {
+	in := `{"jsonrpc":"2.0","id":1,"result":{"isError":true,"content":[{"type":"text","text":"secondary rate limit, retry after 30 seconds"}]}}`
+	var out struct {
+		Result struct {
+			Meta struct {
+				RateLimit rateLimitInfo `json:"rateLimit"`
+			} `json:"_meta"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(annotateRateLimit([]byte(in)), &out); err != nil {
+		t.Fatal(err)
+	}
+	if !out.Result.Meta.RateLimit.Secondary || out.Result.Meta.RateLimit.RetryAfterSeconds != 30 {
+		t.Errorf("Unexpected rate-limit meta %+v", out.Result.Meta.RateLimit)
+	}
+}
+
+func TestAnnotateRateLimitUnchanged(t *testing.T) {
+	for _, in := range []string{
+		`{"jsonrpc":"2.0","id":1,"result":{"content":[{"type":"text","text":"rate limit docs"}]}}`,
+		`{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"Method not found"}}`,
+		`not json`,
+	} {
+		if got := string(annotateRateLimit([]byte(in))); got != in {
+			t.Errorf("annotateRateLimit(%q) = %q, want unchanged", in, got)
+		}
+	}
+}
+
+func TestWarnLowQuota(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"successful result", `{"jsonrpc":"2.0","id":1,"result":{"content":[{"type":"text","text":"X-RateLimit-Limit: 5000\nX-RateLimit-Remaining: 42"}]}}`, "42 of 5000 requests remaining"},
+		{"other error", `{"jsonrpc":"2.0","id":1,"error":{"code":-32603,"message":"404 Not Found (x-ratelimit-remaining: 7)"}}`, "7 requests remaining"},
+		{"plenty left", `{"jsonrpc":"2.0","id":1,"result":{"content":[{"type":"text","text":"X-RateLimit-Remaining: 4000"}]}}`, ""},
+		{"no quota", `{"jsonrpc":"2.0","id":1,"result":{"content":[{"type":"text","text":"3 items remaining: 2"}]}}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			if got := string(annotateRateLimit([]byte(tt.in))); got != tt.in {
+				t.Errorf("Expected the response unchanged, got %s", got)
+			}
+			if tt.want == "" && buf.Len() > 0 {
+				t.Errorf("Expected no warning, got %q", buf.String())
+			}
+			if tt.want != "" && (!strings.Contains(buf.String(), "WARN") || !strings.Contains(buf.String(), tt.want)) {
+				t.Errorf("Expected a warning containing %q, got %q", tt.want, buf.String())
+			}
+		})
+	}
+}
+
+func TestRateLimitAnnotatedThroughProxy(t *testing.T) {
+	srv := mcptest.NewFakeServer(func(req json.RawMessage) []json.RawMessage {
+		return []json.RawMessage{mcptest.Error(req, -32603, "API rate limit exceeded [rate reset in 1m0s]")}
+	})
+	defer srv.Close()
+
+	cfg := newConfig()
+	fake := srv.Config()
+	cfg.CommandPath, cfg.CommandArgs = fake.CommandPath, fake.CommandArgs
+	proxy, err := mcpproxy.NewMCPProxy(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	body := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"get_me","arguments":{}}}`
+	proxy.Handle(w, httptest.NewRequest("POST", "/", strings.NewReader(body)))
+	if !strings.Contains(w.Body.String(), `"retryAfterSeconds":60`) {
+		t.Errorf("Expected the proxy to annotate the rate-limit error, got %s", w.Body.String())
+	}
+}
+
+func TestRetrySecondaryRateLimit(t *testing.T) {
+	tests := []struct {
+		name      string
+		in        string
+		wantDelay time.Duration
+		wantRetry bool
+	}{
+		{"JSON-RPC error", `{"error":{"data":{"rateLimit":{"retryAfterSeconds":30,"secondary":true}}}}`, 30 * time.Second, true},
+		{"tool result", `{"result":{"_meta":{"rateLimit":{"secondary":true}}}}`, secondaryRetryDelay, true},
+		{"primary limit", `{"error":{"data":{"rateLimit":{"retryAfterSeconds":600}}}}`, 0, false},
+		{"success", `{"result":{"content":[]}}`, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delay, retry := retrySecondaryRateLimit([]byte(tt.in))
+			if delay != tt.wantDelay || retry != tt.wantRetry {
+				t.Errorf("retrySecondaryRateLimit() = %s, %v; want %s, %v", delay, retry, tt.wantDelay, tt.wantRetry)
+			}
+		})
+	}
+}
+
+func TestAutoRetryThroughProxy(t *testing.T) {
+	t.Setenv("GITHUB_AUTO_RETRY", "true")
+
+	calls := 0
+	srv := mcptest.NewFakeServer(func(req json.RawMessage) []json.RawMessage {
+		calls++
+		if calls == 1 {
+			return []json.RawMessage{mcptest.Error(req, -32603, "You have exceeded a secondary rate limit. Please retry after 1 seconds.")}
+		}
+		return []json.RawMessage{mcptest.Result(req, map[string]interface{}{"content": []interface{}{}})}
+	})
+	defer srv.Close()
+
+	cfg := newConfig()
+	fake := srv.Config()
+	cfg.CommandPath, cfg.CommandArgs = fake.CommandPath, fake.CommandArgs
+	proxy, err := mcpproxy.NewMCPProxy(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	body := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"get_me","arguments":{}}}`
+	proxy.Handle(w, httptest.NewRequest("POST", "/", strings.NewReader(body)))
+	if strings.Contains(w.Body.String(), "error") || !strings.Contains(w.Body.String(), `"result"`) {
+		t.Errorf("Expected the retried request to succeed, got %s", w.Body.String())
+	}
+	if calls != 2 {
+		t.Errorf("Expected 2 calls to the server, got %d", calls)
+	}
+}
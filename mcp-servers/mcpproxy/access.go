@@ -0,0 +1,92 @@
+package mcpproxy
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+// accessWriter records the status of a response for the access log, and
+// its body for the audit log.
+type accessWriter struct {
+	http.ResponseWriter
+	status   int
+	method   string // JSON-RPC method, once the body is decoded
+	keepBody bool
+	body     []byte // uncompressed response, if keepBody
+}
+
+func (w *accessWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *accessWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if w.keepBody && w.Header().Get("Content-Encoding") == "" {
+		w.body = append(w.body, b...)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush keeps the notifications stream working behind the access log.
+func (w *accessWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *accessWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// keepBody returns the accessWriter of w, or wraps w in one, set to keep the
+// response body.
+func keepBody(w http.ResponseWriter) *accessWriter {
+	aw, ok := w.(*accessWriter)
+	if !ok {
+		aw = &accessWriter{ResponseWriter: w}
+	}
+	aw.keepBody = true
+	return aw
+}
+
+// noteMethod records the JSON-RPC method of the request being answered on w,
+// if it has an access log entry.
+func noteMethod(w http.ResponseWriter, method string) {
+	if aw, ok := w.(*accessWriter); ok {
+		aw.method = method
+	}
+}
+
+// noteResponse records a response about to be compressed onto w, if its
+// body is kept.
+func noteResponse(w http.ResponseWriter, response []byte) {
+	if aw, ok := w.(*accessWriter); ok && aw.keepBody {
+		aw.body = response
+	}
+}
+
+// logAccess writes the access log line for a request answered on w. The
+// JSON-RPC method is quoted since clients choose it; "-" stands for none,
+// as for a GET.
+func (p *MCPProxy) logAccess(r *http.Request, w *accessWriter, start time.Time) {
+	client, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		client = r.RemoteAddr
+	}
+	method := "-"
+	if w.method != "" {
+		method = w.method
+	}
+	status := w.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	p.logger.accessf("client=%s http_method=%s path=%s mcp_method=%q status=%d duration=%s",
+		client, r.Method, r.URL.EscapedPath(), method, status, time.Since(start).Round(time.Microsecond))
+}
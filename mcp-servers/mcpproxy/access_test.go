@@ -0,0 +1,82 @@
+package mcpproxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestHandleAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	trusted, _ := parseTrustedProxies([]string{"10.0.0.1"})
+	proxy := &MCPProxy{
+		config:   Config{ServerName: "test", AccessLog: true, MaxRequestBytes: 64},
+		logger:   newLogger("test", "error"),
+		requests: make(chan *request, 1),
+		trusted:  trusted,
+	}
+	drainRequests(proxy, func(json.RawMessage) json.RawMessage {
+		return json.RawMessage(`{"jsonrpc":"2.0","id":1,"result":{}}`)
+	})
+	defer close(proxy.requests)
+
+	r := httptest.NewRequest("POST", "/", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`))
+	r.RemoteAddr = "10.0.0.1:4000"
+	r.Header.Set("X-Forwarded-For", "203.0.113.7")
+	proxy.Handle(httptest.NewRecorder(), r)
+
+	r = httptest.NewRequest("POST", "/", strings.NewReader(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"padding":"`+strings.Repeat("x", 64)+`"}}`))
+	r.RemoteAddr = "198.51.100.2:4000"
+	proxy.Handle(httptest.NewRecorder(), r)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	want := []*regexp.Regexp{
+		regexp.MustCompile(`\[test\] ACCESS client=203\.0\.113\.7 http_method=POST path=/ mcp_method="tools/list" status=200 duration=\S+$`),
+		regexp.MustCompile(`\[test\] ACCESS client=198\.51\.100\.2 http_method=POST path=/ mcp_method="-" status=413 duration=\S+$`),
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("Expected %d access log lines even at error level, got:\n%s", len(want), buf.String())
+	}
+	for i, re := range want {
+		if !re.MatchString(lines[i]) {
+			t.Errorf("Line %d: expected to match %s, got %q", i, re, lines[i])
+		}
+	}
+}
+
+func TestAccessWriterKeepsFlusher(t *testing.T) {
+	var w http.ResponseWriter = &accessWriter{ResponseWriter: httptest.NewRecorder()}
+	if _, ok := w.(http.Flusher); !ok {
+		t.Error("Expected the access log writer to support streaming")
+	}
+}
+
+func TestAccessLogDisabled(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	proxy := &MCPProxy{
+		config:   Config{ServerName: "test"},
+		logger:   newLogger("test", "info"),
+		requests: make(chan *request, 1),
+	}
+	drainRequests(proxy, func(json.RawMessage) json.RawMessage {
+		return json.RawMessage(`{"jsonrpc":"2.0","id":1,"result":{}}`)
+	})
+	defer close(proxy.requests)
+
+	proxy.Handle(httptest.NewRecorder(), httptest.NewRequest("POST", "/", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`)))
+	if strings.Contains(buf.String(), "ACCESS") {
+		t.Errorf("Expected no access log by default, got:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,181 @@
+package mcpproxy
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"expvar"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+)
+
+// drainTimeout bounds how long /admin/drain waits for active requests.
+const drainTimeout = 30 * time.Second
+
+// newAdminMux returns the handler for the admin listener: the MCP server's
+// command line and pending requests, the pprof profiles and expvar when
+// Config.EnablePprof is set, and the restart, drain and reload endpoints, and
+// /debug/recent with Config.RecentRequests, when Config.AdminToken is set.
+// None of these may ever be reachable on the main listener.
+func (p *MCPProxy) newAdminMux() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/cmd", p.handleDebugCmd)
+	mux.HandleFunc("/debug/pending", p.handleDebugPending)
+	if p.config.EnablePprof {
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+		mux.Handle("/debug/vars", expvar.Handler())
+	}
+	if p.config.AdminToken == "" {
+		return mux
+	}
+	mux.HandleFunc("/admin/restart", p.handleRestart)
+	mux.HandleFunc("/admin/drain", p.handleDrain)
+	mux.HandleFunc("/admin/reload", p.handleReload)
+	if p.recent != nil {
+		mux.HandleFunc("/debug/recent", p.handleRecent)
+	}
+	return requireToken(p.config.AdminToken, mux)
+}
+
+// requireToken rejects requests that don't carry token as a bearer token.
+func requireToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleRestart replaces the MCP server process and resumes accepting
+// requests if the proxy was draining. Session processes are retired and
+// started again on their next request; additional instances are restarted.
+func (p *MCPProxy) handleRestart(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	p.logger.warnf("Restart requested by %s", r.RemoteAddr)
+	p.sessions.retireAll()
+	p.instances.restartAll()
+	if err := p.restart(); err != nil {
+		p.logger.errorf("Restart failed: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	p.draining.Store(false)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"pid": p.pid()})
+}
+
+// handleDrain stops accepting new MCP requests, which get HTTP 503, and
+// waits for active ones to finish. A restart resumes accepting requests.
+func (p *MCPProxy) handleDrain(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	p.logger.warnf("Drain requested by %s", r.RemoteAddr)
+	p.draining.Store(true)
+
+	deadline := time.Now().Add(drainTimeout)
+	for p.active.Load() > 0 && time.Now().Before(deadline) && r.Context().Err() == nil {
+		time.Sleep(50 * time.Millisecond)
+	}
+	active := p.active.Load()
+	if active == 0 {
+		p.logger.infof("Drained, no requests active")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"drained": active == 0, "active": active})
+}
+
+// handleDebugCmd serves how the MCP server process was started: the resolved
+// command, its arguments with secrets masked, its working directory and the
+// names, but not the values, of its environment variables.
+func (p *MCPProxy) handleDebugCmd(w http.ResponseWriter, r *http.Request) {
+	c := p.config
+	if c.BackendType == backendHTTP {
+		http.Error(w, "no MCP server process: requests are forwarded to MCP_UPSTREAM_URL", http.StatusNotFound)
+		return
+	}
+
+	path, err := exec.LookPath(c.CommandPath)
+	if err != nil {
+		path = c.CommandPath
+	}
+	args := make([]string, len(c.CommandArgs))
+	for i, arg := range c.CommandArgs {
+		args[i] = c.redactText(arg)
+	}
+	cwd := c.WorkDir
+	if cwd == "" {
+		// The process inherits the proxy's
+		cwd, _ = os.Getwd()
+	}
+	env := envKeys(subprocessEnv(c))
+	slices.Sort(env)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"path":     path,
+		"args":     args,
+		"cwd":      cwd,
+		"env_keys": slices.Compact(env),
+		"pid":      p.procPID.Load(),
+	})
+}
+
+// pid returns the MCP server's process ID.
+func (p *MCPProxy) pid() int {
+	p.io.Lock()
+	defer p.io.Unlock()
+	if p.cmd == nil {
+		return 0
+	}
+	return p.cmd.Process.Pid
+}
+
+// serveManagement serves the health, metrics and diagnostic endpoints on ln,
+// the Config.AdminPort listener. Without them the proxy looks dead to its
+// probes, so a failure is logged as an error.
+func (p *MCPProxy) serveManagement(ln net.Listener) {
+	p.logger.infof("Health, metrics and diagnostic endpoints on port %s", p.config.AdminPort)
+	if err := http.Serve(ln, p.newManagementMux()); err != nil {
+		p.logger.errorf("Management listener failed: %v", err)
+	}
+}
+
+// serveAdmin runs the admin listener on Config.AdminAddr. A failure is
+// logged rather than fatal, since the proxy itself still works.
+func (p *MCPProxy) serveAdmin() {
+	addr := p.config.AdminAddr
+	if err := validateListenAddr(addr); err != nil {
+		p.logger.errorf("Not starting admin listener: %v", err)
+		return
+	}
+	if p.config.EnablePprof {
+		p.logger.warnf("pprof and expvar enabled on http://%s/debug/pprof/; do not expose this address", endpointHost(addr))
+	}
+	if p.config.AdminToken != "" {
+		p.logger.infof("Admin endpoints enabled on http://%s/admin/", endpointHost(addr))
+	}
+	if err := http.ListenAndServe(addr, p.newAdminMux()); err != nil {
+		p.logger.errorf("Admin listener failed: %v", err)
+	}
+}
@@ -0,0 +1,231 @@
+package mcpproxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAdminMuxServesProfiles(t *testing.T) {
+	proxy := &MCPProxy{config: Config{EnablePprof: true}}
+	mux := proxy.newAdminMux()
+	for _, path := range []string{"/debug/pprof/", "/debug/pprof/goroutine?debug=1", "/debug/vars"} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("GET %s: expected 200, got %d", path, w.Code)
+		}
+	}
+}
+
+func TestMainMuxDoesNotServeProfiles(t *testing.T) {
+	proxy := &MCPProxy{config: Config{ServerName: "test"}, requests: make(chan *request, 1)}
+	mux := proxy.newMux()
+
+	for _, path := range []string{"/debug/pprof/", "/debug/vars", "/admin/restart"} {
+		_, pattern := mux.Handler(httptest.NewRequest("GET", path, nil))
+		if pattern != "/" {
+			t.Errorf("GET %s: expected only the MCP catch-all, got pattern %q", path, pattern)
+		}
+	}
+}
+
+func TestAdminRequiresToken(t *testing.T) {
+	proxy := &MCPProxy{config: Config{AdminToken: "s3cret", EnablePprof: true}}
+	mux := proxy.newAdminMux()
+
+	for _, auth := range []string{"", "Bearer wrong"} {
+		req := httptest.NewRequest("POST", "/admin/drain", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("Authorization %q: expected 401, got %d", auth, w.Code)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/debug/pprof/", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected pprof to require the token too, got %d", w.Code)
+	}
+}
+
+func adminPost(t *testing.T, h http.Handler, path string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest("POST", path, nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func TestAdminRestart(t *testing.T) {
+	proxy, err := NewMCPProxy(Config{ServerName: "test", CommandPath: "cat", AdminToken: "s3cret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+	admin := proxy.newAdminMux()
+
+	call := func() int {
+		w := httptest.NewRecorder()
+		proxy.Handle(w, httptest.NewRequest("POST", "/", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`)))
+		return w.Code
+	}
+	if code := call(); code != http.StatusOK {
+		t.Fatalf("Expected 200 before the restart, got %d", code)
+	}
+
+	oldPID := proxy.pid()
+	w := adminPost(t, admin, "/admin/restart")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected restart to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		PID int `json:"pid"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.PID == 0 || resp.PID == oldPID {
+		t.Errorf("Expected a new PID after restart, got %d (was %d)", resp.PID, oldPID)
+	}
+
+	if code := call(); code != http.StatusOK {
+		t.Errorf("Expected 200 after the restart, got %d", code)
+	}
+}
+
+func TestAdminDrain(t *testing.T) {
+	proxy, err := NewMCPProxy(Config{ServerName: "test", CommandPath: "cat", AdminToken: "s3cret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+	admin := proxy.newAdminMux()
+
+	if w := adminPost(t, admin, "/admin/drain"); !strings.Contains(w.Body.String(), `"drained":true`) {
+		t.Fatalf("Expected drain to complete, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w := httptest.NewRecorder()
+	proxy.Handle(w, httptest.NewRequest("POST", "/", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`)))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 while draining, got %d", w.Code)
+	}
+
+	// A restart resumes accepting requests
+	adminPost(t, admin, "/admin/restart")
+	w = httptest.NewRecorder()
+	proxy.Handle(w, httptest.NewRequest("POST", "/", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`)))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected 200 after restart, got %d", w.Code)
+	}
+}
+
+func TestAdminDebugCmd(t *testing.T) {
+	t.Setenv("MCPPROXY_TEST_SECRET", "hunter2")
+	proxy, err := NewMCPProxy(Config{
+		ServerName:  "test",
+		CommandPath: "cat",
+		CommandArgs: []string{"-u", "--password=hunter2"},
+		ExtraEnv:    []string{"EXTRA_KEY=extra-value"},
+		AdminToken:  "s3cret",
+		LogRedactor: func(s string) string { return strings.ReplaceAll(s, "hunter2", "***") },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+
+	req := httptest.NewRequest("GET", "/debug/cmd", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	w := httptest.NewRecorder()
+	proxy.newAdminMux().ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "hunter2") || strings.Contains(w.Body.String(), "extra-value") {
+		t.Errorf("Expected no secrets or environment values, got %s", w.Body.String())
+	}
+
+	var body struct {
+		Path    string   `json:"path"`
+		Args    []string `json:"args"`
+		Cwd     string   `json:"cwd"`
+		EnvKeys []string `json:"env_keys"`
+		PID     int      `json:"pid"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(body.Path, "/cat") || len(body.Args) != 2 || body.Args[0] != "-u" || body.Cwd == "" || body.PID != proxy.pid() {
+		t.Errorf("Unexpected command line %+v", body)
+	}
+	keys := strings.Join(body.EnvKeys, ",")
+	if !strings.Contains(keys, "EXTRA_KEY") || !strings.Contains(keys, "MCPPROXY_TEST_SECRET") {
+		t.Errorf("Expected inherited and extra environment keys, got %s", keys)
+	}
+}
+
+func TestAdminPortMovesManagementEndpoints(t *testing.T) {
+	paths := []string{"/healthz", "/readyz", "/metrics", "/logs", "/config", "/openapi.json", "/version"}
+
+	proxy := &MCPProxy{config: Config{ServerName: "test"}}
+	for _, path := range paths[:3] {
+		if _, pattern := proxy.newMux().Handler(httptest.NewRequest("GET", path, nil)); pattern != path {
+			t.Errorf("Expected %s on the main listener without ADMIN_PORT, got pattern %q", path, pattern)
+		}
+	}
+	for _, path := range []string{"/logs", "/config"} {
+		if _, pattern := proxy.newMux().Handler(httptest.NewRequest("GET", path, nil)); pattern != "/" {
+			t.Errorf("Expected %s to be left to the MCP handler without ENABLE_DIAGNOSTICS, got pattern %q", path, pattern)
+		}
+	}
+
+	proxy.config.AdminPort = "9090"
+	main, management := proxy.newMux(), proxy.newManagementMux()
+	for _, path := range paths {
+		if _, pattern := main.Handler(httptest.NewRequest("GET", path, nil)); pattern != "/" {
+			t.Errorf("Expected %s to be left to the MCP handler with ADMIN_PORT, got pattern %q", path, pattern)
+		}
+		if _, pattern := management.Handler(httptest.NewRequest("GET", path, nil)); pattern != path {
+			t.Errorf("Expected %s on the ADMIN_PORT listener, got pattern %q", path, pattern)
+		}
+	}
+	if _, pattern := management.Handler(httptest.NewRequest("POST", "/", nil)); pattern != "" {
+		t.Errorf("Expected no MCP endpoint on the ADMIN_PORT listener, got pattern %q", pattern)
+	}
+}
+
+func TestDiagnosticsOnMainListenerRequireToken(t *testing.T) {
+	proxy := &MCPProxy{
+		config: Config{ServerName: "test", EnableDiagnostics: true},
+		logger: newLogger("test", "warn"),
+		stderr: newLineBuffer(10),
+	}
+	if _, pattern := proxy.newMux().Handler(httptest.NewRequest("GET", "/logs", nil)); pattern != "/" {
+		t.Errorf("Expected /logs not to be served without ADMIN_TOKEN, got pattern %q", pattern)
+	}
+
+	proxy.config.AdminToken = "secret"
+	mux := proxy.newMux()
+	for _, path := range []string{"/logs", "/config"} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected 401 for %s without the token, got %d", path, w.Code)
+		}
+
+		w = httptest.NewRecorder()
+		r := httptest.NewRequest("GET", path, nil)
+		r.Header.Set("Authorization", "Bearer secret")
+		mux.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected 200 for %s with the token, got %d", path, w.Code)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package mcpproxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// commandPath returns the MCP server command: MCP_COMMAND if set, else the
+// variable named by cfg.PathEnvVar if set, else cfg.CommandPath. With
+// MCP_EXPAND_ENV=true an override has environment variables expanded; see
+// expandEnv.
+func commandPath(cfg Config) string {
+	v := os.Getenv("MCP_COMMAND")
+	if v == "" && cfg.PathEnvVar != "" {
+		v = os.Getenv(cfg.PathEnvVar)
+	}
+	if v == "" {
+		return cfg.CommandPath
+	}
+	if envBool(nil, "MCP_EXPAND_ENV", false) {
+		v = expandEnv(v)
+	}
+	return v
+}
+
+// commandArgs returns the MCP server arguments, letting MCP_ARGS override
+// cfg.CommandArgs. MCP_ARGS may be a JSON array of strings; otherwise it is
+// split on commas unless MCP_ARGS_MODE=shell, in which case it is tokenized
+// with shell-style quoting. With MCP_EXPAND_ENV=true each argument then has
+// environment variables expanded; see expandEnv.
+func commandArgs(cfg Config) ([]string, error) {
+	v := os.Getenv("MCP_ARGS")
+	if v == "" {
+		return cfg.CommandArgs, nil
+	}
+	args, err := parseArgs(v, os.Getenv("MCP_ARGS_MODE"))
+	if err != nil || !envBool(nil, "MCP_EXPAND_ENV", false) {
+		return args, err
+	}
+	for i, arg := range args {
+		args[i] = expandEnv(arg)
+	}
+	return args, nil
+}
+
+// expandEnv replaces $VAR and ${VAR} in s with the value of the environment
+// variable, or nothing if it is unset. $$ stands for a literal $.
+func expandEnv(s string) string {
+	return os.Expand(s, func(name string) string {
+		if name == "$" {
+			return "$"
+		}
+		return os.Getenv(name)
+	})
+}
+
+// parseArgs splits an argument string according to mode ("" or "comma", or "shell").
+func parseArgs(s, mode string) ([]string, error) {
+	switch strings.ToLower(mode) {
+	case "", "comma":
+		if args, ok := parseJSONArgs(s); ok {
+			return args, nil
+		}
+		return splitComma(s), nil
+	case "shell":
+		return splitShell(s)
+	}
+	return nil, fmt.Errorf("unknown MCP_ARGS_MODE %q (expected \"comma\" or \"shell\")", mode)
+}
+
+// parseJSONArgs parses s as a JSON array of strings such as ["-mcp","--verbose"].
+// It reports false if s doesn't start with '[' or isn't a valid array, so the
+// caller can fall back to comma splitting.
+func parseJSONArgs(s string) ([]string, bool) {
+	if !strings.HasPrefix(strings.TrimSpace(s), "[") {
+		return nil, false
+	}
+	var args []string
+	if err := json.Unmarshal([]byte(s), &args); err != nil {
+		return nil, false
+	}
+	return args, true
+}
+
+func splitComma(s string) []string {
+	var args []string
+	for _, arg := range strings.Split(s, ",") {
+		if arg = strings.TrimSpace(arg); arg != "" {
+			args = append(args, arg)
+		}
+	}
+	return args
+}
+
+// splitShell tokenizes s the way a POSIX shell would split words, without
+// performing any expansion. Single quotes preserve everything literally,
+// double quotes allow \" and \\ escapes, and a backslash outside quotes
+// escapes the next character.
+func splitShell(s string) ([]string, error) {
+	var (
+		args    []string
+		current strings.Builder
+		inWord  bool
+	)
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			if inWord {
+				args = append(args, current.String())
+				current.Reset()
+				inWord = false
+			}
+		case c == '\\':
+			if i+1 >= len(s) {
+				return nil, fmt.Errorf("trailing backslash in %q", s)
+			}
+			i++
+			current.WriteByte(s[i])
+			inWord = true
+		case c == '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated single quote in %q", s)
+			}
+			current.WriteString(s[i+1 : i+1+end])
+			i += end + 1
+			inWord = true
+		case c == '"':
+			i++
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) && strings.IndexByte(`"\$`+"`", s[i+1]) >= 0 {
+					i++
+				}
+				current.WriteByte(s[i])
+			}
+			if i >= len(s) {
+				return nil, fmt.Errorf("unterminated double quote in %q", s)
+			}
+			inWord = true
+		default:
+			current.WriteByte(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,138 @@
+package mcpproxy
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		mode    string
+		want    []string
+		wantErr bool
+	}{
+		{"comma default", "-mcp,--verbose", "", []string{"-mcp", "--verbose"}, false},
+		{"comma trims spaces", " stdio , --read-only ", "comma", []string{"stdio", "--read-only"}, false},
+		{"json array", `["-mcp","--verbose"]`, "", []string{"-mcp", "--verbose"}, false},
+		{"json array keeps commas and spaces", ` ["--query", "SELECT a, b FROM t"]`, "", []string{"--query", "SELECT a, b FROM t"}, false},
+		{"json empty array", `[]`, "", []string{}, false},
+		{"invalid json falls back to comma", `[a,b`, "", []string{"[a", "b"}, false},
+		{"json array of non-strings falls back to comma", `[1,2]`, "", []string{"[1", "2]"}, false},
+		{"shell simple", "-mcp --verbose", "shell", []string{"-mcp", "--verbose"}, false},
+		{"shell double quotes with commas", `--query "SELECT a, b FROM t"`, "shell", []string{"--query", "SELECT a, b FROM t"}, false},
+		{"shell single quotes", `--name 'it''s'`, "shell", []string{"--name", "its"}, false},
+		{"shell escaped quote in double quotes", `--msg "say \"hi\""`, "shell", []string{"--msg", `say "hi"`}, false},
+		{"shell backslash outside quotes", `a\ b c`, "shell", []string{"a b", "c"}, false},
+		{"shell empty quoted arg", `--flag ""`, "shell", []string{"--flag", ""}, false},
+		{"shell literal backslash in double quotes", `"C:\path"`, "shell", []string{`C:\path`}, false},
+		{"shell unterminated double quote", `--query "SELECT`, "shell", nil, true},
+		{"shell unterminated single quote", `'abc`, "shell", nil, true},
+		{"shell trailing backslash", `abc\`, "shell", nil, true},
+		{"unknown mode", "a", "yaml", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseArgs(tt.in, tt.mode)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseArgs(%q, %q) error = %v, wantErr %v", tt.in, tt.mode, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseArgs(%q, %q) = %q, want %q", tt.in, tt.mode, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommandArgsEnvOverride(t *testing.T) {
+	cfg := Config{CommandArgs: []string{"stdio"}}
+
+	os.Unsetenv("MCP_ARGS")
+	if got, _ := commandArgs(cfg); !reflect.DeepEqual(got, []string{"stdio"}) {
+		t.Errorf("Expected config args when MCP_ARGS is unset, got %q", got)
+	}
+
+	os.Setenv("MCP_ARGS", `stdio --toolsets "repos issues"`)
+	os.Setenv("MCP_ARGS_MODE", "shell")
+	defer os.Unsetenv("MCP_ARGS")
+	defer os.Unsetenv("MCP_ARGS_MODE")
+
+	got, err := commandArgs(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []string{"stdio", "--toolsets", "repos issues"}) {
+		t.Errorf("Unexpected args %q", got)
+	}
+}
+
+func TestCommandArgsExpandEnv(t *testing.T) {
+	t.Setenv("DB_NAME", "sales, eu")
+	t.Setenv("MCP_ARGS", `--db,$DB_NAME,--url=${DB_NAME}db,--price=$$5,$UNSET_VAR`)
+	cfg := Config{}
+
+	// Without MCP_EXPAND_ENV, a $ is kept as it is
+	got, err := commandArgs(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"--db", "$DB_NAME", "--url=${DB_NAME}db", "--price=$$5", "$UNSET_VAR"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected no expansion by default, got %q", got)
+	}
+
+	t.Setenv("MCP_EXPAND_ENV", "true")
+	got, err = commandArgs(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"--db", "sales, eu", "--url=sales, eudb", "--price=$5", ""}; !reflect.DeepEqual(got, want) {
+		t.Errorf("commandArgs() = %q, want %q", got, want)
+	}
+}
+
+func TestCommandPathExpandEnv(t *testing.T) {
+	t.Setenv("TOOLS_DIR", "/opt/tools")
+	t.Setenv("SQL_PATH", "$TOOLS_DIR/sql")
+	cfg := Config{CommandPath: "sql", PathEnvVar: "SQL_PATH"}
+
+	if got := commandPath(cfg); got != "$TOOLS_DIR/sql" {
+		t.Errorf("Expected no expansion by default, got %q", got)
+	}
+	t.Setenv("MCP_EXPAND_ENV", "true")
+	if got := commandPath(cfg); got != "/opt/tools/sql" {
+		t.Errorf("commandPath() = %q, want /opt/tools/sql", got)
+	}
+	t.Setenv("SQL_PATH", "")
+	if got := commandPath(cfg); got != "sql" {
+		t.Errorf("Expected CommandPath without an override, got %q", got)
+	}
+}
+
+func TestCommandPathPrecedence(t *testing.T) {
+	cfg := Config{CommandPath: "/opt/oracle/sqlcl/bin/sql", PathEnvVar: "SQL_PATH"}
+	for _, tc := range []struct {
+		mcpCommand, sqlPath, want string
+	}{
+		{"", "", "/opt/oracle/sqlcl/bin/sql"},
+		{"", "/usr/local/bin/sql", "/usr/local/bin/sql"},
+		{"/bin/other-server", "", "/bin/other-server"},
+		{"/bin/other-server", "/usr/local/bin/sql", "/bin/other-server"},
+	} {
+		t.Setenv("MCP_COMMAND", tc.mcpCommand)
+		t.Setenv("SQL_PATH", tc.sqlPath)
+		if got := commandPath(cfg); got != tc.want {
+			t.Errorf("MCP_COMMAND=%q SQL_PATH=%q: commandPath() = %q, want %q", tc.mcpCommand, tc.sqlPath, got, tc.want)
+		}
+	}
+
+	// Without a PathEnvVar, MCP_COMMAND still applies, and is expanded
+	t.Setenv("TOOLS_DIR", "/opt/tools")
+	t.Setenv("MCP_COMMAND", "$TOOLS_DIR/server")
+	t.Setenv("MCP_EXPAND_ENV", "true")
+	if got := commandPath(Config{CommandPath: "server"}); got != "/opt/tools/server" {
+		t.Errorf("commandPath() = %q, want /opt/tools/server", got)
+	}
+}
@@ -0,0 +1,248 @@
+package mcpproxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// auditQueueSize bounds the audit entries waiting to be written. Once it is
+// full, entries are dropped rather than holding up requests.
+const auditQueueSize = 1024
+
+// auditLog writes a JSON line per tools/call to Config.AuditLogFile from a
+// goroutine of its own, rotating the file by size.
+//
+// A nil *auditLog records nothing.
+type auditLog struct {
+	path     string
+	maxBytes int64
+	backups  int
+	logger   *logger
+
+	entries chan []byte
+	dropped atomic.Int64
+	stop    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+
+	// Only used by the writer goroutine
+	file *os.File
+	size int64
+}
+
+// auditEntry is one line of the audit log.
+type auditEntry struct {
+	Time       string          `json:"time"`
+	Server     string          `json:"server"`
+	ClientIP   string          `json:"client_ip"`
+	ClientID   string          `json:"client_id,omitempty"`
+	RequestID  json.RawMessage `json:"request_id"`
+	Tool       string          `json:"tool"`
+	Arguments  json.RawMessage `json:"arguments,omitempty"`
+	Status     string          `json:"status"` // ok, tool_error or error
+	HTTPStatus int             `json:"http_status"`
+	ErrorCode  int             `json:"error_code,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	DurationMS float64         `json:"duration_ms"`
+}
+
+// newAuditLog opens the audit log at path, appending to an existing one, and
+// starts its writer.
+func newAuditLog(path string, maxBytes int64, backups int, lg *logger) (*auditLog, error) {
+	a := &auditLog{
+		path:     path,
+		maxBytes: maxBytes,
+		backups:  backups,
+		logger:   lg,
+		entries:  make(chan []byte, auditQueueSize),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	if err := a.open(); err != nil {
+		return nil, fmt.Errorf("invalid AUDIT_LOG_FILE: %w", err)
+	}
+	go a.run()
+	return a, nil
+}
+
+// record queues entry for writing without blocking.
+func (a *auditLog) record(entry auditEntry) {
+	if a == nil {
+		return
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		a.logger.warnf("Failed to encode audit entry: %v", err)
+		return
+	}
+	select {
+	case a.entries <- append(line, '\n'):
+	default:
+		if a.dropped.Add(1) == 1 {
+			a.logger.errorf("Audit log queue full, dropping entries; see mcp_audit_dropped_total")
+		}
+	}
+}
+
+// close writes the queued entries and closes the file.
+func (a *auditLog) close() {
+	if a == nil {
+		return
+	}
+	a.once.Do(func() { close(a.stop) })
+	<-a.done
+}
+
+func (a *auditLog) run() {
+	defer close(a.done)
+	for {
+		select {
+		case line := <-a.entries:
+			a.write(line)
+		case <-a.stop:
+			for {
+				select {
+				case line := <-a.entries:
+					a.write(line)
+				default:
+					if a.file != nil {
+						a.file.Close()
+					}
+					return
+				}
+			}
+		}
+	}
+}
+
+func (a *auditLog) open() error {
+	f, err := os.OpenFile(a.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	a.file, a.size = f, info.Size()
+	return nil
+}
+
+func (a *auditLog) write(line []byte) {
+	if a.maxBytes > 0 && a.size > 0 && a.size+int64(len(line)) > a.maxBytes {
+		a.rotate()
+	}
+	if a.file == nil {
+		if err := a.open(); err != nil {
+			a.logger.errorf("Failed to open audit log: %v", err)
+			return
+		}
+	}
+	n, err := a.file.Write(line)
+	a.size += int64(n)
+	if err != nil {
+		a.logger.errorf("Failed to write audit log: %v", err)
+	}
+}
+
+// rotate renames the file to path.1, shifting older backups up and removing
+// the oldest, so the next write starts a new file.
+func (a *auditLog) rotate() {
+	a.file.Close()
+	a.file = nil
+	if a.backups <= 0 {
+		os.Remove(a.path)
+		return
+	}
+	for i := a.backups - 1; i > 0; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", a.path, i), fmt.Sprintf("%s.%d", a.path, i+1))
+	}
+	if err := os.Rename(a.path, a.path+".1"); err != nil {
+		a.logger.errorf("Failed to rotate audit log: %v", err)
+	}
+}
+
+// auditToolCall records a tools/call answered on w, once the response has
+// been written.
+func (p *MCPProxy) auditToolCall(r *http.Request, msg json.RawMessage, w *accessWriter, start time.Time) {
+	var call struct {
+		ID     json.RawMessage `json:"id"`
+		Params struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		} `json:"params"`
+	}
+	json.Unmarshal(msg, &call)
+
+	clientIP, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		clientIP = r.RemoteAddr
+	}
+	entry := auditEntry{
+		Time:       start.UTC().Format(time.RFC3339Nano),
+		Server:     p.config.ServerName,
+		ClientIP:   clientIP,
+		ClientID:   r.Header.Get("X-Client-Id"),
+		RequestID:  call.ID,
+		Tool:       call.Params.Name,
+		Arguments:  call.Params.Arguments,
+		HTTPStatus: w.status,
+		DurationMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if entry.HTTPStatus == 0 {
+		entry.HTTPStatus = http.StatusOK
+	}
+	if len(entry.Arguments) > 0 && !p.config.AuditRawArguments {
+		entry.Arguments = p.redactArguments(entry.Arguments)
+	}
+
+	entry.Status, entry.ErrorCode, entry.Error = responseOutcome(w.body)
+	if entry.Status == "ok" && entry.HTTPStatus != http.StatusOK {
+		entry.Status = "error"
+	}
+	if entry.Status == "error" && entry.ErrorCode == 0 {
+		// Not a JSON-RPC response, e.g. a rejected HTTP body
+		entry.Error = http.StatusText(entry.HTTPStatus)
+	}
+	p.audit.record(entry)
+}
+
+// redactArguments masks sensitive keys in tool arguments, and applies
+// Config.LogRedactor to their string values, so the SQL a tool ran is kept
+// without the passwords in it.
+func (p *MCPProxy) redactArguments(args json.RawMessage) json.RawMessage {
+	var v interface{}
+	if err := json.Unmarshal(args, &v); err != nil {
+		return nil
+	}
+	v = redactStrings(redactValue(v, p.config.RedactKeys), p.config.redactText)
+	out, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return out
+}
+
+// redactStrings applies redact to every string in v.
+func redactStrings(v interface{}, redact func(string) string) interface{} {
+	switch val := v.(type) {
+	case string:
+		return redact(val)
+	case map[string]interface{}:
+		for k, child := range val {
+			val[k] = redactStrings(child, redact)
+		}
+	case []interface{}:
+		for i, child := range val {
+			val[i] = redactStrings(child, redact)
+		}
+	}
+	return v
+}
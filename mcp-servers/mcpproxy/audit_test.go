@@ -0,0 +1,148 @@
+package mcpproxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readAuditLog(t *testing.T, path string) []auditEntry {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []auditEntry
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var e auditEntry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("Invalid audit line %q: %v", line, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func TestHandleAuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	audit, err := newAuditLog(path, 1<<20, 1, newLogger("test", "warn"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxy := &MCPProxy{
+		config: Config{
+			ServerName:  "test",
+			RedactKeys:  defaultRedactKeys,
+			DeniedTools: []string{"drop-database"},
+			LogRedactor: func(s string) string { return strings.ReplaceAll(s, "tiger", "***") },
+		},
+		logger:   newLogger("test", "warn"),
+		requests: make(chan *request, 1),
+		audit:    audit,
+	}
+	drainRequests(proxy, func(msg json.RawMessage) json.RawMessage {
+		var req struct {
+			Params struct {
+				Name string `json:"name"`
+			} `json:"params"`
+		}
+		json.Unmarshal(msg, &req)
+		switch req.Params.Name {
+		case "failing":
+			return json.RawMessage(fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"result":{"content":[],"isError":true}}`, rawID(msg)))
+		case "missing":
+			return json.RawMessage(fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"error":{"code":-32602,"message":"unknown tool"}}`, rawID(msg)))
+		}
+		return json.RawMessage(fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"result":{"content":[]}}`, rawID(msg)))
+	})
+	defer close(proxy.requests)
+
+	for _, body := range []string{
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"run-sql","arguments":{"sql":"ALTER USER app IDENTIFIED BY tiger","password":"hunter2"}}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"failing"}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"missing"}}`,
+		`{"jsonrpc":"2.0","id":"five","method":"tools/call","params":{"name":"drop-database"}}`,
+	} {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(body))
+		r.RemoteAddr = "203.0.113.7:4000"
+		r.Header.Set("X-Client-Id", "agent-7")
+		proxy.Handle(httptest.NewRecorder(), r)
+	}
+	audit.close()
+
+	entries := readAuditLog(t, path)
+	if len(entries) != 4 {
+		t.Fatalf("Expected an entry per tools/call only, got %+v", entries)
+	}
+	first := entries[0]
+	if first.Tool != "run-sql" || first.Status != "ok" || first.HTTPStatus != 200 ||
+		first.ClientIP != "203.0.113.7" || first.ClientID != "agent-7" || string(first.RequestID) != "1" || first.Server != "test" {
+		t.Errorf("Unexpected first entry %+v", first)
+	}
+	if args := string(first.Arguments); args != `{"password":"[REDACTED]","sql":"ALTER USER app IDENTIFIED BY ***"}` {
+		t.Errorf("Expected redacted arguments, got %s", args)
+	}
+	if _, err := time.Parse(time.RFC3339Nano, first.Time); err != nil {
+		t.Errorf("Expected an RFC 3339 time, got %q", first.Time)
+	}
+
+	if e := entries[1]; e.Status != "tool_error" {
+		t.Errorf("Expected tool_error for isError results, got %+v", e)
+	}
+	if e := entries[2]; e.Status != "error" || e.ErrorCode != -32602 || e.Error != "unknown tool" {
+		t.Errorf("Expected the JSON-RPC error, got %+v", e)
+	}
+	if e := entries[3]; e.Status != "error" || e.ErrorCode != CodeNotAllowed || string(e.RequestID) != `"five"` {
+		t.Errorf("Expected the blocked call to be audited, got %+v", e)
+	}
+}
+
+func TestAuditLogRotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	audit, err := newAuditLog(path, 150, 2, newLogger("test", "warn"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		audit.record(auditEntry{Tool: fmt.Sprintf("tool-%d", i), Status: "ok"})
+	}
+	audit.close()
+
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Fatalf("Expected %s: %v", name, err)
+		}
+		if info.Size() > 150 {
+			t.Errorf("Expected %s within the size limit, got %d bytes", name, info.Size())
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("Expected only 2 backups, got %v", err)
+	}
+	if entries := readAuditLog(t, path); entries[len(entries)-1].Tool != "tool-9" {
+		t.Errorf("Expected the newest entry in the current file, got %+v", entries)
+	}
+}
+
+func TestAuditLogDropsWhenFull(t *testing.T) {
+	// No writer is running, so the queue fills up
+	audit := &auditLog{entries: make(chan []byte, 1), logger: newLogger("test", "error")}
+	audit.record(auditEntry{Tool: "a"})
+	audit.record(auditEntry{Tool: "b"})
+	if got := audit.dropped.Load(); got != 1 {
+		t.Errorf("Expected 1 dropped entry, got %d", got)
+	}
+}
+
+func TestNewAuditLogInvalidPath(t *testing.T) {
+	if _, err := newAuditLog(filepath.Join(t.TempDir(), "missing", "audit.log"), 0, 0, nil); err == nil {
+		t.Error("Expected an error for a file in a missing directory")
+	}
+}
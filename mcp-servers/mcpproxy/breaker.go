@@ -0,0 +1,142 @@
+package mcpproxy
+
+import (
+	"sync"
+	"time"
+)
+
+// breakerState is the state of a circuit breaker, exported as mcp_breaker_state.
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+var breakerStateNames = map[breakerState]string{
+	breakerClosed:   "closed",
+	breakerOpen:     "open",
+	breakerHalfOpen: "half-open",
+}
+
+func (s breakerState) String() string {
+	return breakerStateNames[s]
+}
+
+// breaker stops sending requests to an MCP server that keeps failing, so a
+// persistent outage (bad config, missing license) gets fast 503s instead of
+// a restart per request. After threshold consecutive failures within window
+// it opens for cooldown, then lets a single probe request through: success
+// closes it again, failure reopens it.
+//
+// A nil *breaker always allows requests.
+type breaker struct {
+	threshold int
+	window    time.Duration
+	cooldown  time.Duration
+	logger    *logger
+	now       func() time.Time
+
+	mu           sync.Mutex
+	state        breakerState
+	failures     int
+	firstFailure time.Time
+	openedAt     time.Time
+	probing      bool
+}
+
+// newBreaker returns a breaker, or nil if threshold disables it.
+func newBreaker(threshold int, window, cooldown time.Duration, lg *logger) *breaker {
+	if threshold <= 0 {
+		return nil
+	}
+	return &breaker{threshold: threshold, window: window, cooldown: cooldown, logger: lg, now: time.Now}
+}
+
+// allow reports whether a request may proceed. Every allowed request must be
+// followed by a call to record.
+func (b *breaker) allow() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.setState(breakerHalfOpen)
+		b.probing = true
+		return true
+	case breakerHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	}
+	return true
+}
+
+// record reports the outcome of an allowed request.
+func (b *breaker) record(ok bool) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	if b.state == breakerHalfOpen {
+		b.probing = false
+		if ok {
+			b.failures = 0
+			b.setState(breakerClosed)
+		} else {
+			b.openedAt = now
+			b.setState(breakerOpen)
+		}
+		return
+	}
+
+	if ok {
+		b.failures = 0
+		return
+	}
+	if b.failures == 0 || now.Sub(b.firstFailure) > b.window {
+		b.failures = 0
+		b.firstFailure = now
+	}
+	b.failures++
+	if b.state == breakerClosed && b.failures >= b.threshold {
+		b.openedAt = now
+		b.setState(breakerOpen)
+	}
+}
+
+// currentState returns the breaker's state. b.mu must not be held.
+func (b *breaker) currentState() breakerState {
+	if b == nil {
+		return breakerClosed
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// setState changes state and logs the transition. b.mu must be held.
+func (b *breaker) setState(state breakerState) {
+	if state == b.state {
+		return
+	}
+	switch state {
+	case breakerOpen:
+		b.logger.warnf("Circuit breaker %s -> %s, rejecting requests for %s", b.state, state, b.cooldown)
+	default:
+		b.logger.infof("Circuit breaker %s -> %s", b.state, state)
+	}
+	b.state = state
+}
@@ -0,0 +1,147 @@
+package mcpproxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestBreaker(now *time.Time) *breaker {
+	b := newBreaker(3, time.Minute, 30*time.Second, nil)
+	b.now = func() time.Time { return *now }
+	return b
+}
+
+func TestBreakerOpensAfterThreshold(t *testing.T) {
+	now := time.Now()
+	b := newTestBreaker(&now)
+
+	for i := 0; i < 3; i++ {
+		if !b.allow() {
+			t.Fatalf("Request %d: expected closed breaker to allow", i)
+		}
+		b.record(false)
+	}
+	if got := b.currentState(); got != breakerOpen {
+		t.Fatalf("Expected open after 3 failures, got %s", got)
+	}
+	if b.allow() {
+		t.Error("Expected open breaker to reject")
+	}
+}
+
+func TestBreakerSuccessResetsFailures(t *testing.T) {
+	now := time.Now()
+	b := newTestBreaker(&now)
+
+	b.record(false)
+	b.record(false)
+	b.record(true)
+	b.record(false)
+	if got := b.currentState(); got != breakerClosed {
+		t.Errorf("Expected success to reset the failure count, got %s", got)
+	}
+}
+
+func TestBreakerFailuresOutsideWindow(t *testing.T) {
+	now := time.Now()
+	b := newTestBreaker(&now)
+
+	b.record(false)
+	b.record(false)
+	now = now.Add(2 * time.Minute)
+	b.record(false)
+	if got := b.currentState(); got != breakerClosed {
+		t.Errorf("Expected failures outside the window not to open the breaker, got %s", got)
+	}
+}
+
+func TestBreakerHalfOpenProbe(t *testing.T) {
+	now := time.Now()
+	b := newTestBreaker(&now)
+	for i := 0; i < 3; i++ {
+		b.record(false)
+	}
+
+	// After the cooldown a single probe is let through
+	now = now.Add(31 * time.Second)
+	if !b.allow() {
+		t.Fatal("Expected a probe after the cooldown")
+	}
+	if b.allow() {
+		t.Error("Expected only one probe while half-open")
+	}
+
+	// A failed probe reopens the breaker for another cooldown
+	b.record(false)
+	if got := b.currentState(); got != breakerOpen {
+		t.Fatalf("Expected failed probe to reopen, got %s", got)
+	}
+	if b.allow() {
+		t.Error("Expected reopened breaker to reject")
+	}
+
+	// A successful probe closes it
+	now = now.Add(31 * time.Second)
+	if !b.allow() {
+		t.Fatal("Expected a probe after the second cooldown")
+	}
+	b.record(true)
+	if got := b.currentState(); got != breakerClosed {
+		t.Errorf("Expected successful probe to close, got %s", got)
+	}
+}
+
+func TestNilBreakerAllows(t *testing.T) {
+	b := newBreaker(-1, time.Minute, time.Minute, nil)
+	if b != nil {
+		t.Fatal("Expected a negative threshold to disable the breaker")
+	}
+	b.record(false)
+	if !b.allow() || b.currentState() != breakerClosed {
+		t.Error("Expected a nil breaker to always allow")
+	}
+}
+
+func TestHandleRejectsWhileBreakerOpen(t *testing.T) {
+	now := time.Now()
+	proxy := &MCPProxy{
+		config:   Config{ServerName: "test"},
+		requests: make(chan *request, 1),
+		breaker:  newTestBreaker(&now),
+	}
+	proxy.exited.Store(true)
+	proxy.registerMetrics()
+
+	call := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		proxy.Handle(w, httptest.NewRequest("POST", "/", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`)))
+		return w
+	}
+	for i := 0; i < 3; i++ {
+		if w := call(); !strings.Contains(w.Body.String(), errServerExited) {
+			t.Fatalf("Request %d: expected %q, got %s", i, errServerExited, w.Body.String())
+		}
+	}
+
+	w := call()
+	var resp struct {
+		Error struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusServiceUnavailable || resp.Error.Message != errBreakerOpen {
+		t.Errorf("Expected 503 %q once the breaker opened, got %d %s", errBreakerOpen, w.Code, w.Body.String())
+	}
+
+	m := httptest.NewRecorder()
+	defaultRegistry.ServeHTTP(m, httptest.NewRequest("GET", "/metrics", nil))
+	if !strings.Contains(m.Body.String(), "mcp_breaker_state 1\n") {
+		t.Errorf("Expected mcp_breaker_state 1, got:\n%s", m.Body.String())
+	}
+}
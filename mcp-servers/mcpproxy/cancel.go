@@ -0,0 +1,180 @@
+package mcpproxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// errClientCancelled is the cause of a request cancelled by its client's
+// notifications/cancelled.
+var errClientCancelled = errors.New("request cancelled by the client")
+
+// pendingRequests tracks requests waiting for the MCP server, so a client can
+// cancel its own with notifications/cancelled, and stuck ones can be found
+// at /debug/pending. Entries are keyed by correlation id, since clients
+// pick their own ids and often reuse them, starting from 1.
+type pendingRequests struct {
+	mu      sync.Mutex
+	entries map[string]*pendingEntry
+}
+
+type pendingEntry struct {
+	cancel        context.CancelCauseFunc
+	client        string // the client that may cancel the request
+	id            interface{}
+	method        string
+	correlationID string
+	since         time.Time
+}
+
+// add registers a pending request and returns a func that removes it.
+func (pr *pendingRequests) add(e *pendingEntry) func() {
+	pr.mu.Lock()
+	if pr.entries == nil {
+		pr.entries = make(map[string]*pendingEntry)
+	}
+	pr.entries[e.correlationID] = e
+	pr.mu.Unlock()
+
+	return func() {
+		pr.mu.Lock()
+		if pr.entries[e.correlationID] == e {
+			delete(pr.entries, e.correlationID)
+		}
+		pr.mu.Unlock()
+	}
+}
+
+// pendingOwner identifies who may cancel the request r carries: its session
+// when Config.SessionFunc assigns it one, which is keyed by the client's
+// credentials, else the client IP as resolved through TRUSTED_PROXIES.
+// Headers such as X-Client-Id are set by the client, so they aren't used.
+func (p *MCPProxy) pendingOwner(r *http.Request) string {
+	if p.config.SessionFunc != nil {
+		if key, _ := p.config.SessionFunc(r); key != "" {
+			return "session:" + key
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// cancel cancels client's pending request with id if it is the only one,
+// returning how many matched. Clients behind one address may reuse ids, and
+// cancelling the wrong one is worse than cancelling none.
+func (pr *pendingRequests) cancel(client string, id interface{}) int {
+	pr.mu.Lock()
+	var key string
+	var match *pendingEntry
+	n := 0
+	for k, e := range pr.entries {
+		if e.client == client && formatID(e.id) == formatID(id) {
+			key, match = k, e
+			n++
+		}
+	}
+	if n == 1 {
+		delete(pr.entries, key)
+	}
+	pr.mu.Unlock()
+
+	if n == 1 {
+		match.cancel(errClientCancelled)
+	}
+	return n
+}
+
+// stats returns how many requests are pending and how long the oldest has
+// been.
+func (pr *pendingRequests) stats() (n int, oldest time.Duration) {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	for _, e := range pr.entries {
+		if age := time.Since(e.since); age > oldest {
+			oldest = age
+		}
+	}
+	return len(pr.entries), oldest
+}
+
+// pendingView is a pending request as served at /debug/pending.
+type pendingView struct {
+	ID            interface{} `json:"id"`
+	Method        string      `json:"method"`
+	CorrelationID string      `json:"correlation_id"`
+	AgeSeconds    float64     `json:"age_seconds"`
+}
+
+// list returns the pending requests, oldest first.
+func (pr *pendingRequests) list() []pendingView {
+	pr.mu.Lock()
+	views := make([]pendingView, 0, len(pr.entries))
+	for _, e := range pr.entries {
+		views = append(views, pendingView{
+			ID:            e.id,
+			Method:        e.method,
+			CorrelationID: e.correlationID,
+			AgeSeconds:    time.Since(e.since).Seconds(),
+		})
+	}
+	pr.mu.Unlock()
+	sort.Slice(views, func(i, j int) bool { return views[i].AgeSeconds > views[j].AgeSeconds })
+	return views
+}
+
+// handleDebugPending serves the requests waiting for the MCP server, oldest
+// first, with their id, method, correlation id and age.
+func (p *MCPProxy) handleDebugPending(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p.pending.list())
+}
+
+// cancelPending handles a client's notifications/cancelled for one of its
+// pending requests, reporting whether it matched any. The request's waiter
+// is answered with an error and the MCP server is sent the cancellation, or
+// never sees the request if it was still queued. A cancellation matching
+// several requests is dropped rather than forwarded.
+func (p *MCPProxy) cancelPending(r *http.Request, msg json.RawMessage) bool {
+	var n struct {
+		Params struct {
+			RequestID interface{} `json:"requestId"`
+		} `json:"params"`
+	}
+	dec := json.NewDecoder(bytes.NewReader(msg))
+	dec.UseNumber()
+	if dec.Decode(&n) != nil || n.Params.RequestID == nil {
+		return false
+	}
+	switch matched := p.pending.cancel(p.pendingOwner(r), n.Params.RequestID); matched {
+	case 0:
+		return false
+	case 1:
+		p.logger.infof("Client cancelled request %s", formatID(n.Params.RequestID))
+	default:
+		p.logger.warnf("Ignoring cancellation of request %s: %d pending requests from the client share that id", formatID(n.Params.RequestID), matched)
+	}
+	return true
+}
+
+// cancelReason describes why a request's context is done, for the
+// notifications/cancelled sent to the MCP server.
+func cancelReason(cause error) string {
+	switch {
+	case errors.Is(cause, context.DeadlineExceeded):
+		return "request timed out"
+	case errors.Is(cause, errClientCancelled):
+		return "request cancelled by the client"
+	default:
+		return "client disconnected"
+	}
+}
@@ -0,0 +1,173 @@
+package mcpproxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClientCancelUnblocksPendingRequest(t *testing.T) {
+	// Answers the first request only once it is cancelled, then echoes
+	proxy, err := NewMCPProxy(Config{
+		ServerName:  "test",
+		CommandPath: "sh",
+		CommandArgs: []string{"-c", `read req; read cancel; echo "$cancel" >&2; echo '{"jsonrpc":"2.0","id":7,"error":{"code":-32800,"message":"cancelled"}}'; exec cat`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		w := httptest.NewRecorder()
+		proxy.Handle(w, httptest.NewRequest("POST", "/", strings.NewReader(`{"jsonrpc":"2.0","id":7,"method":"tools/call"}`)))
+		done <- w
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		proxy.pending.mu.Lock()
+		n := len(proxy.pending.entries)
+		proxy.pending.mu.Unlock()
+		if n > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	w := httptest.NewRecorder()
+	proxy.Handle(w, httptest.NewRequest("POST", "/", strings.NewReader(`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":7,"reason":"user aborted"}}`)))
+	if w.Code != http.StatusAccepted {
+		t.Errorf("Expected 202 for the cancellation, got %d", w.Code)
+	}
+	select {
+	case w := <-done:
+		if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"code":-32800`) || !strings.Contains(w.Body.String(), `"id":7`) {
+			t.Errorf("Expected a cancellation error for request 7, got %d: %s", w.Code, w.Body.String())
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the cancellation to unblock the pending request")
+	}
+
+	// The server was told, and the stream stays in step
+	if w := ping(proxy); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"method":"ping"`) {
+		t.Errorf("Expected the echoed ping, got %d: %s", w.Code, w.Body.String())
+	}
+	if lines := strings.Join(proxy.stderr.snapshot(), "\n"); !strings.Contains(lines, `"requestId":7`) || !strings.Contains(lines, "cancelled by the client") {
+		t.Errorf("Expected the server to be sent the cancellation, got %q", lines)
+	}
+}
+
+func TestUnmatchedCancelIsForwarded(t *testing.T) {
+	p := &MCPProxy{
+		config:   Config{ServerName: "test"},
+		logger:   newLogger("test", "warn"),
+		requests: make(chan *request, 1),
+	}
+	forwarded := make(chan string, 1)
+	go func() {
+		for req := range p.requests {
+			forwarded <- string(req.msg)
+			close(req.response)
+		}
+	}()
+
+	w := httptest.NewRecorder()
+	p.Handle(w, httptest.NewRequest("POST", "/", strings.NewReader(`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":"x"}}`)))
+	if w.Code != http.StatusAccepted {
+		t.Errorf("Expected 202, got %d", w.Code)
+	}
+	if msg := <-forwarded; !strings.Contains(msg, "notifications/cancelled") {
+		t.Errorf("Expected an unknown request's cancellation to reach the server, got %q", msg)
+	}
+}
+
+func TestPendingMatchedByClient(t *testing.T) {
+	var pr pendingRequests
+	cancelled := false
+	remove := pr.add(&pendingEntry{client: "ip:192.0.2.1", id: json.Number("7"), correlationID: "c1", cancel: func(error) { cancelled = true }})
+	if pr.cancel("ip:203.0.113.9", json.Number("7")) != 0 || pr.cancel("ip:192.0.2.1", "7") != 0 {
+		t.Error("Expected no match for another client's request or a string id")
+	}
+	if pr.cancel("ip:192.0.2.1", float64(7)) != 1 || !cancelled {
+		t.Error("Expected the client's own request to be cancelled")
+	}
+	remove()
+	if pr.cancel("ip:192.0.2.1", json.Number("7")) != 0 {
+		t.Error("Expected a cancelled request to be removed")
+	}
+
+	// Two requests sharing an id can't be told apart, so neither is cancelled
+	cancelled = false
+	pr.add(&pendingEntry{client: "ip:192.0.2.1", id: json.Number("1"), correlationID: "c2", cancel: func(error) { cancelled = true }})
+	pr.add(&pendingEntry{client: "ip:192.0.2.1", id: json.Number("1"), correlationID: "c3", cancel: func(error) { cancelled = true }})
+	if n := pr.cancel("ip:192.0.2.1", json.Number("1")); n != 2 || cancelled {
+		t.Errorf("Expected an ambiguous cancellation to match 2 and cancel none, got %d", n)
+	}
+}
+
+func TestPendingOwnerIgnoresClientID(t *testing.T) {
+	p := &MCPProxy{}
+	a := httptest.NewRequest("POST", "/", nil)
+	a.RemoteAddr = "192.0.2.1:1234"
+	a.Header.Set("X-Client-Id", "agent-7")
+	b := httptest.NewRequest("POST", "/", nil)
+	b.RemoteAddr = "203.0.113.9:1234"
+	b.Header.Set("X-Client-Id", "agent-7")
+	if p.pendingOwner(a) == p.pendingOwner(b) {
+		t.Errorf("Expected clients at different addresses to differ despite X-Client-Id, got %q", p.pendingOwner(a))
+	}
+
+	p.config.SessionFunc = headerSession
+	a.Header.Set("X-Token", "alice")
+	b.Header.Set("X-Token", "alice")
+	if got := p.pendingOwner(a); got != "session:alice" || p.pendingOwner(b) != got {
+		t.Errorf("Expected the session to identify the client, got %q and %q", got, p.pendingOwner(b))
+	}
+}
+
+func TestDebugPending(t *testing.T) {
+	proxy := &MCPProxy{config: Config{ServerName: "test"}}
+	if n, oldest := proxy.pending.stats(); n != 0 || oldest != 0 {
+		t.Errorf("Expected nothing pending, got %d, %s", n, oldest)
+	}
+	proxy.pending.add(&pendingEntry{id: json.Number("1"), method: "ping", correlationID: "c2", since: time.Now()})
+	proxy.pending.add(&pendingEntry{id: "slow", method: "tools/call", correlationID: "c1", since: time.Now().Add(-time.Minute)})
+	// Another client reusing the same id is counted too
+	proxy.pending.add(&pendingEntry{id: json.Number("1"), method: "ping", correlationID: "c3", since: time.Now()})
+
+	if n, oldest := proxy.pending.stats(); n != 3 || oldest < time.Minute {
+		t.Errorf("Expected 3 pending, the oldest a minute old, got %d, %s", n, oldest)
+	}
+
+	w := httptest.NewRecorder()
+	proxy.newAdminMux().ServeHTTP(w, httptest.NewRequest("GET", "/debug/pending", nil))
+	var views []pendingView
+	if err := json.Unmarshal(w.Body.Bytes(), &views); err != nil {
+		t.Fatalf("Invalid body %q: %v", w.Body.String(), err)
+	}
+	if len(views) != 3 || views[0].ID != "slow" || views[0].Method != "tools/call" || views[0].CorrelationID != "c1" ||
+		views[0].AgeSeconds < 60 || views[1].ID != float64(1) || views[2].ID != float64(1) {
+		t.Errorf("Expected the requests oldest first, got %+v", views)
+	}
+}
+
+func TestCancelReason(t *testing.T) {
+	tests := map[error]string{
+		context.DeadlineExceeded: "request timed out",
+		errClientCancelled:       "request cancelled by the client",
+		context.Canceled:         "client disconnected",
+		errors.New("other"):      "client disconnected",
+	}
+	for cause, want := range tests {
+		if got := cancelReason(cause); got != want {
+			t.Errorf("cancelReason(%v) = %q, want %q", cause, got, want)
+		}
+	}
+}
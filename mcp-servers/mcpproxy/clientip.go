@@ -0,0 +1,124 @@
+package mcpproxy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// trustedProxies are the load balancers and ingresses whose X-Forwarded-For
+// and X-Real-IP headers are believed. A nil trustedProxies trusts nobody, so
+// clients can't spoof their address.
+type trustedProxies []*net.IPNet
+
+// parseTrustedProxies parses Config.TrustedProxies, each an IP address or a
+// CIDR range.
+func parseTrustedProxies(entries []string) (trustedProxies, error) {
+	var nets trustedProxies
+	for _, entry := range entries {
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid TRUSTED_PROXIES entry %q: not an IP address or CIDR range", entry)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TRUSTED_PROXIES entry %q: not an IP address or CIDR range", entry)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+// trusts reports whether addr, an IP address, is a trusted proxy.
+func (t trustedProxies) trusts(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, n := range t {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the address of the client that sent r. When r comes from
+// a trusted proxy, that is the last X-Forwarded-For entry not added by a
+// trusted proxy, or else X-Real-IP; otherwise it is the peer address.
+func (t trustedProxies) clientIP(r *http.Request) string {
+	peer, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		peer = r.RemoteAddr
+	}
+	if !t.trusts(peer) {
+		return peer
+	}
+
+	// Each proxy appends the address it got the request from, so walk back
+	// from the nearest one; only the entries added by trusted proxies are
+	// reliable.
+	var hops []string
+	for _, h := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(h, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if net.ParseIP(hop) == nil {
+			break
+		}
+		if i == 0 || !t.trusts(hop) {
+			return hop
+		}
+	}
+	if ip := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(ip) != nil {
+		return ip
+	}
+	return peer
+}
+
+// clientIDKey is the context key of an X-Client-Id header passed on by a
+// trusted proxy; see withClientIP.
+type clientIDKey struct{}
+
+// withClientIP returns r with RemoteAddr set to the real client address when
+// r comes through a trusted proxy, so rate limiting and logs see the client
+// rather than the load balancer. The proxy's X-Client-Id, if any, is kept in
+// the context for clientKey.
+func (p *MCPProxy) withClientIP(r *http.Request) *http.Request {
+	if len(p.trusted) == 0 {
+		return r
+	}
+	peer, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		peer = r.RemoteAddr
+	}
+	ip := p.trusted.clientIP(r)
+	var id string
+	if p.trusted.trusts(peer) {
+		id = r.Header.Get("X-Client-Id")
+	}
+	if ip == peer && id == "" {
+		return r
+	}
+
+	ctx := r.Context()
+	if id != "" {
+		ctx = context.WithValue(ctx, clientIDKey{}, id)
+	}
+	r = r.Clone(ctx)
+	if ip != peer {
+		r.RemoteAddr = net.JoinHostPort(ip, "0")
+	}
+	return r
+}
@@ -0,0 +1,150 @@
+package mcpproxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseTrustedProxies(t *testing.T) {
+	trusted, err := parseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.5", "::1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for addr, want := range map[string]bool{
+		"10.1.2.3":    true,
+		"192.168.1.5": true,
+		"192.168.1.6": false,
+		"::1":         true,
+		"not-an-ip":   false,
+	} {
+		if got := trusted.trusts(addr); got != want {
+			t.Errorf("trusts(%q) = %v, want %v", addr, got, want)
+		}
+	}
+
+	for _, entry := range []string{"10.0.0.0/33", "lb.internal"} {
+		if _, err := parseTrustedProxies([]string{entry}); err == nil {
+			t.Errorf("Expected an error for %q", entry)
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	trusted, _ := parseTrustedProxies([]string{"10.0.0.0/8"})
+	tests := []struct {
+		name      string
+		trusted   trustedProxies
+		peer      string
+		forwarded string
+		realIP    string
+		want      string
+	}{
+		{"no trusted proxies", nil, "10.0.0.1:4000", "203.0.113.7", "", "10.0.0.1"},
+		{"untrusted peer", trusted, "198.51.100.2:4000", "203.0.113.7", "203.0.113.8", "198.51.100.2"},
+		{"trusted peer", trusted, "10.0.0.1:4000", "203.0.113.7", "", "203.0.113.7"},
+		{"spoofed entry before the client", trusted, "10.0.0.1:4000", "1.2.3.4, 203.0.113.7", "", "203.0.113.7"},
+		{"chain of trusted proxies", trusted, "10.0.0.1:4000", "203.0.113.7, 10.0.0.2", "", "203.0.113.7"},
+		{"only trusted hops", trusted, "10.0.0.1:4000", "10.0.0.3, 10.0.0.2", "", "10.0.0.3"},
+		{"X-Real-IP", trusted, "10.0.0.1:4000", "", "203.0.113.8", "203.0.113.8"},
+		{"malformed header", trusted, "10.0.0.1:4000", "garbage", "", "10.0.0.1"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.RemoteAddr = tt.peer
+		if tt.forwarded != "" {
+			r.Header.Set("X-Forwarded-For", tt.forwarded)
+		}
+		if tt.realIP != "" {
+			r.Header.Set("X-Real-IP", tt.realIP)
+		}
+		if got := tt.trusted.clientIP(r); got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestHandleRateLimitsForwardedClients(t *testing.T) {
+	trusted, _ := parseTrustedProxies([]string{"10.0.0.1"})
+	proxy := &MCPProxy{
+		config:   Config{ServerName: "test"},
+		logger:   newLogger("test", "warn"),
+		requests: make(chan *request, 1),
+		limiter:  newRateLimiter(1, 1),
+		trusted:  trusted,
+	}
+	drainRequests(proxy, func(json.RawMessage) json.RawMessage {
+		return json.RawMessage(`{"jsonrpc":"2.0","id":1,"result":{}}`)
+	})
+	defer close(proxy.requests)
+
+	call := func(peer, forwarded string) int {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+		r.RemoteAddr = peer
+		r.Header.Set("X-Forwarded-For", forwarded)
+		w := httptest.NewRecorder()
+		proxy.Handle(w, r)
+		return w.Code
+	}
+
+	// Clients behind the load balancer each get their own bucket
+	if code := call("10.0.0.1:4000", "203.0.113.7"); code != http.StatusOK {
+		t.Errorf("Expected 200 for the first client, got %d", code)
+	}
+	if code := call("10.0.0.1:4000", "203.0.113.8"); code != http.StatusOK {
+		t.Errorf("Expected 200 for the second client, got %d", code)
+	}
+	if code := call("10.0.0.1:4000", "203.0.113.7"); code != http.StatusTooManyRequests {
+		t.Errorf("Expected 429 for the first client's second request, got %d", code)
+	}
+
+	// A client connecting directly can't escape its bucket with the header
+	if code := call("198.51.100.2:4000", "203.0.113.9"); code != http.StatusOK {
+		t.Errorf("Expected 200 for the direct client, got %d", code)
+	}
+	if code := call("198.51.100.2:4000", "203.0.113.10"); code != http.StatusTooManyRequests {
+		t.Errorf("Expected 429 for the direct client despite a new X-Forwarded-For, got %d", code)
+	}
+}
+
+func TestHandleRateLimitIgnoresUntrustedClientID(t *testing.T) {
+	trusted, _ := parseTrustedProxies([]string{"10.0.0.1"})
+	proxy := &MCPProxy{
+		config:   Config{ServerName: "test"},
+		logger:   newLogger("test", "warn"),
+		requests: make(chan *request, 1),
+		limiter:  newRateLimiter(1, 1),
+		trusted:  trusted,
+	}
+	drainRequests(proxy, func(json.RawMessage) json.RawMessage {
+		return json.RawMessage(`{"jsonrpc":"2.0","id":1,"result":{}}`)
+	})
+	defer close(proxy.requests)
+
+	call := func(peer, clientID string) int {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+		r.RemoteAddr = peer
+		r.Header.Set("X-Client-Id", clientID)
+		w := httptest.NewRecorder()
+		proxy.Handle(w, r)
+		return w.Code
+	}
+
+	// A client connecting directly can't rotate X-Client-Id for fresh buckets
+	if code := call("198.51.100.2:4000", "a"); code != http.StatusOK {
+		t.Errorf("Expected 200 for the direct client, got %d", code)
+	}
+	if code := call("198.51.100.2:4000", "b"); code != http.StatusTooManyRequests {
+		t.Errorf("Expected 429 for the direct client despite a new X-Client-Id, got %d", code)
+	}
+
+	// The load balancer's X-Client-Id is believed
+	if code := call("10.0.0.1:4000", "a"); code != http.StatusOK {
+		t.Errorf("Expected 200 for client a behind the load balancer, got %d", code)
+	}
+	if code := call("10.0.0.1:4000", "b"); code != http.StatusOK {
+		t.Errorf("Expected 200 for client b behind the load balancer, got %d", code)
+	}
+}
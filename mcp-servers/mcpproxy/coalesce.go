@@ -0,0 +1,103 @@
+package mcpproxy
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// flightGroup lets identical requests in flight share one round trip to the
+// MCP server, for the methods in Config.CoalesceMethods. It is a minimal
+// single-flight, so the proxy doesn't need golang.org/x/sync.
+type flightGroup struct {
+	methods map[string]bool
+
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+// flightCall is a request in flight whose response other requests wait for.
+type flightCall struct {
+	done     chan struct{}
+	response json.RawMessage // set by the leader before done is closed
+}
+
+func newFlightGroup(methods []string) *flightGroup {
+	g := &flightGroup{
+		methods: make(map[string]bool, len(methods)),
+		calls:   make(map[string]*flightCall),
+	}
+	for _, m := range methods {
+		g.methods[m] = true
+	}
+	return g
+}
+
+// coalesces reports whether requests for method may share a response.
+func (g *flightGroup) coalesces(method string) bool {
+	return g != nil && g.methods[method]
+}
+
+// join returns the call in flight for key, or starts one with the caller as
+// its leader. The leader must call finish once it has set the response.
+func (g *flightGroup) join(key string) (call *flightCall, leader bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if call := g.calls[key]; call != nil {
+		return call, false
+	}
+	call = &flightCall{done: make(chan struct{})}
+	g.calls[key] = call
+	return call, true
+}
+
+// finish releases the requests waiting for call.
+func (g *flightGroup) finish(key string, call *flightCall) {
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+	close(call.done)
+}
+
+// wait returns the leader's response, or nil if the leader failed or ctx is
+// done first.
+func (c *flightCall) wait(ctx context.Context) json.RawMessage {
+	select {
+	case <-c.done:
+		return c.response
+	case <-ctx.Done():
+		return nil
+	}
+}
+
+// flightKey identifies requests to target that would get the same response:
+// same method and same params, ignoring formatting. Session processes act for
+// different clients, so their requests are never shared across sessions.
+func (p *MCPProxy) flightKey(target *MCPProxy, msg json.RawMessage) string {
+	var req struct {
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"`
+	}
+	json.Unmarshal(msg, &req)
+
+	params := req.Params
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(params))
+	dec.UseNumber()
+	if dec.Decode(&v) == nil {
+		params, _ = json.Marshal(v)
+	}
+
+	h := sha256.New()
+	if p.sessions != nil {
+		fmt.Fprintf(h, "%p\x00", target)
+	}
+	h.Write([]byte(req.Method))
+	h.Write([]byte{0})
+	h.Write(params)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,101 @@
+package mcpproxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// slowEchoServer logs each message to stderr and echoes it back after a delay.
+var slowEchoServer = Config{
+	ServerName:  "test",
+	CommandPath: "sh",
+	CommandArgs: []string{"-c", `while read -r line; do echo "$line" >&2; sleep 0.3; echo "$line"; done`},
+}
+
+func TestCoalesceIdenticalRequests(t *testing.T) {
+	cfg := slowEchoServer
+	cfg.CoalesceMethods = []string{"tools/list"}
+	proxy, err := NewMCPProxy(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+
+	var wg sync.WaitGroup
+	responses := make([]*httptest.ResponseRecorder, 10)
+	for i := range responses {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// Formatting differences don't matter
+			body := fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"tools/list","params":{ "a" : 1 }}`, i)
+			if i%2 == 1 {
+				body = fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"tools/list","params":{"a":1}}`, i)
+			}
+			w := httptest.NewRecorder()
+			proxy.Handle(w, httptest.NewRequest("POST", "/", strings.NewReader(body)))
+			responses[i] = w
+		}(i)
+	}
+	wg.Wait()
+
+	for i, w := range responses {
+		var resp struct {
+			ID int `json:"id"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if w.Code != http.StatusOK || resp.ID != i {
+			t.Errorf("Request %d: expected 200 with its own id, got %d: %s", i, w.Code, w.Body.String())
+		}
+	}
+	if calls := len(proxy.stderr.snapshot()); calls != 1 {
+		t.Errorf("Expected one call to the MCP server, got %d", calls)
+	}
+}
+
+func TestCoalesceOnlyConfiguredMethods(t *testing.T) {
+	cfg := slowEchoServer
+	cfg.CoalesceMethods = []string{"tools/list"}
+	proxy, err := NewMCPProxy(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+
+	var wg sync.WaitGroup
+	for _, body := range []string{
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"x"}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"x"}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/list","params":{"cursor":"a"}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/list","params":{"cursor":"b"}}`,
+	} {
+		wg.Add(1)
+		go func(body string) {
+			defer wg.Done()
+			proxy.Handle(httptest.NewRecorder(), httptest.NewRequest("POST", "/", strings.NewReader(body)))
+		}(body)
+	}
+	wg.Wait()
+
+	if calls := len(proxy.stderr.snapshot()); calls != 4 {
+		t.Errorf("Expected each distinct or unlisted request to reach the MCP server, got %d calls", calls)
+	}
+}
+
+func TestFlightKeySeparatesSessions(t *testing.T) {
+	p := &MCPProxy{}
+	a, b := &MCPProxy{}, &MCPProxy{}
+	msg := json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)
+	if p.flightKey(a, msg) != p.flightKey(b, msg) {
+		t.Error("Expected instances of one server to share a key")
+	}
+	p.sessions = &sessionPool{}
+	if p.flightKey(a, msg) == p.flightKey(b, msg) {
+		t.Error("Expected different session processes to have different keys")
+	}
+}
@@ -0,0 +1,55 @@
+package mcpproxy
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// shouldCompress reports whether a response of size bytes should be gzipped
+// for this request.
+func (p *MCPProxy) shouldCompress(r *http.Request, size int) bool {
+	if !p.config.EnableCompression || size < p.config.CompressionMinBytes || prefersEventStream(r) {
+		return false
+	}
+	return acceptsGzip(r.Header.Get("Accept-Encoding"))
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip,
+// honoring an explicit "gzip;q=0" refusal.
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+			continue
+		}
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if q, err := strconv.ParseFloat(v, 64); err == nil && q == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// writeGzip writes the response gzip-compressed. The compressed size isn't
+// known up front, so the body is sent without a Content-Length.
+func (p *MCPProxy) writeGzip(w http.ResponseWriter, response []byte) {
+	w.Header().Set("Content-Encoding", "gzip")
+	w.WriteHeader(http.StatusOK)
+
+	gz := gzip.NewWriter(w)
+	if _, err := gz.Write(response); err != nil {
+		p.logger.warnf("Failed to write compressed response: %v", err)
+		return
+	}
+	if err := gz.Close(); err != nil {
+		p.logger.warnf("Failed to write compressed response: %v", err)
+		return
+	}
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,87 @@
+package mcpproxy
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAcceptsGzip(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{"", false},
+		{"gzip", true},
+		{"deflate, gzip;q=1.0, br", true},
+		{"GZIP", true},
+		{"gzip;q=0", false},
+		{"gzip; q=0.000", false},
+		{"br, deflate", false},
+	}
+
+	for _, tt := range tests {
+		if got := acceptsGzip(tt.header); got != tt.want {
+			t.Errorf("acceptsGzip(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestHandleCompression(t *testing.T) {
+	large := `{"jsonrpc":"2.0","id":1,"result":{"text":"` + strings.Repeat("row ", 1000) + `"}}`
+	small := `{"jsonrpc":"2.0","id":1,"result":{}}`
+
+	tests := []struct {
+		name           string
+		enabled        bool
+		acceptEncoding string
+		response       string
+		wantGzip       bool
+	}{
+		{"compresses large responses", true, "gzip", large, true},
+		{"skips small responses", true, "gzip", small, false},
+		{"skips clients without gzip", true, "", large, false},
+		{"off by default", false, "gzip", large, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxy := &MCPProxy{
+				config:   Config{ServerName: "test", EnableCompression: tt.enabled, CompressionMinBytes: 1024},
+				requests: make(chan *request, 1),
+			}
+			drainRequests(proxy, func(json.RawMessage) json.RawMessage { return json.RawMessage(tt.response) })
+			defer close(proxy.requests)
+
+			req := httptest.NewRequest("POST", "/", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/call"}`))
+			req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			w := httptest.NewRecorder()
+			proxy.Handle(w, req)
+
+			body := w.Body.String()
+			if gotGzip := w.Header().Get("Content-Encoding") == "gzip"; gotGzip != tt.wantGzip {
+				t.Fatalf("Expected gzip=%v, got Content-Encoding %q", tt.wantGzip, w.Header().Get("Content-Encoding"))
+			}
+			if tt.wantGzip {
+				if w.Header().Get("Content-Length") != "" {
+					t.Error("Expected no Content-Length on a compressed response")
+				}
+				zr, err := gzip.NewReader(w.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				data, err := io.ReadAll(zr)
+				if err != nil {
+					t.Fatal(err)
+				}
+				body = string(data)
+			}
+			if body != tt.response {
+				t.Errorf("Expected response %q, got %q", tt.response, body)
+			}
+		})
+	}
+}
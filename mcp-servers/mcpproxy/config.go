@@ -0,0 +1,374 @@
+package mcpproxy
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JSON-RPC error codes returned by the proxy itself.
+// Rejections use the exported codes in errcodes.go.
+const (
+	codeServerBusy        = -32000
+	codeServerUnavailable = -32002
+	codeRequestTimeout    = -32003
+	codeRequestCancelled  = -32800
+)
+
+// errServerExited is the error message for requests to an MCP server that has
+// exited or stopped responding.
+const errServerExited = "MCP server is not running"
+
+// errServerRestarting is the error message for requests that failed because
+// the MCP server exited and is being restarted.
+const errServerRestarting = "MCP server exited and is restarting, try again shortly"
+
+// errDraining is the error message for requests rejected while draining.
+const errDraining = "proxy is draining, not accepting new requests"
+
+// errShuttingDown is the error message for requests that arrive once the
+// proxy has started shutting down.
+const errShuttingDown = "server shutting down"
+
+// errRequestTimeout is the error message for requests whose deadline passed.
+const errRequestTimeout = "MCP server did not answer before the request timeout"
+
+// errBreakerOpen is the error message while the circuit breaker is open.
+const errBreakerOpen = "MCP server is failing repeatedly, try again later"
+
+// commandName names a server after its command, e.g. "github-mcp-server" for
+// /server/github-mcp-server, so several proxies' logs can be told apart.
+func (c *Config) commandName() string {
+	path := commandPath(*c)
+	if path == "" {
+		return "mcp-server"
+	}
+	return filepath.Base(path)
+}
+
+// applyDefaults fills in unset fields and applies environment overrides.
+// Environment variables take precedence over values set in code so operators
+// can tune a deployment without rebuilding the image.
+func (c *Config) applyDefaults() {
+	c.envErrs = nil
+
+	if v := os.Getenv("MCP_SERVER_NAME"); v != "" {
+		c.ServerName = v
+	}
+	if c.ServerName == "" {
+		c.ServerName = c.commandName()
+	}
+
+	if v := os.Getenv("MCP_BACKEND_TYPE"); v != "" {
+		c.BackendType = v
+	}
+	if c.BackendType == "" {
+		c.BackendType = backendStdio
+	}
+	if v := os.Getenv("MCP_UPSTREAM_URL"); v != "" {
+		c.UpstreamURL = v
+	}
+
+	if c.Port == "" {
+		c.Port = "8080"
+	}
+	if v := os.Getenv("LISTEN_ADDR"); v != "" {
+		c.ListenAddr = v
+	}
+	if v := os.Getenv("LISTEN_UNIX"); v != "" {
+		c.ListenUnix = v
+	}
+
+	c.EnablePprof = envBool(&c.envErrs, "ENABLE_PPROF", c.EnablePprof)
+	if v := os.Getenv("ADMIN_TOKEN"); v != "" {
+		c.AdminToken = v
+	}
+	if v := os.Getenv("ADMIN_ADDR"); v != "" {
+		c.AdminAddr = v
+	}
+	if c.AdminAddr == "" {
+		c.AdminAddr = "127.0.0.1:6060"
+	}
+	if v := os.Getenv("ADMIN_PORT"); v != "" {
+		c.AdminPort = v
+	}
+	c.EnableDiagnostics = envBool(&c.envErrs, "ENABLE_DIAGNOSTICS", c.EnableDiagnostics)
+
+	if v := os.Getenv("MCP_CWD"); v != "" {
+		c.WorkDir = v
+	}
+	if v := os.Getenv("MCP_RUN_AS_UID"); v != "" {
+		c.RunAsUID = v
+	}
+	if v := os.Getenv("MCP_RUN_AS_GID"); v != "" {
+		c.RunAsGID = v
+	}
+	c.Nice = envInt(&c.envErrs, "MCP_NICE", c.Nice)
+	if v := os.Getenv("MCP_CPU_AFFINITY"); v != "" {
+		c.CPUAffinity = v
+	}
+
+	if v := os.Getenv("MCP_DELIMITER"); v != "" {
+		c.Delimiter = v
+	}
+	if c.Delimiter == "" {
+		c.Delimiter = `\n`
+	}
+
+	c.QueueSize = envInt(&c.envErrs, "MCP_QUEUE_SIZE", c.QueueSize)
+	if c.QueueSize <= 0 {
+		c.QueueSize = 100
+	}
+	c.FastNotifications = envBool(&c.envErrs, "FAST_NOTIFICATIONS", c.FastNotifications)
+
+	c.MaxRequestBytes = envInt(&c.envErrs, "MAX_REQUEST_BYTES", c.MaxRequestBytes)
+	if c.MaxRequestBytes <= 0 {
+		c.MaxRequestBytes = 4 << 20
+	}
+
+	c.WarnMessageBytes = envInt(&c.envErrs, "MCP_WARN_MESSAGE_BYTES", c.WarnMessageBytes)
+	if c.WarnMessageBytes == 0 {
+		c.WarnMessageBytes = 1 << 20
+	}
+
+	c.WriteTimeout = envDuration(&c.envErrs, "MCP_WRITE_TIMEOUT", c.WriteTimeout)
+	if c.WriteTimeout == 0 {
+		c.WriteTimeout = 30 * time.Second
+	}
+
+	c.RequestTimeout = envDuration(&c.envErrs, "MCP_REQUEST_TIMEOUT", c.RequestTimeout)
+	c.MaxRequestTimeout = envDuration(&c.envErrs, "MCP_MAX_REQUEST_TIMEOUT", c.MaxRequestTimeout)
+	c.CancelKillGrace = envDuration(&c.envErrs, "MCP_CANCEL_KILL_GRACE", c.CancelKillGrace)
+	if c.MaxRequestTimeout == 0 {
+		c.MaxRequestTimeout = 10 * time.Minute
+	}
+
+	c.MaxRestarts = envInt(&c.envErrs, "MCP_MAX_RESTARTS", c.MaxRestarts)
+	if c.MaxRestarts == 0 {
+		c.MaxRestarts = 5
+	}
+	if v := os.Getenv("MCP_STOP_SIGNAL"); v != "" {
+		c.StopSignal = v
+	}
+	if c.StopSignal == "" {
+		c.StopSignal = stopStdinClose + ",SIGTERM"
+	}
+	c.StopTimeout = envDuration(&c.envErrs, "MCP_STOP_TIMEOUT", c.StopTimeout)
+	if c.StopTimeout <= 0 {
+		c.StopTimeout = 5 * time.Second
+	}
+	if v := os.Getenv("CRASH_WEBHOOK_URL"); v != "" {
+		c.CrashWebhookURL = v
+	}
+
+	c.EnableMetrics = envBool(&c.envErrs, "ENABLE_METRICS", c.EnableMetrics)
+	c.ResourceSampleInterval = envDuration(&c.envErrs, "RESOURCE_SAMPLE_INTERVAL", c.ResourceSampleInterval)
+	if c.ResourceSampleInterval <= 0 {
+		c.ResourceSampleInterval = 15 * time.Second
+	}
+	c.MaxRSSBytes = envInt(&c.envErrs, "MCP_MAX_RSS_BYTES", c.MaxRSSBytes)
+	c.MaxRSSWindow = envDuration(&c.envErrs, "MCP_MAX_RSS_WINDOW", c.MaxRSSWindow)
+	if c.MaxRSSWindow <= 0 {
+		c.MaxRSSWindow = time.Minute
+	}
+	c.HealthPingInterval = envDuration(&c.envErrs, "HEALTH_PING_INTERVAL", c.HealthPingInterval)
+	c.HealthPingTimeout = envDuration(&c.envErrs, "HEALTH_PING_TIMEOUT", c.HealthPingTimeout)
+	if c.HealthPingTimeout <= 0 {
+		c.HealthPingTimeout = 30 * time.Second
+	}
+	c.HealthPingFailures = envInt(&c.envErrs, "HEALTH_PING_FAILURES", c.HealthPingFailures)
+	if c.HealthPingFailures <= 0 {
+		c.HealthPingFailures = 3
+	}
+
+	c.BreakerThreshold = envInt(&c.envErrs, "BREAKER_THRESHOLD", c.BreakerThreshold)
+	if c.BreakerThreshold == 0 {
+		c.BreakerThreshold = 5
+	}
+	c.BreakerWindow = envDuration(&c.envErrs, "BREAKER_WINDOW", c.BreakerWindow)
+	if c.BreakerWindow <= 0 {
+		c.BreakerWindow = time.Minute
+	}
+	c.BreakerCooldown = envDuration(&c.envErrs, "BREAKER_COOLDOWN", c.BreakerCooldown)
+	if c.BreakerCooldown <= 0 {
+		c.BreakerCooldown = 30 * time.Second
+	}
+
+	c.MaxSessions = envInt(&c.envErrs, "MCP_MAX_SESSIONS", c.MaxSessions)
+	if c.MaxSessions <= 0 {
+		c.MaxSessions = 10
+	}
+	c.MaxSessionsPerClient = envInt(&c.envErrs, "MCP_MAX_SESSIONS_PER_CLIENT", c.MaxSessionsPerClient)
+	if v := os.Getenv("MCP_READY_PROBE"); v != "" {
+		c.ReadyPattern = v
+	}
+	c.StartupDelay = envDuration(&c.envErrs, "MCP_STARTUP_DELAY", c.StartupDelay)
+	c.ReadyRetries = envInt(&c.envErrs, "MCP_READY_RETRIES", c.ReadyRetries)
+	if c.ReadyRetries == 0 {
+		c.ReadyRetries = 3
+	}
+	c.ReadyRetryBackoff = envDuration(&c.envErrs, "MCP_READY_RETRY_BACKOFF", c.ReadyRetryBackoff)
+	if c.ReadyRetryBackoff <= 0 {
+		c.ReadyRetryBackoff = 500 * time.Millisecond
+	}
+
+	c.Instances = envInt(&c.envErrs, "MCP_INSTANCES", c.Instances)
+	if c.Instances <= 0 {
+		c.Instances = 1
+	}
+	c.SessionIdleTimeout = envDuration(&c.envErrs, "MCP_SESSION_IDLE_TIMEOUT", c.SessionIdleTimeout)
+	if c.SessionIdleTimeout <= 0 {
+		c.SessionIdleTimeout = 10 * time.Minute
+	}
+
+	c.StderrBufferLines = envInt(&c.envErrs, "STDERR_BUFFER_LINES", c.StderrBufferLines)
+	if c.StderrBufferLines <= 0 {
+		c.StderrBufferLines = 200
+	}
+
+	c.RateLimitRPS = envFloat(&c.envErrs, "RATE_LIMIT_RPS", c.RateLimitRPS)
+	c.RateLimitBurst = envInt(&c.envErrs, "RATE_LIMIT_BURST", c.RateLimitBurst)
+	c.TrustedProxies = envList("TRUSTED_PROXIES", c.TrustedProxies)
+
+	c.ToolsCacheTTL = envDuration(&c.envErrs, "TOOLS_CACHE_TTL", c.ToolsCacheTTL)
+	c.StrictJSONRPC = envBool(&c.envErrs, "STRICT_JSONRPC", c.StrictJSONRPC)
+	c.EnableCORS = envBool(&c.envErrs, "ENABLE_CORS", c.EnableCORS)
+	c.EnableCompression = envBool(&c.envErrs, "ENABLE_COMPRESSION", c.EnableCompression)
+	c.EnableH2C = envBool(&c.envErrs, "ENABLE_H2C", c.EnableH2C)
+	c.CompressionMinBytes = envInt(&c.envErrs, "COMPRESSION_MIN_BYTES", c.CompressionMinBytes)
+	if c.CompressionMinBytes <= 0 {
+		c.CompressionMinBytes = 1024
+	}
+
+	if c.MaxResponseRetries <= 0 {
+		c.MaxResponseRetries = 3
+	}
+
+	c.AllowedTools = envList("MCP_ALLOWED_TOOLS", c.AllowedTools)
+	c.DeniedTools = envList("MCP_DENIED_TOOLS", c.DeniedTools)
+	c.CoalesceMethods = envList("MCP_COALESCE_METHODS", c.CoalesceMethods)
+
+	if v := os.Getenv("LOG_LEVEL"); v != "" {
+		c.LogLevel = v
+	}
+	c.LogPayloads = envBool(&c.envErrs, "LOG_PAYLOADS", c.LogPayloads)
+	if v := os.Getenv("LOG_SKIPPED_NOTIFICATIONS"); v != "" {
+		c.LogSkippedNotifications = v
+	}
+	c.RespectClientLogLevel = envBool(&c.envErrs, "RESPECT_CLIENT_LOG_LEVEL", c.RespectClientLogLevel)
+	c.AccessLog = envBool(&c.envErrs, "ACCESS_LOG", c.AccessLog)
+	if v := os.Getenv("AUDIT_LOG_FILE"); v != "" {
+		c.AuditLogFile = v
+	}
+	c.AuditLogMaxBytes = envInt(&c.envErrs, "AUDIT_LOG_MAX_BYTES", c.AuditLogMaxBytes)
+	if c.AuditLogMaxBytes == 0 {
+		c.AuditLogMaxBytes = 100 << 20
+	}
+	c.AuditLogBackups = envInt(&c.envErrs, "AUDIT_LOG_BACKUPS", c.AuditLogBackups)
+	if c.AuditLogBackups == 0 {
+		c.AuditLogBackups = 5
+	}
+	c.AuditRawArguments = envBool(&c.envErrs, "AUDIT_LOG_RAW_ARGUMENTS", c.AuditRawArguments)
+	c.RecentRequests = envInt(&c.envErrs, "MCP_RECENT_REQUESTS", c.RecentRequests)
+	c.RedactContentBytes = envInt(&c.envErrs, "MCP_REDACT_CONTENT_BYTES", c.RedactContentBytes)
+	c.RedactContentTypes = envList("MCP_REDACT_CONTENT_TYPES", c.RedactContentTypes)
+	c.DropRedactedContent = envBool(&c.envErrs, "MCP_REDACT_CONTENT_DROP", c.DropRedactedContent)
+	c.RedactKeys = envList("LOG_REDACT_KEYS", c.RedactKeys)
+	if len(c.RedactKeys) == 0 {
+		c.RedactKeys = defaultRedactKeys
+	}
+}
+
+// envInt returns the integer value of the named environment variable, or
+// def if it is unset or invalid. An invalid value is recorded in errs, or
+// logged if errs is nil.
+func envInt(errs *configErrors, name string, def int) int {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		envInvalid(errs, fmt.Errorf("invalid %s %q: not a whole number", name, v), "use a whole number, e.g. 10")
+		return def
+	}
+	return n
+}
+
+// envFloat returns the floating-point value of the named environment
+// variable, or def if it is unset or invalid. An invalid value is recorded
+// in errs, or logged if errs is nil.
+func envFloat(errs *configErrors, name string, def float64) float64 {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		envInvalid(errs, fmt.Errorf("invalid %s %q: not a number", name, v), "use a number, e.g. 0.5")
+		return def
+	}
+	return f
+}
+
+// envBool returns the boolean value of the named environment variable, or
+// def if it is unset or invalid. An invalid value is recorded in errs, or
+// logged if errs is nil.
+func envBool(errs *configErrors, name string, def bool) bool {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		envInvalid(errs, fmt.Errorf("invalid %s %q: not a boolean", name, v), "use true or false")
+		return def
+	}
+	return b
+}
+
+// envDuration returns the duration value (e.g. "30s") of the named
+// environment variable, or def if it is unset or invalid. An invalid value
+// is recorded in errs, or logged if errs is nil.
+func envDuration(errs *configErrors, name string, def time.Duration) time.Duration {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		envInvalid(errs, fmt.Errorf("invalid %s %q: not a duration", name, v), "use a number with a unit, e.g. 30s or 5m")
+		return def
+	}
+	return d
+}
+
+// envInvalid records an invalid environment value in errs, or logs it when
+// the value is read after startup and errs is nil.
+func envInvalid(errs *configErrors, err error, hint string) {
+	if errs == nil {
+		log.Printf("Ignoring %v", err)
+		return
+	}
+	errs.add(err, hint)
+}
+
+// envList returns the comma-separated values of the named environment variable,
+// or def if it is unset. Surrounding whitespace and empty entries are dropped.
+func envList(name string, def []string) []string {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	var list []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
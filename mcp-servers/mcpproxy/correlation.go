@@ -0,0 +1,18 @@
+package mcpproxy
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// correlationIDHeader is the response header carrying the id the proxy gave an
+// HTTP request. The id prefixes the log lines about the request: receiving it,
+// sending it to the MCP server and reading the response.
+const correlationIDHeader = "X-Correlation-Id"
+
+// newCorrelationID returns a short random id for an HTTP request.
+func newCorrelationID() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,39 @@
+package mcpproxy
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestCorrelationID(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	proxy, err := NewMCPProxy(Config{ServerName: "test", CommandPath: "cat", LogLevel: "debug"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, second := ping(proxy), ping(proxy)
+	proxy.stop()
+
+	cid := first.Header().Get(correlationIDHeader)
+	if !regexp.MustCompile(`^[0-9a-f]{8}$`).MatchString(cid) {
+		t.Fatalf("Expected a short correlation id, got %q", cid)
+	}
+	if other := second.Header().Get(correlationIDHeader); other == "" || other == cid {
+		t.Errorf("Expected each request to get its own id, got %q twice", other)
+	}
+
+	// Every stage of the exchange is tagged with the same id
+	for _, stage := range []string{"HTTP request from", "Received HTTP request:", "Sending:", "Received:", "Sending HTTP response:"} {
+		want := "[" + cid + "] " + stage
+		if strings.Count(buf.String(), want) != 1 {
+			t.Errorf("Expected one %q line, got:\n%s", want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package mcpproxy
+
+import "net/http"
+
+// corsAllowedHeaders are the request headers browser clients may send: the
+// ones the proxy reads, and the bearer token of token sessions.
+const corsAllowedHeaders = "Content-Type, Accept, Authorization, X-Request-Timeout, X-Client-Id, traceparent, tracestate"
+
+// applyCORS adds the CORS headers when Config.EnableCORS is set, and answers
+// a preflight OPTIONS request itself, reporting true if it did.
+func (p *MCPProxy) applyCORS(w http.ResponseWriter, r *http.Request) bool {
+	if !p.current().enableCORS {
+		return false
+	}
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", corsAllowedHeaders)
+	w.Header().Set("Access-Control-Expose-Headers", correlationIDHeader)
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return true
+	}
+	return false
+}
+
+// withCORS applies the CORS headers to a route other than Handle, so browser
+// clients can read its responses, e.g. an adapter's error for a removed
+// endpoint, instead of getting an opaque CORS failure.
+func (p *MCPProxy) withCORS(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if p.applyCORS(w, r) {
+			return
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,72 @@
+package mcpproxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCORSOnExtraRoutes(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		var calls int
+		proxy := &MCPProxy{
+			config: Config{
+				ServerName: "test",
+				EnableCORS: enabled,
+				ExtraRoutes: map[string]http.HandlerFunc{
+					"/sse": func(w http.ResponseWriter, r *http.Request) {
+						calls++
+						http.Error(w, "use the streamable HTTP endpoint at /", http.StatusGone)
+					},
+				},
+			},
+			requests: make(chan *request, 1),
+		}
+		mux := proxy.newMux()
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("OPTIONS", "/sse", nil))
+		if enabled && (w.Code != http.StatusOK || w.Header().Get("Access-Control-Allow-Origin") != "*" || calls != 0) {
+			t.Errorf("Expected the preflight answered with CORS headers, got %d, %v, %d handler calls", w.Code, w.Header(), calls)
+		}
+
+		w = httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", "/sse", nil))
+		if w.Code != http.StatusGone {
+			t.Errorf("Expected the route's own response, got %d", w.Code)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Origin"); (got == "*") != enabled {
+			t.Errorf("enabled=%v: got Access-Control-Allow-Origin %q", enabled, got)
+		}
+	}
+}
+
+func TestCORSPreflightHeaders(t *testing.T) {
+	proxy := &MCPProxy{
+		config:   Config{ServerName: "test", EnableCORS: true, EnableDiagnostics: true, AdminToken: "secret"},
+		logger:   newLogger("test", "warn"),
+		stderr:   newLineBuffer(10),
+		requests: make(chan *request, 1),
+	}
+	mux := proxy.newMux()
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("OPTIONS", "/", nil))
+	allowed := w.Header().Get("Access-Control-Allow-Headers")
+	for _, h := range []string{"Content-Type", "Authorization", "X-Request-Timeout", "X-Client-Id", "traceparent", "tracestate"} {
+		if !strings.Contains(allowed, h) {
+			t.Errorf("Expected %s in Access-Control-Allow-Headers, got %q", h, allowed)
+		}
+	}
+
+	for _, path := range []string{"/logs", "/config"} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", path, nil)
+		r.Header.Set("Authorization", "Bearer secret")
+		mux.ServeHTTP(w, r)
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+			t.Errorf("Expected no CORS headers on %s, got Access-Control-Allow-Origin %q", path, got)
+		}
+	}
+}
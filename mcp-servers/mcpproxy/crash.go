@@ -0,0 +1,94 @@
+package mcpproxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"os/exec"
+	"time"
+)
+
+const (
+	// crashWebhookTimeout bounds a crash alert, so a slow receiver can't pile
+	// up goroutines.
+	crashWebhookTimeout = 5 * time.Second
+
+	// crashReapWait is how long a crash alert waits for the process to be
+	// reaped, by its restart or the proxy stopping, to report its exit code,
+	// and for its last stderr lines.
+	crashReapWait = 2 * time.Second
+
+	// crashStderrLines is how many recent stderr lines a crash alert carries.
+	crashStderrLines = 20
+)
+
+// crashAlert is the payload POSTed to Config.CrashWebhookURL.
+type crashAlert struct {
+	Server   string   `json:"server"`
+	Time     string   `json:"time"`
+	PID      int      `json:"pid"`
+	ExitCode *int     `json:"exit_code"` // null if the process hadn't exited yet
+	Error    string   `json:"error"`
+	Restarts int      `json:"restarts"`
+	Stderr   []string `json:"stderr"`
+}
+
+// alertCrash posts a crash alert for cmd, which became unreachable with err,
+// in the background. reaped is closed once cmd has been waited for, and
+// stderrDone once its stderr has been read. p.io must be held.
+func (p *MCPProxy) alertCrash(cmd *exec.Cmd, reaped, stderrDone <-chan struct{}, err error) {
+	if p.config.CrashWebhookURL == "" {
+		return
+	}
+	alert := crashAlert{
+		Server:   p.config.ServerName,
+		Time:     time.Now().UTC().Format(time.RFC3339),
+		PID:      cmd.Process.Pid,
+		Error:    err.Error(),
+		Restarts: int(p.restarts.Load()),
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), crashReapWait)
+		defer cancel()
+		select {
+		case <-reaped:
+			code := cmd.ProcessState.ExitCode()
+			alert.ExitCode = &code
+		case <-ctx.Done():
+		}
+		select {
+		case <-stderrDone:
+		case <-ctx.Done():
+		}
+		alert.Stderr = p.stderr.snapshot()
+		if n := len(alert.Stderr); n > crashStderrLines {
+			alert.Stderr = alert.Stderr[n-crashStderrLines:]
+		}
+		p.postCrashAlert(alert)
+	}()
+}
+
+func (p *MCPProxy) postCrashAlert(alert crashAlert) {
+	body, _ := json.Marshal(alert)
+	ctx, cancel := context.WithTimeout(context.Background(), crashWebhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.config.CrashWebhookURL, bytes.NewReader(body))
+	if err != nil {
+		p.logger.warnf("Failed to send crash alert: %v", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		p.logger.warnf("Failed to send crash alert: %v", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		p.logger.warnf("Crash webhook returned %s", resp.Status)
+		return
+	}
+	p.logger.debugf("Sent crash alert to the webhook")
+}
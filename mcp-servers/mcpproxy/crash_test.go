@@ -0,0 +1,93 @@
+package mcpproxy
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCrashWebhook(t *testing.T) {
+	alerts := make(chan []byte, 4)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Expected a JSON POST, got %s %s", r.Method, r.Header.Get("Content-Type"))
+		}
+		body, _ := io.ReadAll(r.Body)
+		alerts <- body
+	}))
+	defer srv.Close()
+
+	// The server dies on its first request
+	proxy, err := NewMCPProxy(Config{
+		ServerName:      "crashy",
+		CommandPath:     "sh",
+		CommandArgs:     []string{"-c", `read line; echo "out of memory" >&2; exit 3`},
+		CrashWebhookURL: srv.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+	pid := proxy.pid()
+
+	if w := ping(proxy); w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected 503 when the server dies, got %d", w.Code)
+	}
+
+	var body []byte
+	select {
+	case body = <-alerts:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a crash alert")
+	}
+
+	var alert map[string]interface{}
+	if err := json.Unmarshal(body, &alert); err != nil {
+		t.Fatalf("Invalid alert %s: %v", body, err)
+	}
+	for _, key := range []string{"server", "time", "pid", "exit_code", "error", "restarts", "stderr"} {
+		if _, ok := alert[key]; !ok {
+			t.Errorf("Expected %q in the alert, got %s", key, body)
+		}
+	}
+	if alert["server"] != "crashy" || alert["pid"] != float64(pid) || alert["exit_code"] != float64(3) || alert["restarts"] != float64(1) {
+		t.Errorf("Unexpected alert %s", body)
+	}
+	if stderr, _ := alert["stderr"].([]interface{}); len(stderr) == 0 || stderr[len(stderr)-1] != "out of memory" {
+		t.Errorf("Expected the last stderr lines, got %v", alert["stderr"])
+	}
+}
+
+func TestCrashWebhookNotSentOnStop(t *testing.T) {
+	alerts := make(chan struct{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		alerts <- struct{}{}
+	}))
+	defer srv.Close()
+
+	cfg := slowEchoServer
+	cfg.CrashWebhookURL = srv.URL
+	proxy, err := NewMCPProxy(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := proxy.restart(); err != nil {
+		t.Fatal(err)
+	}
+	proxy.stop()
+
+	select {
+	case <-alerts:
+		t.Error("Expected no crash alert for a restart or stop")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestCrashWebhookURLValidated(t *testing.T) {
+	if _, err := NewMCPProxy(Config{CommandPath: "cat", CrashWebhookURL: "hooks.example.com/alert"}); err == nil {
+		t.Error("Expected an error for a URL without a scheme")
+	}
+}
@@ -0,0 +1,135 @@
+package mcpproxy
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// subprocessEnv returns the environment for the MCP server: the proxy's own
+// environment followed by cfg.ExtraEnv, so extra values win over inherited ones.
+func subprocessEnv(cfg Config) []string {
+	return append(os.Environ(), cfg.ExtraEnv...)
+}
+
+// parseExtraEnv parses an MCP_EXTRA_ENV value. It is either a comma-separated
+// list of KEY=VALUE pairs, or "@" followed by the path of a dotenv-style file.
+func parseExtraEnv(s string) ([]string, error) {
+	if path := strings.TrimPrefix(s, "@"); path != s {
+		return loadEnvFile(path)
+	}
+
+	var env []string
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if key, _, ok := strings.Cut(entry, "="); !ok || key == "" {
+			return nil, fmt.Errorf("invalid entry %q, expected KEY=VALUE", entry)
+		}
+		env = append(env, entry)
+	}
+	return env, nil
+}
+
+// validateWorkDir checks that the subprocess working directory exists.
+func validateWorkDir(dir string) error {
+	if dir == "" {
+		return nil
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("MCP server working directory %q: %w", dir, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("MCP server working directory %q is not a directory", dir)
+	}
+	return nil
+}
+
+// loadEnvFile reads a dotenv-style file and returns its variables as KEY=VALUE
+// pairs. Blank lines and lines starting with # are ignored, an optional
+// "export " prefix is accepted, and values may be wrapped in single or double
+// quotes. Unquoted values are used verbatim, so a # inside them is not a comment.
+func loadEnvFile(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read env file: %w", err)
+	}
+
+	var env []string
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("%s:%d: expected KEY=VALUE", path, i+1)
+		}
+		value = strings.TrimSpace(value)
+		if n := len(value); n >= 2 && (value[0] == '"' || value[0] == '\'') && value[n-1] == value[0] {
+			value = value[1 : n-1]
+		}
+		env = append(env, key+"="+value)
+	}
+	return env, nil
+}
+
+// envKeys returns the keys of KEY=VALUE pairs, for logging without values.
+func envKeys(env []string) []string {
+	keys := make([]string, len(env))
+	for i, kv := range env {
+		keys[i], _, _ = strings.Cut(kv, "=")
+	}
+	return keys
+}
+
+// envExports records the variables an exported MCP_ENV_FILE set in the
+// proxy's environment, with the value each had before (nil if unset).
+type envExports map[string]*string
+
+// loadExportedEnvFile loads the MCP_ENV_FILE at path, if any, and with
+// MCP_ENV_FILE_EXPORT also sets its variables in the proxy's own environment.
+// Variables exported by an earlier load that the file no longer sets are
+// restored to their previous value.
+func loadExportedEnvFile(path string, exported envExports) ([]string, error) {
+	var env []string
+	if path != "" {
+		var err error
+		if env, err = loadEnvFile(path); err != nil {
+			return nil, fmt.Errorf("invalid MCP_ENV_FILE: %w", err)
+		}
+	}
+	set := make(map[string]bool)
+	if path != "" && envBool(nil, "MCP_ENV_FILE_EXPORT", false) {
+		for _, kv := range env {
+			key, value, _ := strings.Cut(kv, "=")
+			if _, ok := exported[key]; !ok {
+				if prev, ok := os.LookupEnv(key); ok {
+					exported[key] = &prev
+				} else {
+					exported[key] = nil
+				}
+			}
+			os.Setenv(key, value)
+			set[key] = true
+		}
+	}
+	for key, prev := range exported {
+		if set[key] {
+			continue
+		}
+		if prev != nil {
+			os.Setenv(key, *prev)
+		} else {
+			os.Unsetenv(key)
+		}
+		delete(exported, key)
+	}
+	return env, nil
+}
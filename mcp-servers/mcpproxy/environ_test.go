@@ -0,0 +1,145 @@
+package mcpproxy
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseExtraEnv(t *testing.T) {
+	file := t.TempDir() + "/extra.env"
+	if err := os.WriteFile(file, []byte("TNS_ADMIN=/opt/tns\n\nNLS_LANG=AMERICAN_AMERICA.AL32UTF8\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		in      string
+		want    []string
+		wantErr bool
+	}{
+		{"pairs", "A=1, B=two", []string{"A=1", "B=two"}, false},
+		{"value with equals", "OPTS=-Dx=y", []string{"OPTS=-Dx=y"}, false},
+		{"empty value", "EMPTY=", []string{"EMPTY="}, false},
+		{"file", "@" + file, []string{"TNS_ADMIN=/opt/tns", "NLS_LANG=AMERICAN_AMERICA.AL32UTF8"}, false},
+		{"missing file", "@" + file + ".missing", nil, true},
+		{"missing equals", "A=1,B", nil, true},
+		{"missing key", "=1", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseExtraEnv(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseExtraEnv(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseExtraEnv(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubprocessEnvAppendsExtra(t *testing.T) {
+	os.Setenv("MCP_TEST_INHERITED", "yes")
+	defer os.Unsetenv("MCP_TEST_INHERITED")
+
+	env := subprocessEnv(Config{ExtraEnv: []string{"MCP_TEST_EXTRA=1"}})
+
+	var inherited, extra bool
+	for _, kv := range env {
+		inherited = inherited || kv == "MCP_TEST_INHERITED=yes"
+		extra = extra || kv == "MCP_TEST_EXTRA=1"
+	}
+	if !inherited || !extra {
+		t.Errorf("Expected inherited and extra variables, got inherited=%v extra=%v", inherited, extra)
+	}
+	if env[len(env)-1] != "MCP_TEST_EXTRA=1" {
+		t.Error("Expected extra variables last so they override inherited ones")
+	}
+}
+
+func TestValidateWorkDir(t *testing.T) {
+	dir := t.TempDir()
+	file := dir + "/file"
+	os.WriteFile(file, nil, 0o600)
+
+	if err := validateWorkDir(""); err != nil {
+		t.Errorf("Expected empty dir to be allowed, got %v", err)
+	}
+	if err := validateWorkDir(dir); err != nil {
+		t.Errorf("Expected existing dir to be valid, got %v", err)
+	}
+	if err := validateWorkDir(dir + "/missing"); err == nil {
+		t.Error("Expected error for missing dir")
+	}
+	if err := validateWorkDir(file); err == nil {
+		t.Error("Expected error for a regular file")
+	}
+}
+
+func TestLoadEnvFile(t *testing.T) {
+	file := t.TempDir() + "/secrets.env"
+	content := `# Database credentials
+ORACLE_USER=scott
+
+export ORACLE_PWD="ti ger"
+GITHUB_PERSONAL_ACCESS_TOKEN='ghp_#abc'
+NO_COMMENT=a#b
+`
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := loadEnvFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"ORACLE_USER=scott", "ORACLE_PWD=ti ger", "GITHUB_PERSONAL_ACCESS_TOKEN=ghp_#abc", "NO_COMMENT=a#b"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("loadEnvFile() = %q, want %q", got, want)
+	}
+	if keys := envKeys(got); !reflect.DeepEqual(keys, []string{"ORACLE_USER", "ORACLE_PWD", "GITHUB_PERSONAL_ACCESS_TOKEN", "NO_COMMENT"}) {
+		t.Errorf("envKeys() = %q", keys)
+	}
+}
+
+func TestLoadEnvFileErrors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := loadEnvFile(dir + "/missing.env"); err == nil {
+		t.Error("Expected error for a missing file")
+	}
+
+	bad := dir + "/bad.env"
+	os.WriteFile(bad, []byte("OK=1\nnot a pair\n"), 0o600)
+	if _, err := loadEnvFile(bad); err == nil {
+		t.Error("Expected error for a line without '='")
+	}
+}
+
+func TestEnvFileNotLoggedWithValues(t *testing.T) {
+	file := t.TempDir() + "/secrets.env"
+	os.WriteFile(file, []byte("ORACLE_PWD=tiger\n"), 0o600)
+	os.Setenv("MCP_ENV_FILE", file)
+	defer os.Unsetenv("MCP_ENV_FILE")
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	proxy, err := NewMCPProxy(Config{ServerName: "test", CommandPath: "cat"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.cmd.Process.Kill()
+
+	if !strings.Contains(buf.String(), "ORACLE_PWD") || strings.Contains(buf.String(), "tiger") {
+		t.Errorf("Expected key but not value in logs, got:\n%s", buf.String())
+	}
+	if proxy.cmd.Env[len(proxy.cmd.Env)-1] != "ORACLE_PWD=tiger" {
+		t.Errorf("Expected env file variable in subprocess env")
+	}
+}
@@ -0,0 +1,67 @@
+package mcpproxy
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// JSON-RPC error codes for requests the proxy turns away before they reach
+// the MCP server. Request middlewares should reject with the same codes,
+// using an *RPCError, so clients can map them. Config.ErrorCodes changes the
+// codes actually sent.
+const (
+	// CodeNotAllowed rejects a call to a tool that isn't exposed.
+	CodeNotAllowed = -32001
+	// CodeRateLimited rejects a request over a client or tool rate limit.
+	CodeRateLimited = -32004
+	// CodeUnauthorized rejects a request without valid credentials.
+	CodeUnauthorized = -32005
+	// CodeInvalidRequest rejects a malformed request, and is used for
+	// middleware errors that aren't an *RPCError.
+	CodeInvalidRequest = -32600
+)
+
+// errorCodeNames are the names of the codes MCP_ERROR_CODES can change.
+var errorCodeNames = map[string]int{
+	"not_allowed":  CodeNotAllowed,
+	"rate_limited": CodeRateLimited,
+	"unauthorized": CodeUnauthorized,
+}
+
+// parseErrorCodes parses MCP_ERROR_CODES, comma-separated name=code pairs
+// such as "rate_limited=-32029,unauthorized=-32099", into a map from the
+// default code to the one to send instead.
+func parseErrorCodes(s string) (map[int]int, error) {
+	pairs, err := parseToolPairs(s)
+	if err != nil {
+		return nil, err
+	}
+	codes := make(map[int]int, len(pairs))
+	for name, v := range pairs {
+		from, ok := errorCodeNames[name]
+		if !ok {
+			names := make([]string, 0, len(errorCodeNames))
+			for n := range errorCodeNames {
+				names = append(names, n)
+			}
+			sort.Strings(names)
+			return nil, fmt.Errorf("unknown error %q, expected one of %v", name, names)
+		}
+		to, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid code %q for %s: must be an integer", v, name)
+		}
+		codes[from] = to
+	}
+	return codes, nil
+}
+
+// errorCode returns the code to send for the rejection code, as changed by
+// Config.ErrorCodes.
+func (c *Config) errorCode(code int) int {
+	if to, ok := c.ErrorCodes[code]; ok {
+		return to
+	}
+	return code
+}
@@ -0,0 +1,79 @@
+package mcpproxy
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseErrorCodes(t *testing.T) {
+	got, err := parseErrorCodes("rate_limited=-32029, unauthorized = -32099")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[int]int{CodeRateLimited: -32029, CodeUnauthorized: -32099}; !reflect.DeepEqual(got, want) {
+		t.Errorf("parseErrorCodes() = %v, want %v", got, want)
+	}
+	for _, in := range []string{"busy=-32000", "rate_limited=soon", "rate_limited"} {
+		if _, err := parseErrorCodes(in); err == nil {
+			t.Errorf("parseErrorCodes(%q): expected an error", in)
+		}
+	}
+}
+
+func TestRejectionCodes(t *testing.T) {
+	callTool := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"drop_table"}}`
+	reject := func(err error) RequestMiddleware {
+		return func([]byte) ([]byte, error) { return nil, err }
+	}
+	tests := []struct {
+		name  string
+		cfg   Config
+		body  string
+		calls int
+		want  int
+	}{
+		{"tool not allowed", Config{DeniedTools: []string{"drop_table"}}, callTool, 1, CodeNotAllowed},
+		{"client rate limit", Config{RateLimitRPS: 0.001, RateLimitBurst: 1}, callTool, 2, CodeRateLimited},
+		{"tool rate limit", Config{ToolRateLimits: map[string]float64{"drop_table": 0.001}}, callTool, 2, CodeRateLimited},
+		{"invalid request", Config{StrictJSONRPC: true}, `{"id":1,"method":"ping"}`, 1, CodeInvalidRequest},
+		{"middleware", Config{RequestMiddlewares: []RequestMiddleware{reject(errors.New("bad"))}}, callTool, 1, CodeInvalidRequest},
+		{"middleware RPCError", Config{RequestMiddlewares: []RequestMiddleware{reject(&RPCError{Code: CodeUnauthorized, Message: "no token"})}}, callTool, 1, CodeUnauthorized},
+		{"changed code", Config{DeniedTools: []string{"drop_table"}, ErrorCodes: map[int]int{CodeNotAllowed: -32099}}, callTool, 1, -32099},
+		{"changed middleware code", Config{
+			RequestMiddlewares: []RequestMiddleware{reject(&RPCError{Code: CodeUnauthorized, Message: "no token"})},
+			ErrorCodes:         map[int]int{CodeUnauthorized: -32098},
+		}, callTool, 1, -32098},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.ServerName = "test"
+			tt.cfg.CommandPath = "cat"
+			proxy, err := NewMCPProxy(tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer proxy.stop()
+
+			var w *httptest.ResponseRecorder
+			for i := 0; i < tt.calls; i++ {
+				w = httptest.NewRecorder()
+				proxy.Handle(w, httptest.NewRequest("POST", "/", strings.NewReader(tt.body)))
+			}
+			var resp struct {
+				Error *struct {
+					Code int `json:"code"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Error == nil {
+				t.Fatalf("Expected a JSON-RPC error, got %d: %s", w.Code, w.Body.String())
+			}
+			if resp.Error.Code != tt.want {
+				t.Errorf("Expected code %d, got %d", tt.want, resp.Error.Code)
+			}
+		})
+	}
+}
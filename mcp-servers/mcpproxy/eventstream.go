@@ -0,0 +1,186 @@
+package mcpproxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// prefersEventStream reports whether the client's Accept header ranks
+// text/event-stream above application/json, in which case the response to its
+// request is sent as Server-Sent Events. The "application/json,
+// text/event-stream" that streamable HTTP clients send keeps plain JSON.
+func prefersEventStream(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	return acceptQuality(accept, "text/event-stream") > acceptQuality(accept, "application/json")
+}
+
+// acceptQuality returns the q value an Accept header gives mediaType, from
+// its most specific matching range, or 0 if none matches.
+func acceptQuality(header, mediaType string) float64 {
+	typ, _, _ := strings.Cut(mediaType, "/")
+	q, best := 0.0, 0
+	for _, part := range strings.Split(header, ",") {
+		mt, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+		var specificity int
+		switch mt {
+		case mediaType:
+			specificity = 3
+		case typ + "/*":
+			specificity = 2
+		case "*/*":
+			specificity = 1
+		default:
+			continue
+		}
+		if specificity <= best {
+			continue
+		}
+		best, q = specificity, 1
+		if v, ok := params["q"]; ok {
+			q, _ = strconv.ParseFloat(v, 64)
+		}
+	}
+	return q
+}
+
+// eventStreamWriter sends each message written to it as a Server-Sent Event,
+// so a response and the progress notifications before it share the
+// request's stream. Once the stream has started, later status codes are
+// dropped, since the headers are already sent.
+type eventStreamWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (w *eventStreamWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Del("Content-Length")
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write sends b, a single JSON-RPC message, as one event.
+func (w *eventStreamWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	var event bytes.Buffer
+	event.WriteString("event: message\n")
+	for _, line := range bytes.Split(bytes.TrimSpace(b), []byte("\n")) {
+		event.WriteString("data: ")
+		event.Write(line)
+		event.WriteByte('\n')
+	}
+	event.WriteByte('\n')
+	if _, err := w.ResponseWriter.Write(event.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (w *eventStreamWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *eventStreamWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// send writes a notification as an event right away.
+func (w *eventStreamWriter) send(msg json.RawMessage) {
+	w.Write(msg)
+	w.Flush()
+}
+
+// progressStreams routes notifications/progress to the event stream of the
+// request that asked for them with a progressToken, instead of the GET
+// stream. A nil *progressStreams routes nothing.
+type progressStreams struct {
+	mu      sync.Mutex
+	streams map[string]chan json.RawMessage // by formatted progress token
+}
+
+func newProgressStreams() *progressStreams {
+	return &progressStreams{streams: make(map[string]chan json.RawMessage)}
+}
+
+// add routes the progress notifications for token to the returned channel
+// until the returned func is called.
+func (ps *progressStreams) add(token string) (<-chan json.RawMessage, func()) {
+	if ps == nil {
+		return nil, func() {}
+	}
+	ch := make(chan json.RawMessage, notificationBuffer)
+	ps.mu.Lock()
+	ps.streams[token] = ch
+	ps.mu.Unlock()
+	return ch, func() {
+		ps.mu.Lock()
+		delete(ps.streams, token)
+		ps.mu.Unlock()
+	}
+}
+
+// deliver hands a progress notification to the stream waiting for it,
+// reporting false if there is none. A stream that falls behind misses it.
+func (ps *progressStreams) deliver(msg json.RawMessage) bool {
+	if ps == nil {
+		return false
+	}
+	var n struct {
+		Params struct {
+			ProgressToken json.RawMessage `json:"progressToken"`
+		} `json:"params"`
+	}
+	if json.Unmarshal(msg, &n) != nil || n.Params.ProgressToken == nil {
+		return false
+	}
+	ps.mu.Lock()
+	ch, ok := ps.streams[formatToken(n.Params.ProgressToken)]
+	ps.mu.Unlock()
+	if ok {
+		select {
+		case ch <- msg:
+		default:
+		}
+	}
+	return ok
+}
+
+// progressToken returns the formatted params._meta.progressToken of a
+// request, or "" if it asks for no progress.
+func progressToken(msg json.RawMessage) string {
+	var m struct {
+		Params struct {
+			Meta struct {
+				ProgressToken json.RawMessage `json:"progressToken"`
+			} `json:"_meta"`
+		} `json:"params"`
+	}
+	if json.Unmarshal(msg, &m) != nil || m.Params.Meta.ProgressToken == nil {
+		return ""
+	}
+	return formatToken(m.Params.Meta.ProgressToken)
+}
+
+// formatToken compacts a progress token, so the request's and the
+// notification's compare equal whatever their spacing.
+func formatToken(raw json.RawMessage) string {
+	var buf bytes.Buffer
+	json.Compact(&buf, raw)
+	return buf.String()
+}
@@ -0,0 +1,84 @@
+package mcpproxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPrefersEventStream(t *testing.T) {
+	for accept, want := range map[string]bool{
+		"":                                    false,
+		"application/json":                    false,
+		"application/json, text/event-stream": false,
+		"*/*":                                 false,
+		"text/event-stream":                   true,
+		"text/*":                              true,
+		"text/event-stream, application/json;q=0.5": true,
+		"text/event-stream;q=0.5, application/json": false,
+		"application/json;q=0.9, */*":               true,
+	} {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.Header.Set("Accept", accept)
+		if got := prefersEventStream(r); got != want {
+			t.Errorf("prefersEventStream(%q) = %v, want %v", accept, got, want)
+		}
+	}
+}
+
+// emitsProgress is an MCP server that reports progress on every request
+// before answering it.
+var emitsProgress = Config{
+	ServerName:  "test",
+	CommandPath: "sh",
+	CommandArgs: []string{"-c", `while read line; do
+echo '{"jsonrpc":"2.0","method":"notifications/progress","params":{"progressToken":"t1","progress":1}}'
+echo '{"jsonrpc":"2.0","id":1,"result":{}}'
+done`},
+}
+
+func TestHandleAcceptEventStream(t *testing.T) {
+	proxy, err := NewMCPProxy(emitsProgress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+
+	const call = `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"slow","_meta":{"progressToken":"t1"}}}`
+	post := func(accept string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(call))
+		r.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		proxy.Handle(w, r)
+		return w
+	}
+
+	w := post("application/json, text/event-stream")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("Expected plain JSON, got %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	if body := strings.TrimSpace(w.Body.String()); body != `{"jsonrpc":"2.0","id":1,"result":{}}` {
+		t.Errorf("Expected only the response, got %q", body)
+	}
+
+	// The headers as sent, before the response tried to change them
+	w = post("text/event-stream")
+	if ct := w.Result().Header.Get("Content-Type"); w.Code != http.StatusOK || ct != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %d %q", w.Code, ct)
+	}
+	want := "event: message\ndata: " + `{"jsonrpc":"2.0","method":"notifications/progress","params":{"progressToken":"t1","progress":1}}` + "\n\n" +
+		"event: message\ndata: " + `{"jsonrpc":"2.0","id":1,"result":{}}` + "\n\n"
+	if w.Body.String() != want {
+		t.Errorf("Expected the progress and the response as events, got %q", w.Body.String())
+	}
+}
+
+func TestEventStreamError(t *testing.T) {
+	w := httptest.NewRecorder()
+	writeJSONRPCError(&eventStreamWriter{ResponseWriter: w}, http.StatusServiceUnavailable, 1, codeServerUnavailable, "unavailable")
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Content-Type") != "text/event-stream" ||
+		!strings.HasPrefix(w.Body.String(), `event: message`+"\n"+`data: {"error":`) || !strings.HasSuffix(w.Body.String(), "}\n\n") {
+		t.Errorf("Expected the error as an event, got %d %q %q", w.Code, w.Header().Get("Content-Type"), w.Body.String())
+	}
+}
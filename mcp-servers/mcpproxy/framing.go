@@ -0,0 +1,170 @@
+package mcpproxy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// readMessage returns the next message from the MCP server's stdout. Messages
+// end with the delimiter, a newline by default; a final message the server
+// writes without it before closing its stdout is still returned, and the next
+// read reports the closed stdout. p.io must be held.
+func (p *MCPProxy) readMessage() (json.RawMessage, error) {
+	if len(p.unread) > 0 {
+		msg := p.unread[0]
+		p.unread = p.unread[1:]
+		p.observeMessage(msg)
+		return msg, nil
+	}
+	for {
+		line, err := readDelimited(p.stdout, p.delimiter)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("error reading from MCP server: %w", err)
+		}
+		line = bytes.TrimSpace(bytes.TrimSuffix(line, p.delimiter))
+		if len(line) == 0 {
+			if err != nil {
+				return nil, errStdoutClosed
+			}
+			continue
+		}
+
+		msgs := splitMessages(line)
+		if len(msgs) == 1 && !json.Valid(msgs[0]) {
+			// Keep the reader going rather than pass on, or log raw, what
+			// may be binary
+			p.logger.warnf("Skipped output from MCP server that isn't JSON: %s", describeNonJSON(line))
+			if err != nil {
+				return nil, errStdoutClosed
+			}
+			continue
+		}
+		if len(msgs) > 1 {
+			p.logger.warnf("MCP server wrote %d JSON messages on one line", len(msgs))
+		}
+		p.unread = msgs[1:]
+		p.observeMessage(msgs[0])
+		return msgs[0], nil
+	}
+}
+
+// nonJSONPreview is how many leading bytes of a line that isn't JSON are
+// logged, in hex so binary output can't corrupt the log.
+const nonJSONPreview = 16
+
+// describeNonJSON summarizes a line the MCP server wrote that isn't JSON, such
+// as a stray log line or a crash dump, by its size and first bytes.
+func describeNonJSON(line []byte) string {
+	if len(line) <= nonJSONPreview {
+		return fmt.Sprintf("%d bytes: %x", len(line), line)
+	}
+	return fmt.Sprintf("%d bytes, starting %x", len(line), line[:nonJSONPreview])
+}
+
+// serverMessageBytes is the size distribution of messages read from MCP
+// servers, shared by every process of the proxy.
+var serverMessageBytes = newHistogram("mcp_server_message_bytes",
+	"Size of messages read from the MCP server's stdout in bytes.",
+	[]float64{256, 1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20, 4 << 20, 16 << 20})
+
+// observeMessage records the size of msg, warning about ones over
+// Config.WarnMessageBytes.
+func (p *MCPProxy) observeMessage(msg json.RawMessage) {
+	serverMessageBytes.observe(float64(len(msg)))
+	if limit := p.config.WarnMessageBytes; limit > 0 && len(msg) > limit {
+		p.logger.warnf("MCP server wrote a %d-byte message, over MCP_WARN_MESSAGE_BYTES=%d", len(msg), limit)
+	}
+}
+
+// splitMessages returns the JSON values in line, which is usually exactly one
+// but some servers write several without a newline between them. A line that
+// isn't a sequence of JSON values is returned whole, to fail where it is
+// parsed.
+func splitMessages(line []byte) []json.RawMessage {
+	dec := json.NewDecoder(bytes.NewReader(line))
+	var msgs []json.RawMessage
+	for {
+		var msg json.RawMessage
+		err := dec.Decode(&msg)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return []json.RawMessage{line}
+		}
+		msgs = append(msgs, msg)
+	}
+	if len(msgs) == 1 {
+		return []json.RawMessage{line}
+	}
+	return msgs
+}
+
+// readDelimited reads from r up to and including delimiter, or a newline if
+// delimiter is empty. Like bufio.Reader.ReadBytes, it returns the data read
+// before an error, which is io.EOF if r ended without the delimiter.
+func readDelimited(r *bufio.Reader, delimiter []byte) ([]byte, error) {
+	if len(delimiter) == 0 {
+		return r.ReadBytes('\n')
+	}
+	if len(delimiter) == 1 {
+		return r.ReadBytes(delimiter[0])
+	}
+	last := delimiter[len(delimiter)-1]
+	var data []byte
+	for {
+		chunk, err := r.ReadBytes(last)
+		data = append(data, chunk...)
+		if err != nil || bytes.HasSuffix(data, delimiter) {
+			return data, err
+		}
+	}
+}
+
+// parseDelimiter converts an MCP_DELIMITER value to bytes, interpreting the
+// escapes \0, \n, \r, \t, \\ and \xNN.
+func parseDelimiter(s string) ([]byte, error) {
+	var delimiter []byte
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			delimiter = append(delimiter, s[i])
+			continue
+		}
+		if i++; i == len(s) {
+			return nil, fmt.Errorf("%q ends with a backslash", s)
+		}
+		switch s[i] {
+		case '0':
+			delimiter = append(delimiter, 0)
+		case 'n':
+			delimiter = append(delimiter, '\n')
+		case 'r':
+			delimiter = append(delimiter, '\r')
+		case 't':
+			delimiter = append(delimiter, '\t')
+		case '\\':
+			delimiter = append(delimiter, '\\')
+		case 'x':
+			if i+3 > len(s) {
+				return nil, fmt.Errorf("%q has an incomplete \\x escape", s)
+			}
+			b, err := strconv.ParseUint(s[i+1:i+3], 16, 8)
+			if err != nil {
+				return nil, fmt.Errorf("%q has an invalid \\x escape", s)
+			}
+			delimiter = append(delimiter, byte(b))
+			i += 2
+		default:
+			return nil, fmt.Errorf("%q has an unknown escape \\%c", s, s[i])
+		}
+	}
+	if len(delimiter) == 0 {
+		return nil, errors.New("the delimiter is empty")
+	}
+	return delimiter, nil
+}
@@ -0,0 +1,178 @@
+package mcpproxy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSplitMessages(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{`{"id":1}`, []string{`{"id":1}`}},
+		{`{"id":1}{"id":2}`, []string{`{"id":1}`, `{"id":2}`}},
+		{`{"id":1} {"method":"x","params":{"s":"}{"}}`, []string{`{"id":1}`, `{"method":"x","params":{"s":"}{"}}`}},
+		{`{"id":1}{"id":`, []string{`{"id":1}{"id":`}},
+		{`not json`, []string{`not json`}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, msg := range splitMessages([]byte(tt.line)) {
+			got = append(got, string(msg))
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("splitMessages(%s) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestReadConcatenatedMessages(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	// A notification and two responses, all on one line
+	stdout := `{"jsonrpc":"2.0","method":"notifications/progress"}{"jsonrpc":"2.0","id":1,"result":1}{"jsonrpc":"2.0","id":2,"result":2}` + "\n"
+	proxy := &MCPProxy{
+		config: Config{ServerName: "test"},
+		logger: newLogger("test", "warn"),
+		stdout: bufio.NewReader(strings.NewReader(stdout)),
+	}
+	request := json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"x"}`)
+	for _, want := range []string{`{"jsonrpc":"2.0","id":1,"result":1}`, `{"jsonrpc":"2.0","id":2,"result":2}`} {
+		got, err := proxy.readResponse(request, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("readResponse() = %s, want %s", got, want)
+		}
+	}
+	if _, err := proxy.readResponse(request, ""); err != errStdoutClosed {
+		t.Errorf("Expected the closed stdout after the last message, got %v", err)
+	}
+	if !strings.Contains(buf.String(), "MCP server wrote 3 JSON messages on one line") {
+		t.Errorf("Expected a warning, got:\n%s", buf.String())
+	}
+}
+
+func TestSkipNonJSONOutput(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	binary := "\x7fELF\x02\x01\x01\x00\xff\xfe core dumped \x1b[2J"
+	proxy := &MCPProxy{
+		config: Config{ServerName: "test"},
+		logger: newLogger("test", "warn"),
+		stdout: bufio.NewReader(strings.NewReader(binary + "\n" + `{"jsonrpc":"2.0","id":1,"result":{}}` + "\n")),
+	}
+	got, err := proxy.readResponse(json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"x"}`), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != `{"jsonrpc":"2.0","id":1,"result":{}}` {
+		t.Errorf("Expected the response after the binary line, got %s", got)
+	}
+	want := fmt.Sprintf("Skipped output from MCP server that isn't JSON: %d bytes, starting 7f454c4602010100fffe20636f726520", len(binary))
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("Expected %q, got:\n%s", want, buf.String())
+	}
+	if strings.ContainsAny(buf.String(), "\x00\x1b") || strings.Contains(buf.String(), "core dumped") {
+		t.Errorf("Expected no raw bytes in the log, got:\n%q", buf.String())
+	}
+}
+
+func TestWarnMessageBytes(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	large := `{"jsonrpc":"2.0","id":1,"result":"` + strings.Repeat("x", 100) + `"}`
+	proxy := &MCPProxy{
+		config: Config{ServerName: "test", WarnMessageBytes: 64},
+		logger: newLogger("test", "warn"),
+		stdout: bufio.NewReader(strings.NewReader(`{"jsonrpc":"2.0","id":1,"result":1}` + "\n" + large + "\n")),
+	}
+	if _, err := proxy.readMessage(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Expected no warning for a small message, got:\n%s", buf.String())
+	}
+	if _, err := proxy.readMessage(); err != nil {
+		t.Fatal(err)
+	}
+	want := fmt.Sprintf("MCP server wrote a %d-byte message, over MCP_WARN_MESSAGE_BYTES=64", len(large))
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("Expected %q, got:\n%s", want, buf.String())
+	}
+}
+
+func TestParseDelimiter(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		err  bool
+	}{
+		{`\n`, "\n", false},
+		{`\0`, "\x00", false},
+		{`\r\n`, "\r\n", false},
+		{`\x1e`, "\x1e", false},
+		{`|\\`, `|\`, false},
+		{``, "", true},
+		{`\`, "", true},
+		{`\x1`, "", true},
+		{`\xzz`, "", true},
+		{`\q`, "", true},
+	}
+	for _, tt := range tests {
+		got, err := parseDelimiter(tt.in)
+		if (err != nil) != tt.err || string(got) != tt.want {
+			t.Errorf("parseDelimiter(%q) = %q, %v; want %q, error %v", tt.in, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestNULDelimiter(t *testing.T) {
+	// cat echoes each request with its NUL
+	proxy, err := NewMCPProxy(Config{
+		ServerName:  "test",
+		CommandPath: "cat",
+		Delimiter:   `\0`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+
+	for i := 0; i < 2; i++ {
+		w := ping(proxy)
+		if w.Code != http.StatusOK || w.Body.String() != `{"jsonrpc":"2.0","id":1,"method":"ping"}` {
+			t.Errorf("Expected the NUL-delimited echo, got %d: %s", w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestReadMultiByteDelimiter(t *testing.T) {
+	// The \r on its own is part of the message, not the end of it
+	proxy := &MCPProxy{
+		config:    Config{ServerName: "test"},
+		stdout:    bufio.NewReader(strings.NewReader("{\"id\":1,\r\"result\":1}\r\n{\"id\":2}\r\n")),
+		delimiter: []byte("\r\n"),
+	}
+	for _, want := range []string{"{\"id\":1,\r\"result\":1}", `{"id":2}`} {
+		got, err := proxy.readMessage()
+		if err != nil || string(got) != want {
+			t.Errorf("readMessage() = %q, %v; want %q", got, err, want)
+		}
+	}
+}
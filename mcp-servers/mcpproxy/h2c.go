@@ -0,0 +1,15 @@
+//go:build go1.24
+
+package mcpproxy
+
+import "net/http"
+
+// enableH2C lets srv serve HTTP/2 without TLS to clients with prior knowledge,
+// alongside HTTP/1.1. It reports whether this build supports it.
+func enableH2C(srv *http.Server) bool {
+	var protocols http.Protocols
+	protocols.SetHTTP1(true)
+	protocols.SetUnencryptedHTTP2(true)
+	srv.Protocols = &protocols
+	return true
+}
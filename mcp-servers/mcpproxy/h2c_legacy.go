@@ -0,0 +1,11 @@
+//go:build !go1.24
+
+package mcpproxy
+
+import "net/http"
+
+// enableH2C reports that h2c isn't supported: net/http serves unencrypted
+// HTTP/2 from Go 1.24 on.
+func enableH2C(srv *http.Server) bool {
+	return false
+}
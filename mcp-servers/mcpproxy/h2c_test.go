@@ -0,0 +1,41 @@
+//go:build go1.24
+
+package mcpproxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestH2C(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		proxy, err := NewMCPProxy(Config{ServerName: "test", CommandPath: "cat", EnableH2C: enabled})
+		if err != nil {
+			t.Fatal(err)
+		}
+		srv := httptest.NewUnstartedServer(nil)
+		srv.Config = proxy.newServer()
+		srv.Start()
+
+		var h2c http.Protocols
+		h2c.SetUnencryptedHTTP2(true)
+		client := &http.Client{Transport: &http.Transport{Protocols: &h2c}}
+		resp, err := client.Post(srv.URL, "application/json", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+		if enabled {
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.ProtoMajor != 2 || resp.StatusCode != http.StatusOK {
+				t.Errorf("Expected 200 over HTTP/2, got %d over %s", resp.StatusCode, resp.Proto)
+			}
+		} else if err == nil {
+			resp.Body.Close()
+			t.Errorf("Expected HTTP/2 to be refused when disabled, got %s", resp.Proto)
+		}
+		srv.Close()
+		proxy.stop()
+	}
+}
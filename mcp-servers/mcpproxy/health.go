@@ -0,0 +1,89 @@
+package mcpproxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+)
+
+// healthState is the state of the MCP server process as reported by /healthz.
+type healthState int32
+
+const (
+	// stateStarting: the process is running but hasn't passed a readiness
+	// check yet; see HandleReady.
+	stateStarting healthState = iota
+	// stateReady: the process answered the proxy's initialize and tools/list.
+	stateReady
+	// stateDegraded: the process exited or is being replaced and the
+	// supervisor is restarting it.
+	stateDegraded
+	// stateDead: the process exited and won't be restarted.
+	stateDead
+)
+
+func (s healthState) String() string {
+	switch s {
+	case stateStarting:
+		return "starting"
+	case stateReady:
+		return "ready"
+	case stateDegraded:
+		return "degraded"
+	default:
+		return "dead"
+	}
+}
+
+// health tracks the MCP server's healthState and the last error that
+// changed it.
+type health struct {
+	state   atomic.Int32
+	lastErr atomic.Pointer[string]
+}
+
+func (h *health) get() healthState {
+	return healthState(h.state.Load())
+}
+
+func (h *health) set(s healthState) {
+	h.state.Store(int32(s))
+}
+
+// fail sets the state and records err as the last error.
+func (h *health) fail(s healthState, err error) {
+	msg := err.Error()
+	h.lastErr.Store(&msg)
+	h.set(s)
+}
+
+// lastError returns the last recorded error, or "" if there was none.
+func (h *health) lastError() string {
+	if msg := h.lastErr.Load(); msg != nil {
+		return *msg
+	}
+	return ""
+}
+
+// HandleHealth serves /healthz: the MCP server's state as JSON, with HTTP 503
+// once it is dead. A process that is starting or being restarted still gets
+// 200, so a liveness probe leaves the restart to the proxy.
+func (p *MCPProxy) HandleHealth(w http.ResponseWriter, r *http.Request) {
+	status := http.StatusOK
+	if p.health.get() == stateDead {
+		status = http.StatusServiceUnavailable
+	}
+	p.writeHealth(w, status)
+}
+
+// writeHealth writes the health state as the JSON body of a response with
+// the given status.
+func (p *MCPProxy) writeHealth(w http.ResponseWriter, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(struct {
+		State     string `json:"state"`
+		LastError string `json:"last_error,omitempty"`
+		Restarts  int32  `json:"restarts"`
+	}{p.health.get().String(), p.health.lastError(), p.restarts.Load()})
+}
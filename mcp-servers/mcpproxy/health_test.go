@@ -0,0 +1,113 @@
+package mcpproxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type healthBody struct {
+	State     string `json:"state"`
+	LastError string `json:"last_error"`
+	Restarts  int    `json:"restarts"`
+}
+
+func getHealth(t *testing.T, p *MCPProxy) (int, healthBody) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	p.newMux().ServeHTTP(w, httptest.NewRequest("GET", "/healthz", nil))
+	var body healthBody
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Invalid /healthz body %q: %v", w.Body.String(), err)
+	}
+	return w.Code, body
+}
+
+func waitRestarted(p *MCPProxy) {
+	deadline := time.Now().Add(5 * time.Second)
+	for p.restarting.Load() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestHealthThroughRestart(t *testing.T) {
+	proxy, err := NewMCPProxy(closesStdout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+
+	if code, body := getHealth(t, proxy); code != http.StatusOK || body.State != "starting" {
+		t.Errorf("Expected 200 starting before the first response, got %d %+v", code, body)
+	}
+
+	// Client requests don't make it ready; see TestReadyAfterCheck
+	ping(proxy)
+	if code, body := getHealth(t, proxy); code != http.StatusOK || body.State != "starting" || body.LastError != "" {
+		t.Errorf("Expected 200 starting after a client request, got %d %+v", code, body)
+	}
+
+	// Closing stdout makes the supervisor restart the server
+	ping(proxy)
+	if state := proxy.health.get(); state != stateDegraded && state != stateStarting {
+		t.Errorf("Expected degraded or starting after the server exited, got %s", state)
+	}
+	waitRestarted(proxy)
+	code, body := getHealth(t, proxy)
+	if code != http.StatusOK || body.State != "starting" || body.LastError != errStdoutClosed.Error() || body.Restarts != 1 {
+		t.Errorf("Expected 200 starting with the exit error after the restart, got %d %+v", code, body)
+	}
+
+	ping(proxy)
+	if _, body := getHealth(t, proxy); body.Restarts != 0 {
+		t.Errorf("Expected the restart count to reset once the new process answered, got %+v", body)
+	}
+}
+
+func TestHealthDead(t *testing.T) {
+	proxy, err := NewMCPProxy(Config{
+		ServerName:  "test",
+		CommandPath: "sh",
+		CommandArgs: []string{"-c", `exec >&-; read line`},
+		MaxRestarts: -1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+
+	ping(proxy)
+	if code, body := getHealth(t, proxy); code != http.StatusServiceUnavailable || body.State != "dead" || body.LastError == "" {
+		t.Errorf("Expected 503 dead with the exit error, got %d %+v", code, body)
+	}
+
+	// An admin restart revives it
+	if err := proxy.restart(); err != nil {
+		t.Fatal(err)
+	}
+	if code, body := getHealth(t, proxy); code != http.StatusOK || body.State != "starting" {
+		t.Errorf("Expected 200 starting after a restart, got %d %+v", code, body)
+	}
+}
+
+func TestHealthMethods(t *testing.T) {
+	proxy := &MCPProxy{config: Config{ServerName: "test"}}
+	mux := proxy.newMux()
+	for _, path := range []string{"/healthz", "/version"} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("HEAD", path, nil))
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/json" {
+			t.Errorf("Expected HEAD %s to succeed, got %d %q", path, w.Code, w.Header().Get("Content-Type"))
+		}
+
+		for _, method := range []string{"POST", "PUT"} {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+			if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != "GET, HEAD" {
+				t.Errorf("Expected %s %s to get 405 with an Allow header, got %d %q", method, path, w.Code, w.Header().Get("Allow"))
+			}
+		}
+	}
+}
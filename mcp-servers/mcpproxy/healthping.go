@@ -0,0 +1,150 @@
+package mcpproxy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// healthPinger periodically sends the MCP server a ping, for
+// Config.HealthPingInterval, and restarts it after Config.HealthPingFailures
+// pings in a row go unanswered for Config.HealthPingTimeout. This catches a
+// process that is alive but wedged, which the supervisor can't see.
+type healthPinger struct {
+	latency  atomic.Int64 // nanoseconds the last answered ping took
+	restarts atomic.Int64
+	quit     chan struct{} // closed by stop
+	done     chan struct{} // closed once run returns
+
+	sent     int64                  // pings sent; only used by run
+	failures int                    // unanswered pings in a row; only used by run
+	pending  <-chan json.RawMessage // response of an unanswered ping; only used by run
+}
+
+func (p *MCPProxy) startHealthPinger(interval time.Duration) *healthPinger {
+	h := &healthPinger{quit: make(chan struct{}), done: make(chan struct{})}
+	go h.run(p, interval)
+	return h
+}
+
+// stop ends the pings, so none is sent once the request queue is closed. A
+// nil h is ignored.
+func (h *healthPinger) stop() {
+	if h == nil {
+		return
+	}
+	close(h.quit)
+	<-h.done
+}
+
+func (h *healthPinger) run(p *MCPProxy, interval time.Duration) {
+	defer close(h.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-h.quit:
+			return
+		}
+		// The supervisor is already dealing with an exited process, and a
+		// warming up one isn't expected to answer yet
+		if p.exited.Load() || p.restarting.Load() || !p.warmedUp() {
+			h.failures, h.pending = 0, nil
+			continue
+		}
+
+		err := h.ping(p)
+		if err == nil {
+			h.failures = 0
+			continue
+		}
+		h.failures++
+		p.logger.warnf("Health ping %d of %d failed: %v", h.failures, p.config.HealthPingFailures, err)
+		if h.failures >= p.config.HealthPingFailures {
+			h.restartWedged(p, err)
+		}
+	}
+}
+
+// ping sends a ping through the request queue, so it waits for requests
+// ahead of it, and returns an error if it isn't answered within
+// Config.HealthPingTimeout. While an earlier ping is still unanswered, no new
+// one is sent.
+func (h *healthPinger) ping(p *MCPProxy) error {
+	if h.pending != nil {
+		select {
+		case <-h.pending:
+			h.pending = nil
+		default:
+			return errors.New("the previous ping is still unanswered")
+		}
+	}
+
+	h.sent++
+	req := &request{
+		msg:       json.RawMessage(fmt.Sprintf(`{"jsonrpc":"2.0","id":"mcpproxy-ping-%d","method":"ping"}`, h.sent)),
+		isRequest: true,
+		response:  make(chan json.RawMessage, 1),
+	}
+	start := time.Now()
+	if err := p.enqueue(req); err != nil {
+		// Busy rather than hung, since the last ping was answered, or stopping
+		p.logger.debugf("Skipping health ping: %v", err)
+		return nil
+	}
+
+	timer := time.NewTimer(p.config.HealthPingTimeout)
+	defer timer.Stop()
+	select {
+	case _, ok := <-req.response:
+		if !ok {
+			// The process went away; the supervisor takes over
+			return nil
+		}
+		// Any response, even an error from a server without ping, shows
+		// it is reading and answering
+		h.latency.Store(int64(time.Since(start)))
+		return nil
+	case <-timer.C:
+		h.pending = req.response
+		return fmt.Errorf("no answer within %s", p.config.HealthPingTimeout)
+	case <-h.quit:
+		return nil
+	}
+}
+
+// restartWedged kills the MCP server process, so the request it is stuck on
+// fails and the supervisor starts a new one.
+func (h *healthPinger) restartWedged(p *MCPProxy, err error) {
+	h.failures, h.pending = 0, nil
+	pid := int(p.procPID.Load())
+	p.logger.errorf("MCP server (PID: %d) is not answering pings, killing it", pid)
+	p.health.fail(stateDegraded, fmt.Errorf("health ping: %w", err))
+	h.restarts.Add(1)
+	if proc, err := os.FindProcess(pid); err == nil {
+		proc.Kill()
+	}
+}
+
+func (h *healthPinger) lastLatency() float64 {
+	return time.Duration(h.latency.Load()).Seconds()
+}
+
+// warmedUp reports whether the current MCP server process has warmed up; see
+// warmup.
+func (p *MCPProxy) warmedUp() bool {
+	warm := p.warm.Load()
+	if warm == nil {
+		return true
+	}
+	select {
+	case <-warm.done:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,70 @@
+package mcpproxy
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHealthPingRestartsWedgedServer(t *testing.T) {
+	// The server answers its first message, then keeps reading without
+	// answering
+	proxy, err := NewMCPProxy(Config{
+		ServerName:         "test",
+		CommandPath:        "sh",
+		CommandArgs:        []string{"-c", `read -r line; echo "$line"; while read -r line; do :; done`},
+		HealthPingInterval: 20 * time.Millisecond,
+		HealthPingTimeout:  20 * time.Millisecond,
+		HealthPingFailures: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+	firstPID := proxy.pid()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for proxy.pinger.restarts.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if proxy.pinger.restarts.Load() == 0 {
+		t.Fatal("Expected a restart for unanswered pings")
+	}
+	if proxy.pinger.lastLatency() <= 0 {
+		t.Error("Expected the latency of the first, answered ping")
+	}
+	for proxy.pid() == firstPID && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if proxy.pid() == firstPID {
+		t.Error("Expected a new process")
+	}
+
+	w := httptest.NewRecorder()
+	defaultRegistry.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	for _, want := range []string{"# TYPE mcp_health_ping_latency_seconds gauge", "# TYPE mcp_health_ping_restarts_total counter"} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("Expected %q, got:\n%s", want, w.Body.String())
+		}
+	}
+}
+
+func TestHealthPingKeepsAnsweringServer(t *testing.T) {
+	cfg := slowEchoServer
+	cfg.HealthPingInterval = 10 * time.Millisecond
+	cfg.HealthPingTimeout = time.Second
+	proxy, err := NewMCPProxy(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+
+	time.Sleep(100 * time.Millisecond)
+	if n := proxy.pinger.restarts.Load(); n != 0 {
+		t.Errorf("Expected no restarts of a server that answers, got %d", n)
+	}
+	if w := ping(proxy); w.Code != 200 {
+		t.Errorf("Expected requests to be answered alongside pings, got %d", w.Code)
+	}
+}
@@ -0,0 +1,149 @@
+package mcpproxy
+
+import (
+	"sync"
+)
+
+// instancePool runs copies of a stateless MCP server and spreads requests
+// across them, so a slow request doesn't hold up every other client; see
+// Config.Instances. The main process is the first instance.
+type instancePool struct {
+	parent *MCPProxy
+
+	mu        sync.Mutex
+	instances []*instance
+	next      int // where the search for the least busy instance starts
+}
+
+type instance struct {
+	proxy   *MCPProxy
+	pending int // requests in flight
+}
+
+// newInstancePool starts n-1 processes in addition to parent's.
+func newInstancePool(parent *MCPProxy, n int) (*instancePool, error) {
+	ip := &instancePool{
+		parent:    parent,
+		instances: []*instance{{proxy: parent}},
+	}
+	for i := 1; i < n; i++ {
+		proxy, err := ip.start()
+		if err != nil {
+			ip.stopAll()
+			return nil, err
+		}
+		ip.instances = append(ip.instances, &instance{proxy: proxy})
+	}
+	parent.logger.infof("Running %d MCP server instances", n)
+	return ip, nil
+}
+
+func (ip *instancePool) start() (*MCPProxy, error) {
+	proxy, err := startProxy(ip.parent.config, ip.parent.logger)
+	if err != nil {
+		return nil, err
+	}
+	proxy.runtime.Store(ip.parent.current())
+	proxy.notifications = ip.parent.notifications
+	return proxy, nil
+}
+
+// acquire returns the running instance with the fewest requests in flight,
+// taking turns among equally busy ones. Additional instances that exited are
+// replaced; the main process is left to its supervisor. The returned release
+// func must be called once the request has completed.
+func (ip *instancePool) acquire() (*MCPProxy, func(), error) {
+	ip.mu.Lock()
+	defer ip.mu.Unlock()
+
+	best := -1
+	for n := 0; n < len(ip.instances); n++ {
+		i := (ip.next + n) % len(ip.instances)
+		in := ip.instances[i]
+		if in.proxy.exited.Load() && i > 0 {
+			ip.replace(i)
+			in = ip.instances[i]
+		}
+		if in.proxy.exited.Load() {
+			continue
+		}
+		if best < 0 || in.pending < ip.instances[best].pending {
+			best = i
+		}
+	}
+	if best < 0 {
+		// Nothing is running; let the main process report why
+		best = 0
+	}
+	ip.next = (best + 1) % len(ip.instances)
+
+	in := ip.instances[best]
+	in.pending++
+	return in.proxy, func() {
+		ip.mu.Lock()
+		in.pending--
+		ip.mu.Unlock()
+	}, nil
+}
+
+// replace starts a new process for the additional instance i, keeping the
+// exited one if that fails. ip.mu must be held.
+func (ip *instancePool) replace(i int) {
+	old := ip.instances[i].proxy
+	ip.parent.logger.warnf("MCP server instance %d (PID: %d) exited, restarting it", i, old.pid())
+	proxy, err := ip.start()
+	if err != nil {
+		ip.parent.logger.errorf("Restarting MCP server instance %d failed: %v", i, err)
+		return
+	}
+	ip.instances[i] = &instance{proxy: proxy}
+	go old.stop()
+}
+
+// pendingCounts returns the number of requests in flight per instance.
+func (ip *instancePool) pendingCounts() []float64 {
+	ip.mu.Lock()
+	defer ip.mu.Unlock()
+	counts := make([]float64, len(ip.instances))
+	for i, in := range ip.instances {
+		counts[i] = float64(in.pending)
+	}
+	return counts
+}
+
+// extras returns the additional instances' proxies.
+func (ip *instancePool) extras() []*MCPProxy {
+	if ip == nil {
+		return nil
+	}
+	ip.mu.Lock()
+	defer ip.mu.Unlock()
+	proxies := make([]*MCPProxy, 0, len(ip.instances)-1)
+	for _, in := range ip.instances[1:] {
+		proxies = append(proxies, in.proxy)
+	}
+	return proxies
+}
+
+// restartAll replaces the additional instances' processes.
+func (ip *instancePool) restartAll() {
+	for _, proxy := range ip.extras() {
+		if err := proxy.restart(); err != nil {
+			ip.parent.logger.errorf("Restarting MCP server instance failed: %v", err)
+		}
+	}
+}
+
+// setRuntime applies reloaded settings to the additional instances.
+func (ip *instancePool) setRuntime(rc *runtimeConfig) {
+	for _, proxy := range ip.extras() {
+		proxy.runtime.Store(rc)
+	}
+}
+
+// stopAll stops the additional instances.
+func (ip *instancePool) stopAll() {
+	for _, proxy := range ip.extras() {
+		proxy.stop()
+	}
+}
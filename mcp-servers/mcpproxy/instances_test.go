@@ -0,0 +1,120 @@
+package mcpproxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestInstancePoolLeastBusy(t *testing.T) {
+	parent := &MCPProxy{}
+	ip := &instancePool{parent: parent, instances: []*instance{{proxy: parent}, {proxy: &MCPProxy{}}, {proxy: &MCPProxy{}}}}
+
+	// Equally busy instances take turns
+	seen := map[*MCPProxy]bool{}
+	var releases []func()
+	for i := 0; i < 3; i++ {
+		proxy, release, _ := ip.acquire()
+		seen[proxy] = true
+		releases = append(releases, release)
+	}
+	if len(seen) != 3 {
+		t.Fatalf("Expected three requests to go to three instances, got %d", len(seen))
+	}
+
+	// The instance that finished first gets the next request
+	releases[1]()
+	if proxy, _, _ := ip.acquire(); proxy != ip.instances[1].proxy {
+		t.Error("Expected the idle instance to get the next request")
+	}
+	if counts := ip.pendingCounts(); counts[0] != 1 || counts[1] != 1 || counts[2] != 1 {
+		t.Errorf("Expected one request in flight per instance, got %v", counts)
+	}
+
+	// The main process is skipped while it is down
+	parent.exited.Store(true)
+	for i := 0; i < 4; i++ {
+		if proxy, _, _ := ip.acquire(); proxy == parent {
+			t.Fatal("Expected requests not to go to an exited main process")
+		}
+	}
+}
+
+func TestInstancesRunConcurrently(t *testing.T) {
+	proxy, err := NewMCPProxy(Config{
+		ServerName:  "test",
+		CommandPath: "sh",
+		CommandArgs: []string{"-c", `while read -r line; do sleep 0.5; echo "$line"; done`},
+		Instances:   3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	codes := make([]int, 3)
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			codes[i] = ping(proxy).Code
+		}(i)
+	}
+	wg.Wait()
+
+	for i, code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("Request %d: expected 200, got %d", i, code)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 1200*time.Millisecond {
+		t.Errorf("Expected three slow requests to be answered in parallel, took %s", elapsed)
+	}
+
+	w := httptest.NewRecorder()
+	defaultRegistry.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if !strings.Contains(w.Body.String(), `mcp_instance_pending{instance="2"} 0`) {
+		t.Errorf("Expected per-instance pending counts, got %s", w.Body.String())
+	}
+}
+
+func TestInstanceReplacedAfterExit(t *testing.T) {
+	proxy, err := NewMCPProxy(Config{
+		ServerName:  "test",
+		CommandPath: "cat",
+		Instances:   2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+
+	extra := proxy.instances.extras()[0]
+	extra.markExited(errStdoutClosed)
+	if w := ping(proxy); w.Code != http.StatusOK {
+		t.Errorf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if replaced := proxy.instances.extras()[0]; replaced == extra || replaced.exited.Load() {
+		t.Error("Expected the exited instance to be replaced")
+	}
+}
+
+func TestInstancesIgnoredWithSessions(t *testing.T) {
+	cfg := tokenEchoServer
+	cfg.SessionFunc = headerSession
+	cfg.Instances = 2
+	proxy, err := NewMCPProxy(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+
+	if proxy.instances != nil {
+		t.Error("Expected MCP_INSTANCES to be ignored for a server with sessions")
+	}
+}
@@ -0,0 +1,79 @@
+package mcpproxy
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strconv"
+)
+
+// listen opens the proxy's listener: a Unix domain socket at
+// Config.ListenUnix if set, otherwise TCP on Config.listenAddr.
+func (c *Config) listen() (net.Listener, error) {
+	if c.ListenUnix == "" {
+		return net.Listen("tcp", c.listenAddr())
+	}
+	if err := removeStaleSocket(c.ListenUnix); err != nil {
+		return nil, err
+	}
+	// The socket file is removed again when the listener is closed
+	return net.Listen("unix", c.ListenUnix)
+}
+
+// removeStaleSocket removes a socket left behind by a previous run that
+// didn't shut down cleanly. Anything other than a socket is left alone.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("LISTEN_UNIX path %s exists and is not a socket", path)
+	}
+	return os.Remove(path)
+}
+
+// listenAddr returns the TCP address to serve on: Config.ListenAddr if set,
+// otherwise all interfaces on Config.Port.
+func (c *Config) listenAddr() string {
+	if c.ListenAddr != "" {
+		return c.ListenAddr
+	}
+	return ":" + c.Port
+}
+
+// validateListenAddr checks that addr is a host:port pair with a valid port,
+// so a typo fails at startup rather than when the listener is opened.
+func validateListenAddr(addr string) error {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid listen address %q: %w", addr, err)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+		return fmt.Errorf("invalid listen address %q: bad port %q", addr, port)
+	}
+	if host != "" && net.ParseIP(host) == nil {
+		if _, err := net.LookupHost(host); err != nil {
+			return fmt.Errorf("invalid listen address %q: %w", addr, err)
+		}
+	}
+	return nil
+}
+
+// endpointHost returns addr in a form suitable for a URL in log messages,
+// using localhost when addr binds all interfaces.
+func endpointHost(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	return net.JoinHostPort(host, port)
+}
@@ -0,0 +1,131 @@
+package mcpproxy
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestListenAddr(t *testing.T) {
+	cfg := Config{Port: "9090"}
+	if got := cfg.listenAddr(); got != ":9090" {
+		t.Errorf("Expected :9090 without LISTEN_ADDR, got %q", got)
+	}
+
+	t.Setenv("LISTEN_ADDR", "127.0.0.1:8081")
+	cfg.applyDefaults()
+	if got := cfg.listenAddr(); got != "127.0.0.1:8081" {
+		t.Errorf("Expected LISTEN_ADDR to override the port, got %q", got)
+	}
+}
+
+func TestValidateListenAddr(t *testing.T) {
+	tests := []struct {
+		addr    string
+		wantErr bool
+	}{
+		{":8080", false},
+		{"127.0.0.1:8080", false},
+		{"[::1]:8080", false},
+		{"localhost:8080", false},
+		{"127.0.0.1", true},
+		{"127.0.0.1:http-alt", true},
+		{"127.0.0.1:70000", true},
+		{"::1:8080", true},
+	}
+
+	for _, tt := range tests {
+		if err := validateListenAddr(tt.addr); (err != nil) != tt.wantErr {
+			t.Errorf("validateListenAddr(%q) error = %v, wantErr %v", tt.addr, err, tt.wantErr)
+		}
+	}
+}
+
+func TestEndpointHost(t *testing.T) {
+	tests := map[string]string{
+		":8080":          "localhost:8080",
+		"0.0.0.0:8080":   "localhost:8080",
+		"127.0.0.1:8080": "127.0.0.1:8080",
+		"[::1]:8080":     "[::1]:8080",
+	}
+	for addr, want := range tests {
+		if got := endpointHost(addr); got != want {
+			t.Errorf("endpointHost(%q) = %q, want %q", addr, got, want)
+		}
+	}
+}
+
+func TestListenUnix(t *testing.T) {
+	// Socket paths are limited to ~100 bytes, which t.TempDir can exceed
+	dir, err := os.MkdirTemp("", "mcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "proxy.sock")
+
+	// Leave a stale socket behind, as a crashed previous run would
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	proxy := &MCPProxy{
+		config:   Config{ServerName: "test", ListenUnix: path},
+		requests: make(chan *request, 1),
+	}
+	drainRequests(proxy, func(json.RawMessage) json.RawMessage {
+		return json.RawMessage(`{"jsonrpc":"2.0","id":1,"result":{}}`)
+	})
+	defer close(proxy.requests)
+
+	ln, err := proxy.config.listen()
+	if err != nil {
+		t.Fatalf("Expected stale socket to be replaced, got %v", err)
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(proxy.Handle)}
+	go srv.Serve(ln)
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+	resp, err := client.Post("http://unix/", "application/json", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != `{"jsonrpc":"2.0","id":1,"result":{}}` {
+		t.Errorf("Unexpected response over unix socket: %s", body)
+	}
+
+	srv.Shutdown(context.Background())
+	if _, err := os.Lstat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected socket file to be removed on shutdown, got %v", err)
+	}
+}
+
+func TestListenUnixRefusesRegularFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "not-a-socket")
+	if err := os.WriteFile(path, []byte("data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := Config{ListenUnix: path}
+	if _, err := cfg.listen(); err == nil {
+		t.Fatal("Expected an error for a path that isn't a socket")
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("Expected the regular file to be left alone, got %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+package mcpproxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+)
+
+// logLevel controls which messages the proxy logs.
+type logLevel int32
+
+const (
+	levelDebug logLevel = iota
+	levelInfo
+	levelWarn
+	levelError
+)
+
+var levelNames = map[logLevel]string{
+	levelDebug: "DEBUG",
+	levelInfo:  "INFO",
+	levelWarn:  "WARN",
+	levelError: "ERROR",
+}
+
+func (l logLevel) String() string {
+	return levelNames[l]
+}
+
+// parseLogLevel converts a LOG_LEVEL value (debug, info, warn, error) to a logLevel.
+func parseLogLevel(s string) (logLevel, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return levelDebug, nil
+	case "", "info":
+		return levelInfo, nil
+	case "warn", "warning":
+		return levelWarn, nil
+	case "error":
+		return levelError, nil
+	}
+	return levelInfo, fmt.Errorf("unknown log level %q", s)
+}
+
+// skippedNotificationLevel returns the level of Config.LogSkippedNotifications,
+// debug if it is unset.
+func (c *Config) skippedNotificationLevel() logLevel {
+	if c.LogSkippedNotifications == "" {
+		return levelDebug
+	}
+	level, _ := parseLogLevel(c.LogSkippedNotifications)
+	return level
+}
+
+// mcpLogLevel converts a level from an MCP logging/setLevel request (the
+// syslog severities debug through emergency) to the closest logLevel.
+func mcpLogLevel(s string) (logLevel, bool) {
+	switch s {
+	case "debug":
+		return levelDebug, true
+	case "info", "notice":
+		return levelInfo, true
+	case "warning":
+		return levelWarn, true
+	case "error", "critical", "alert", "emergency":
+		return levelError, true
+	}
+	return levelInfo, false
+}
+
+// logger is a minimal leveled logger that prefixes messages with the server name.
+// A nil *logger logs at info level without a prefix, which keeps zero-value
+// proxies in tests usable.
+type logger struct {
+	name  string
+	level atomic.Int32
+}
+
+func newLogger(name, level string) *logger {
+	l := &logger{name: name}
+	lvl, err := parseLogLevel(level)
+	if err != nil {
+		log.Printf("[%s] WARN  Ignoring invalid LOG_LEVEL: %v", name, err)
+	}
+	l.setLevel(lvl)
+	return l
+}
+
+func (l *logger) setLevel(level logLevel) {
+	l.level.Store(int32(level))
+}
+
+func (l *logger) enabled(level logLevel) bool {
+	if l == nil {
+		return level >= levelInfo
+	}
+	return level >= logLevel(l.level.Load())
+}
+
+func (l *logger) logf(level logLevel, format string, args ...interface{}) {
+	if !l.enabled(level) {
+		return
+	}
+	l.printf(level.String(), format, args...)
+}
+
+func (l *logger) printf(label, format string, args ...interface{}) {
+	name := ""
+	if l != nil {
+		name = l.name
+	}
+	log.Printf("[%s] %-5s %s", name, label, fmt.Sprintf(format, args...))
+}
+
+func (l *logger) debugf(format string, args ...interface{}) { l.logf(levelDebug, format, args...) }
+func (l *logger) infof(format string, args ...interface{})  { l.logf(levelInfo, format, args...) }
+func (l *logger) warnf(format string, args ...interface{})  { l.logf(levelWarn, format, args...) }
+func (l *logger) errorf(format string, args ...interface{}) { l.logf(levelError, format, args...) }
+
+// adapterLogger is the logger of the last proxy created, for Warnf.
+var adapterLogger atomic.Pointer[logger]
+
+// Warnf logs a warning with the proxy's server name, subject to LOG_LEVEL,
+// for adapter code such as response middlewares that has no proxy at hand.
+// Before a proxy is created it logs without a server name.
+func Warnf(format string, args ...interface{}) {
+	adapterLogger.Load().warnf(format, args...)
+}
+
+// accessf logs an access log line. Access logging is switched on by its own
+// setting, so it ignores the log level.
+func (l *logger) accessf(format string, args ...interface{}) { l.printf("ACCESS", format, args...) }
+
+// mirrorLogLevel sets the proxy's log level to the one a client asked the MCP
+// server for with logging/setLevel, for Config.RespectClientLogLevel.
+func (p *MCPProxy) mirrorLogLevel(msg json.RawMessage) {
+	var req struct {
+		Params struct {
+			Level string `json:"level"`
+		} `json:"params"`
+	}
+	json.Unmarshal(msg, &req)
+	level, ok := mcpLogLevel(req.Params.Level)
+	if !ok {
+		p.logger.warnf("Ignoring logging/setLevel with unknown level %q", req.Params.Level)
+		return
+	}
+	p.logger.warnf("Client set the log level to %s", level)
+	p.logger.setLevel(level)
+}
@@ -0,0 +1,177 @@
+package mcpproxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseLogLevel(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    logLevel
+		wantErr bool
+	}{
+		{"debug", levelDebug, false},
+		{"INFO", levelInfo, false},
+		{"", levelInfo, false},
+		{"warn", levelWarn, false},
+		{"warning", levelWarn, false},
+		{"error", levelError, false},
+		{"verbose", levelInfo, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseLogLevel(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseLogLevel(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseLogLevel(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoggerFiltersByLevel(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	l := newLogger("test", "warn")
+	l.debugf("debug message")
+	l.infof("info message")
+	l.warnf("warn message")
+	l.errorf("error message")
+
+	out := buf.String()
+	for _, hidden := range []string{"debug message", "info message"} {
+		if strings.Contains(out, hidden) {
+			t.Errorf("Expected %q to be filtered at warn level, got:\n%s", hidden, out)
+		}
+	}
+	for _, shown := range []string{"[test] WARN  warn message", "[test] ERROR error message"} {
+		if !strings.Contains(out, shown) {
+			t.Errorf("Expected %q in output, got:\n%s", shown, out)
+		}
+	}
+}
+
+func TestNilLoggerDefaultsToInfo(t *testing.T) {
+	var l *logger
+	if l.enabled(levelDebug) {
+		t.Error("Expected nil logger to drop debug messages")
+	}
+	if !l.enabled(levelInfo) {
+		t.Error("Expected nil logger to log info messages")
+	}
+}
+
+func TestClientLogLevel(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	for _, respect := range []bool{false, true} {
+		buf.Reset()
+		proxy := &MCPProxy{
+			config:   Config{ServerName: "test", RespectClientLogLevel: respect},
+			logger:   newLogger("test", "info"),
+			requests: make(chan *request, 1),
+		}
+		var forwarded []string
+		drainRequests(proxy, func(msg json.RawMessage) json.RawMessage {
+			forwarded = append(forwarded, string(msg))
+			return json.RawMessage(`{"jsonrpc":"2.0","id":1,"result":{}}`)
+		})
+
+		setLevel := `{"jsonrpc":"2.0","id":1,"method":"logging/setLevel","params":{"level":"debug"}}`
+		proxy.Handle(httptest.NewRecorder(), httptest.NewRequest("POST", "/", strings.NewReader(setLevel)))
+		proxy.Handle(httptest.NewRecorder(), httptest.NewRequest("POST", "/", strings.NewReader(`{"jsonrpc":"2.0","id":2,"method":"ping"}`)))
+		close(proxy.requests)
+
+		if len(forwarded) != 2 || forwarded[0] != setLevel {
+			t.Errorf("respect=%v: expected logging/setLevel to be forwarded, got %q", respect, forwarded)
+		}
+		debugLogged := strings.Contains(buf.String(), "Received HTTP request")
+		if debugLogged != respect {
+			t.Errorf("respect=%v: expected debug payload logging %v, got:\n%s", respect, respect, buf.String())
+		}
+	}
+}
+
+func TestMCPLogLevel(t *testing.T) {
+	for in, want := range map[string]logLevel{
+		"debug":     levelDebug,
+		"notice":    levelInfo,
+		"warning":   levelWarn,
+		"critical":  levelError,
+		"emergency": levelError,
+	} {
+		if got, ok := mcpLogLevel(in); !ok || got != want {
+			t.Errorf("mcpLogLevel(%q) = %v, %v; want %v", in, got, ok, want)
+		}
+	}
+	if _, ok := mcpLogLevel("verbose"); ok {
+		t.Error("Expected an unknown level to be rejected")
+	}
+}
+
+func TestWarnfUsesProxyLogger(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+	defer adapterLogger.Store(adapterLogger.Load())
+
+	adapterLogger.Store(newLogger("adapter", "warn"))
+	Warnf("quota at %d", 5)
+	if !strings.Contains(buf.String(), "[adapter] WARN  quota at 5") {
+		t.Errorf("Expected the warning with the server name, got %q", buf.String())
+	}
+
+	buf.Reset()
+	adapterLogger.Store(newLogger("adapter", "error"))
+	Warnf("quota at %d", 4)
+	if buf.Len() != 0 {
+		t.Errorf("Expected LOG_LEVEL=error to hide the warning, got %q", buf.String())
+	}
+}
+
+func TestClientLogLevelLogsRedactedPayloads(t *testing.T) {
+	t.Setenv("RESPECT_CLIENT_LOG_LEVEL", "true")
+	t.Setenv("LOG_PAYLOADS", "true")
+	cfg := Config{ServerName: "test"}
+	cfg.applyDefaults()
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	proxy := &MCPProxy{
+		config:   cfg,
+		logger:   newLogger("test", "info"),
+		requests: make(chan *request, 1),
+	}
+	drainRequests(proxy, func(msg json.RawMessage) json.RawMessage {
+		return json.RawMessage(`{"jsonrpc":"2.0","id":1,"result":{}}`)
+	})
+
+	setLevel := `{"jsonrpc":"2.0","id":1,"method":"logging/setLevel","params":{"level":"debug"}}`
+	call := `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"connect","arguments":{"user":"scott","password":"hunter2"}}}`
+	proxy.Handle(httptest.NewRecorder(), httptest.NewRequest("POST", "/", strings.NewReader(setLevel)))
+	proxy.Handle(httptest.NewRecorder(), httptest.NewRequest("POST", "/", strings.NewReader(call)))
+	close(proxy.requests)
+
+	out := buf.String()
+	if !strings.Contains(out, `"user":"scott"`) || !strings.Contains(out, `"password":"[REDACTED]"`) {
+		t.Errorf("Expected the redacted payload in the debug log, got:\n%s", out)
+	}
+	if strings.Contains(out, "hunter2") {
+		t.Errorf("Expected the password to be redacted, got:\n%s", out)
+	}
+}
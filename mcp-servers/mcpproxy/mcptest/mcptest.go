@@ -0,0 +1,188 @@
+//go:build unix
+
+// Package mcptest provides a scripted stdio MCP server for testing proxies
+// built on mcpproxy.
+//
+// The proxy always runs its MCP server as a subprocess, so a FakeServer
+// starts a small shell process that relays its stdin and stdout through two
+// named pipes to a handler running in the test:
+//
+//	srv := mcptest.NewFakeServer(func(req json.RawMessage) []json.RawMessage {
+//		return []json.RawMessage{
+//			mcptest.Notification("notifications/progress", map[string]interface{}{"progress": 1}),
+//			mcptest.Result(req, map[string]interface{}{"tools": []interface{}{}}),
+//		}
+//	})
+//	defer srv.Close()
+//
+//	proxy, err := mcpproxy.NewMCPProxy(srv.Config())
+package mcptest
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+
+	"github.com/rh-ai-kickstart/ai-architecture-charts/mcp-servers/mcpproxy"
+)
+
+// Handler answers one message written by the proxy with the messages to
+// write back, in order: typically any notifications followed by the response.
+// It returns nil for notifications from the client.
+type Handler func(req json.RawMessage) []json.RawMessage
+
+// relay is the MCP server process: it copies its stdin to the first named
+// pipe and the second named pipe to its stdout, until its stdin is closed.
+const relay = `cat <"$1" & cat >"$0"; kill $! 2>/dev/null`
+
+// FakeServer is a scripted MCP server. Every process started with its
+// Config talks to the same handler, one at a time, so it serves a process
+// started again by a restart but can't back proxies that use session
+// processes.
+type FakeServer struct {
+	handler Handler
+	dir     string
+	in, out *os.File // named pipes carrying the process's stdin and stdout
+
+	mu       sync.Mutex
+	received []json.RawMessage
+
+	done chan struct{}
+}
+
+// NewFakeServer starts a FakeServer that answers with handler. It panics if
+// the named pipes can't be created. Call Close when done.
+func NewFakeServer(handler Handler) *FakeServer {
+	dir, err := os.MkdirTemp("", "mcptest")
+	if err != nil {
+		panic(fmt.Sprintf("mcptest: %v", err))
+	}
+	in, err := openFIFO(filepath.Join(dir, "in"))
+	if err != nil {
+		os.RemoveAll(dir)
+		panic(fmt.Sprintf("mcptest: %v", err))
+	}
+	out, err := openFIFO(filepath.Join(dir, "out"))
+	if err != nil {
+		in.Close()
+		os.RemoveAll(dir)
+		panic(fmt.Sprintf("mcptest: %v", err))
+	}
+
+	s := &FakeServer{handler: handler, dir: dir, in: in, out: out, done: make(chan struct{})}
+	go s.serve()
+	return s
+}
+
+// Config returns a proxy configuration that runs the fake server.
+func (s *FakeServer) Config() mcpproxy.Config {
+	return mcpproxy.Config{
+		ServerName:  "mcptest",
+		CommandPath: "sh",
+		CommandArgs: []string{"-c", relay, s.in.Name(), s.out.Name()},
+	}
+}
+
+// Received returns the messages the proxy has written to the server so far.
+func (s *FakeServer) Received() []json.RawMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]json.RawMessage(nil), s.received...)
+}
+
+// Close stops the server and removes its named pipes. A process still
+// running no longer gets responses.
+func (s *FakeServer) Close() {
+	s.in.Close()
+	<-s.done
+	s.out.Close()
+	os.RemoveAll(s.dir)
+}
+
+// openFIFO creates a named pipe and opens it for reading and writing, which
+// doesn't wait for the other end and keeps the pipe open across processes.
+func openFIFO(path string) (*os.File, error) {
+	if err := syscall.Mkfifo(path, 0o600); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(path, os.O_RDWR, 0)
+}
+
+// serve answers messages from the relay processes until Close.
+func (s *FakeServer) serve() {
+	defer close(s.done)
+
+	scanner := bufio.NewScanner(s.in)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	w := bufio.NewWriter(s.out)
+	for scanner.Scan() {
+		msg := json.RawMessage(append([]byte(nil), scanner.Bytes()...))
+		s.mu.Lock()
+		s.received = append(s.received, msg)
+		s.mu.Unlock()
+
+		for _, reply := range s.handler(msg) {
+			w.Write(reply)
+			w.WriteByte('\n')
+		}
+		if err := w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// Result returns a response to req carrying result.
+func Result(req json.RawMessage, result interface{}) json.RawMessage {
+	return marshal(map[string]interface{}{"jsonrpc": "2.0", "id": id(req), "result": result})
+}
+
+// Error returns an error response to req.
+func Error(req json.RawMessage, code int, message string) json.RawMessage {
+	return marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      id(req),
+		"error":   map[string]interface{}{"code": code, "message": message},
+	})
+}
+
+// Notification returns a server notification. params may be nil.
+func Notification(method string, params interface{}) json.RawMessage {
+	msg := map[string]interface{}{"jsonrpc": "2.0", "method": method}
+	if params != nil {
+		msg["params"] = params
+	}
+	return marshal(msg)
+}
+
+// Method returns the method of msg, or "" for a response or invalid JSON.
+func Method(msg json.RawMessage) string {
+	var m struct {
+		Method string `json:"method"`
+	}
+	json.Unmarshal(msg, &m)
+	return m.Method
+}
+
+// id returns the raw ID of req, so responses echo it exactly.
+func id(req json.RawMessage) json.RawMessage {
+	var m struct {
+		ID json.RawMessage `json:"id"`
+	}
+	json.Unmarshal(req, &m)
+	if m.ID == nil {
+		return json.RawMessage("null")
+	}
+	return m.ID
+}
+
+func marshal(v interface{}) json.RawMessage {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("mcptest: %v", err))
+	}
+	return data
+}
@@ -0,0 +1,82 @@
+//go:build unix
+
+package mcptest_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rh-ai-kickstart/ai-architecture-charts/mcp-servers/mcpproxy"
+	"github.com/rh-ai-kickstart/ai-architecture-charts/mcp-servers/mcpproxy/mcptest"
+)
+
+func TestFakeServerThroughProxy(t *testing.T) {
+	srv := mcptest.NewFakeServer(func(req json.RawMessage) []json.RawMessage {
+		switch mcptest.Method(req) {
+		case "tools/list":
+			return []json.RawMessage{
+				mcptest.Notification("notifications/message", map[string]interface{}{"level": "info"}),
+				mcptest.Result(req, map[string]interface{}{"tools": []interface{}{map[string]interface{}{"name": "echo"}}}),
+			}
+		case "tools/call":
+			return []json.RawMessage{mcptest.Error(req, -32602, "bad arguments")}
+		case "ping":
+			return []json.RawMessage{mcptest.Result(req, struct{}{})}
+		}
+		return nil
+	})
+	defer srv.Close()
+
+	proxy, err := mcpproxy.NewMCPProxy(srv.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	call := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		proxy.Handle(w, httptest.NewRequest("POST", "/", strings.NewReader(body)))
+		return w
+	}
+
+	w := call(`{"jsonrpc":"2.0","id":"a","method":"tools/list"}`)
+	if w.Code != http.StatusOK || w.Body.String() != `{"id":"a","jsonrpc":"2.0","result":{"tools":[{"name":"echo"}]}}` {
+		t.Errorf("Expected the scripted tools/list result after the notification, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = call(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"echo"}}`)
+	if !strings.Contains(w.Body.String(), `"id":2`) || !strings.Contains(w.Body.String(), `"code":-32602`) {
+		t.Errorf("Expected the scripted error, got %s", w.Body.String())
+	}
+
+	if w := call(`{"jsonrpc":"2.0","method":"notifications/initialized"}`); w.Code != http.StatusAccepted {
+		t.Errorf("Expected 202 for a notification, got %d", w.Code)
+	}
+
+	// Messages are handled in order, so the notification has arrived once
+	// the ping is answered
+	call(`{"jsonrpc":"2.0","id":3,"method":"ping"}`)
+	received := srv.Received()
+	if len(received) != 4 || mcptest.Method(received[2]) != "notifications/initialized" {
+		t.Errorf("Expected the server to receive all four messages, got %q", received)
+	}
+}
+
+func TestResultEchoesID(t *testing.T) {
+	for _, req := range []string{
+		`{"jsonrpc":"2.0","id":7,"method":"ping"}`,
+		`{"jsonrpc":"2.0","id":"abc","method":"ping"}`,
+		`{"jsonrpc":"2.0","id":null,"method":"ping"}`,
+	} {
+		var in, out struct {
+			ID json.RawMessage `json:"id"`
+		}
+		json.Unmarshal([]byte(req), &in)
+		json.Unmarshal(mcptest.Result(json.RawMessage(req), struct{}{}), &out)
+		if string(in.ID) != string(out.ID) {
+			t.Errorf("Result for %s: expected ID %s, got %s", req, in.ID, out.ID)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package mcpproxy
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// registry holds metrics and serves them in the Prometheus text exposition format.
+// It is intentionally minimal so the proxy doesn't need a metrics client dependency.
+type registry struct {
+	mu      sync.Mutex
+	metrics map[string]metric
+}
+
+type metric interface {
+	write(w io.Writer)
+}
+
+// defaultRegistry is served at /metrics.
+var defaultRegistry = &registry{metrics: make(map[string]metric)}
+
+// register adds a metric, replacing any previous metric with the same name.
+func (r *registry) register(name string, m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metrics[name] = m
+}
+
+func (r *registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.metrics))
+	for name := range r.metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	metrics := make([]metric, len(names))
+	for i, name := range names {
+		metrics[i] = r.metrics[name]
+	}
+	r.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	for _, m := range metrics {
+		m.write(w)
+	}
+}
+
+// gaugeFunc is a gauge whose value is computed when metrics are scraped.
+type gaugeFunc struct {
+	name string
+	help string
+	fn   func() float64
+}
+
+func (g *gaugeFunc) write(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %g\n", g.name, g.help, g.name, g.name, g.fn())
+}
+
+// gaugeVecFunc is a gauge with one value per index, labelled with label, whose
+// values are computed when metrics are scraped.
+type gaugeVecFunc struct {
+	name  string
+	help  string
+	label string
+	fn    func() []float64
+}
+
+func (g *gaugeVecFunc) write(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", g.name, g.help, g.name)
+	for i, v := range g.fn() {
+		fmt.Fprintf(w, "%s{%s=\"%d\"} %g\n", g.name, g.label, i, v)
+	}
+}
+
+// counterFunc is a counter whose value is read when metrics are scraped.
+type counterFunc struct {
+	name string
+	help string
+	fn   func() float64
+}
+
+func (c *counterFunc) write(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n%s %g\n", c.name, c.help, c.name, c.name, c.fn())
+}
+
+// histogram counts observations in cumulative buckets with the given upper
+// bounds, which must be sorted.
+type histogram struct {
+	name    string
+	help    string
+	buckets []float64
+
+	mu     sync.Mutex
+	counts []uint64 // per bucket, plus one for +Inf
+	sum    float64
+}
+
+func newHistogram(name, help string, buckets []float64) *histogram {
+	return &histogram{name: name, help: help, buckets: buckets, counts: make([]uint64, len(buckets)+1)}
+}
+
+func (h *histogram) observe(v float64) {
+	i := sort.SearchFloat64s(h.buckets, v)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.counts[i]++
+	h.sum += v
+}
+
+func (h *histogram) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	var total uint64
+	for i, le := range h.buckets {
+		total += h.counts[i]
+		fmt.Fprintf(w, "%s_bucket{le=\"%g\"} %d\n", h.name, le, total)
+	}
+	total += h.counts[len(h.buckets)]
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n%s_sum %g\n%s_count %d\n", h.name, total, h.name, h.sum, h.name, total)
+}
+
+// maxCounterLabels bounds the label values a counterVec tracks, since they may
+// come from clients; the rest are counted as "other".
+const maxCounterLabels = 100
+
+// labelEscaper escapes a label value for the text exposition format.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// counterVec is a counter with one value per label value.
+type counterVec struct {
+	name  string
+	help  string
+	label string
+
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+func newCounterVec(name, help, label string) *counterVec {
+	return &counterVec{name: name, help: help, label: label, values: make(map[string]float64)}
+}
+
+func (c *counterVec) inc(value string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.values[value]; !ok && len(c.values) >= maxCounterLabels {
+		value = "other"
+	}
+	c.values[value]++
+}
+
+func (c *counterVec) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	values := make([]string, 0, len(c.values))
+	for v := range c.values {
+		values = append(values, v)
+	}
+	sort.Strings(values)
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+	for _, v := range values {
+		fmt.Fprintf(w, "%s{%s=\"%s\"} %g\n", c.name, c.label, labelEscaper.Replace(v), c.values[v])
+	}
+}
+
+// RegisterCounter adds a counter with one value per label value to /metrics,
+// so adapters can count their own events, and returns the function that
+// increments it. Like MetricsMiddleware's counters, it tracks at most 100
+// label values and counts the rest as "other".
+func RegisterCounter(name, help, label string) func(value string) {
+	c := newCounterVec(name, help, label)
+	defaultRegistry.register(name, c)
+	return c.inc
+}
+
+// registerMetrics exposes the proxy's runtime state as metrics.
+func (p *MCPProxy) registerMetrics() {
+	defaultRegistry.register("mcp_queue_depth", &gaugeFunc{
+		name: "mcp_queue_depth",
+		help: "Number of requests waiting to be sent to the MCP server.",
+		fn:   func() float64 { return float64(len(p.requests)) },
+	})
+	defaultRegistry.register(serverMessageBytes.name, serverMessageBytes)
+	defaultRegistry.register("mcp_pending_requests", &gaugeFunc{
+		name: "mcp_pending_requests",
+		help: "Number of requests waiting for a response from the MCP server.",
+		fn: func() float64 {
+			n, _ := p.pending.stats()
+			return float64(n)
+		},
+	})
+	defaultRegistry.register("mcp_oldest_pending_seconds", &gaugeFunc{
+		name: "mcp_oldest_pending_seconds",
+		help: "Age of the oldest request waiting for a response from the MCP server, 0 if none.",
+		fn: func() float64 {
+			_, oldest := p.pending.stats()
+			return oldest.Seconds()
+		},
+	})
+	defaultRegistry.register("mcp_breaker_state", &gaugeFunc{
+		name: "mcp_breaker_state",
+		help: "Circuit breaker state: 0 closed, 1 open, 2 half-open.",
+		fn:   func() float64 { return float64(p.breaker.currentState()) },
+	})
+	if p.instances != nil {
+		defaultRegistry.register("mcp_instance_pending", &gaugeVecFunc{
+			name:  "mcp_instance_pending",
+			help:  "Number of requests in flight per MCP server instance.",
+			label: "instance",
+			fn:    p.instances.pendingCounts,
+		})
+	}
+	if p.resources != nil {
+		defaultRegistry.register("mcp_process_cpu_usage", &gaugeFunc{
+			name: "mcp_process_cpu_usage",
+			help: "CPU cores used by the MCP server process over the last sample interval.",
+			fn:   p.resources.cpuUsage,
+		})
+		defaultRegistry.register("mcp_process_resident_memory_bytes", &gaugeFunc{
+			name: "mcp_process_resident_memory_bytes",
+			help: "Resident memory of the MCP server process in bytes.",
+			fn:   p.resources.residentBytes,
+		})
+		if p.config.MaxRSSBytes > 0 {
+			defaultRegistry.register("mcp_memory_restarts_total", &counterFunc{
+				name: "mcp_memory_restarts_total",
+				help: "Restarts of the MCP server process for exceeding MCP_MAX_RSS_BYTES.",
+				fn:   func() float64 { return float64(p.resources.memoryRestarts.Load()) },
+			})
+		}
+	}
+	if p.pinger != nil {
+		defaultRegistry.register("mcp_health_ping_latency_seconds", &gaugeFunc{
+			name: "mcp_health_ping_latency_seconds",
+			help: "Round-trip time of the last answered health ping to the MCP server.",
+			fn:   p.pinger.lastLatency,
+		})
+		defaultRegistry.register("mcp_health_ping_restarts_total", &counterFunc{
+			name: "mcp_health_ping_restarts_total",
+			help: "Restarts of the MCP server process for not answering health pings.",
+			fn:   func() float64 { return float64(p.pinger.restarts.Load()) },
+		})
+	}
+	if p.audit != nil {
+		defaultRegistry.register("mcp_audit_dropped_total", &counterFunc{
+			name: "mcp_audit_dropped_total",
+			help: "Audit log entries dropped because the writer fell behind.",
+			fn:   func() float64 { return float64(p.audit.dropped.Load()) },
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package mcpproxy
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestQueueDepthMetric(t *testing.T) {
+	proxy := &MCPProxy{
+		config:   Config{ServerName: "test"},
+		requests: make(chan *request, 3),
+	}
+	proxy.registerMetrics()
+	proxy.requests <- &request{}
+	proxy.requests <- &request{}
+
+	w := httptest.NewRecorder()
+	defaultRegistry.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	body := w.Body.String()
+	if !strings.Contains(body, "# TYPE mcp_queue_depth gauge") {
+		t.Errorf("Expected gauge type line, got:\n%s", body)
+	}
+	if !strings.Contains(body, "mcp_queue_depth 2\n") {
+		t.Errorf("Expected queue depth 2, got:\n%s", body)
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	h := newHistogram("test_bytes", "Test.", []float64{10, 100})
+	for _, v := range []float64{5, 10, 50, 500} {
+		h.observe(v)
+	}
+
+	w := httptest.NewRecorder()
+	h.write(w)
+
+	want := `# HELP test_bytes Test.
+# TYPE test_bytes histogram
+test_bytes_bucket{le="10"} 2
+test_bytes_bucket{le="100"} 3
+test_bytes_bucket{le="+Inf"} 4
+test_bytes_sum 565
+test_bytes_count 4
+`
+	if got := w.Body.String(); got != want {
+		t.Errorf("Unexpected output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRegisterCounter(t *testing.T) {
+	inc := RegisterCounter("test_events_total", "Test events.", "code")
+	inc("a")
+	inc("a")
+
+	w := httptest.NewRecorder()
+	defaultRegistry.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if !strings.Contains(w.Body.String(), `test_events_total{code="a"} 2`) {
+		t.Errorf("Expected the counter on /metrics, got:\n%s", w.Body.String())
+	}
+}
@@ -0,0 +1,79 @@
+package mcpproxy
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// RequestMiddleware transforms a message on its way to the MCP server. An
+// error rejects the message: the client is answered with a JSON-RPC error
+// instead, carrying the code of an *RPCError, such as CodeNotAllowed or
+// CodeUnauthorized, or CodeInvalidRequest, and later middlewares and the MCP
+// server never see it.
+type RequestMiddleware func(request []byte) ([]byte, error)
+
+// ResponseMiddleware transforms a response on its way to the client.
+type ResponseMiddleware func(response []byte) []byte
+
+// RPCError is an error a RequestMiddleware returns to answer the client with a
+// specific JSON-RPC error code.
+type RPCError struct {
+	Code    int
+	Message string
+}
+
+func (e *RPCError) Error() string {
+	return e.Message
+}
+
+// applyRequestMiddlewares runs msg through Config.RequestMiddlewares in order,
+// then the deprecated RequestMiddleware. If a middleware rejects msg, it
+// returns the error response for the client instead, or nil for a
+// notification.
+func (c *Config) applyRequestMiddlewares(msg json.RawMessage) (out json.RawMessage, rejection json.RawMessage, rejected bool) {
+	for _, mw := range c.RequestMiddlewares {
+		next, err := mw(msg)
+		if err != nil {
+			if hasID(msg) {
+				rejection = c.rpcErrorResponse(messageID(msg), err)
+			}
+			return nil, rejection, true
+		}
+		msg = next
+	}
+	if c.RequestMiddleware != nil {
+		msg = c.RequestMiddleware(msg)
+	}
+	return msg, nil, false
+}
+
+// applyResponseMiddlewares runs response through Config.ResponseMiddlewares
+// in order, then the deprecated ResponseMiddleware.
+func (c *Config) applyResponseMiddlewares(response json.RawMessage) json.RawMessage {
+	for _, mw := range c.ResponseMiddlewares {
+		response = mw(response)
+	}
+	if c.ResponseMiddleware != nil {
+		response = c.ResponseMiddleware(response)
+	}
+	return response
+}
+
+// rpcErrorResponse builds the JSON-RPC error response for a request a
+// middleware rejected with err, with its code changed by c.ErrorCodes.
+func (c *Config) rpcErrorResponse(id interface{}, err error) json.RawMessage {
+	code := CodeInvalidRequest
+	var rpcErr *RPCError
+	if errors.As(err, &rpcErr) {
+		code = c.errorCode(rpcErr.Code)
+	}
+	response, _ := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      id,
+		"error": map[string]interface{}{
+			"code":    code,
+			"message": err.Error(),
+		},
+	})
+	return response
+}
@@ -0,0 +1,177 @@
+package mcpproxy
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// appendTag returns middleware that appends tag to the message's "tags" member.
+func appendTag(tag string) func([]byte) []byte {
+	return func(msg []byte) []byte {
+		var m map[string]interface{}
+		json.Unmarshal(msg, &m)
+		tags, _ := m["tags"].([]interface{})
+		m["tags"] = append(tags, tag)
+		out, _ := json.Marshal(m)
+		return out
+	}
+}
+
+func tagRequest(tag string) RequestMiddleware {
+	mw := appendTag(tag)
+	return func(msg []byte) ([]byte, error) { return mw(msg), nil }
+}
+
+func tags(t *testing.T, body []byte) string {
+	t.Helper()
+	var m struct {
+		Tags []string `json:"tags"`
+	}
+	if err := json.Unmarshal(body, &m); err != nil {
+		t.Fatalf("Invalid response %s: %v", body, err)
+	}
+	return strings.Join(m.Tags, ",")
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	// cat echoes the request, so the response shows what the server received
+	proxy, err := NewMCPProxy(Config{
+		ServerName:          "test",
+		CommandPath:         "cat",
+		RequestMiddlewares:  []RequestMiddleware{tagRequest("req1"), tagRequest("req2")},
+		RequestMiddleware:   appendTag("req-deprecated"),
+		ResponseMiddlewares: []ResponseMiddleware{appendTag("resp1"), appendTag("resp2")},
+		ResponseMiddleware:  appendTag("resp-deprecated"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+
+	w := ping(proxy)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	want := "req1,req2,req-deprecated,resp1,resp2,resp-deprecated"
+	if got := tags(t, w.Body.Bytes()); got != want {
+		t.Errorf("Expected middlewares applied in order %s, got %s", want, got)
+	}
+}
+
+func TestRequestMiddlewareShortCircuits(t *testing.T) {
+	var laterCalls int
+	later := func(msg []byte) ([]byte, error) {
+		laterCalls++
+		return msg, nil
+	}
+	reject := func(msg []byte) ([]byte, error) {
+		var m MCPMessage
+		json.Unmarshal(msg, &m)
+		switch m.Method {
+		case "tools/call":
+			return nil, &RPCError{Code: -32003, Message: "not authorized"}
+		case "resources/read", "notifications/initialized":
+			return nil, errors.New("resources are disabled")
+		}
+		return msg, nil
+	}
+	var responseCalls int
+	proxy, err := NewMCPProxy(Config{
+		ServerName:          "test",
+		CommandPath:         "cat",
+		RequestMiddlewares:  []RequestMiddleware{reject, later},
+		ResponseMiddlewares: []ResponseMiddleware{func(r []byte) []byte { responseCalls++; return r }},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+
+	post := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		proxy.Handle(w, httptest.NewRequest("POST", "/", strings.NewReader(body)))
+		return w
+	}
+
+	w := post(`{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"name":"x"}}`)
+	if w.Code != http.StatusOK || w.Body.String() != `{"error":{"code":-32003,"message":"not authorized"},"id":7,"jsonrpc":"2.0"}` {
+		t.Errorf("Expected the middleware's JSON-RPC error, got %d: %s", w.Code, w.Body.String())
+	}
+	w = post(`{"jsonrpc":"2.0","id":"r","method":"resources/read"}`)
+	if !strings.Contains(w.Body.String(), `"code":-32600`) || !strings.Contains(w.Body.String(), `"id":"r"`) {
+		t.Errorf("Expected Invalid Request for a plain error, got %s", w.Body.String())
+	}
+	if w := post(`{"jsonrpc":"2.0","method":"notifications/initialized"}`); w.Code != http.StatusAccepted {
+		t.Errorf("Expected 202 for a rejected notification, got %d", w.Code)
+	}
+	if laterCalls != 0 || responseCalls != 0 {
+		t.Errorf("Expected rejected messages to skip later middlewares, got %d request and %d response calls", laterCalls, responseCalls)
+	}
+
+	// The MCP server never saw the rejected messages, so it still answers
+	// with the right response
+	if w := ping(proxy); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"method":"ping"`) {
+		t.Errorf("Expected the ping to be echoed, got %d: %s", w.Code, w.Body.String())
+	}
+	if laterCalls != 1 || responseCalls != 1 {
+		t.Errorf("Expected one call of each middleware for the ping, got %d and %d", laterCalls, responseCalls)
+	}
+}
+
+func TestResponseRetry(t *testing.T) {
+	busy := json.RawMessage(`{"jsonrpc":"2.0","id":1,"error":{"code":-32603,"message":"busy"}}`)
+	ok := json.RawMessage(`{"jsonrpc":"2.0","id":1,"result":{}}`)
+
+	tests := []struct {
+		name      string
+		failures  int
+		timeout   time.Duration
+		delay     time.Duration
+		wantSent  int
+		wantError bool
+	}{
+		{"succeeds after a retry", 1, 0, time.Millisecond, 2, false},
+		{"retries run out", 10, 0, time.Millisecond, 3, true},
+		{"delay past the deadline", 1, 50 * time.Millisecond, time.Minute, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxy := &MCPProxy{
+				config: Config{
+					ServerName:         "test",
+					RequestTimeout:     tt.timeout,
+					MaxResponseRetries: 2,
+					ResponseRetry: func(response []byte) (time.Duration, bool) {
+						return tt.delay, strings.Contains(string(response), "busy")
+					},
+				},
+				logger:   newLogger("test", "warn"),
+				requests: make(chan *request, 1),
+			}
+			sent := 0
+			drainRequests(proxy, func(json.RawMessage) json.RawMessage {
+				sent++
+				if sent <= tt.failures {
+					return busy
+				}
+				return ok
+			})
+
+			w := httptest.NewRecorder()
+			proxy.Handle(w, httptest.NewRequest("POST", "/", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/call"}`)))
+			close(proxy.requests)
+
+			if sent != tt.wantSent {
+				t.Errorf("Expected the request to be sent %d times, got %d", tt.wantSent, sent)
+			}
+			if gotError := strings.Contains(w.Body.String(), "busy"); gotError != tt.wantError {
+				t.Errorf("Expected error %v, got %s", tt.wantError, w.Body.String())
+			}
+		})
+	}
+}
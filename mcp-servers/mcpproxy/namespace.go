@@ -0,0 +1,124 @@
+package mcpproxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// defaultToolSeparator joins a backend name and a tool name, as in
+// "oracle__run_sql".
+const defaultToolSeparator = "__"
+
+// toolNamespace qualifies tool names with the backend that serves them, so
+// the tools of several MCP servers can be listed together without colliding
+// and calls can be routed back to the right server.
+type toolNamespace struct {
+	sep      string
+	backends map[string]bool
+}
+
+// newToolNamespace returns a namespace for the named backends, joined to tool
+// names with sep ("" for defaultToolSeparator). Backend names must be unique
+// and not contain sep, so qualified names split unambiguously even when tool
+// names contain it.
+func newToolNamespace(sep string, backends []string) (*toolNamespace, error) {
+	if sep == "" {
+		sep = defaultToolSeparator
+	}
+	ns := &toolNamespace{sep: sep, backends: make(map[string]bool, len(backends))}
+	for _, b := range backends {
+		switch {
+		case b == "":
+			return nil, fmt.Errorf("backend name is empty")
+		case strings.Contains(b, sep):
+			return nil, fmt.Errorf("backend name %q contains the tool separator %q", b, sep)
+		case ns.backends[b]:
+			return nil, fmt.Errorf("duplicate backend name %q", b)
+		}
+		ns.backends[b] = true
+	}
+	return ns, nil
+}
+
+// qualify returns the name clients see for a backend's tool.
+func (ns *toolNamespace) qualify(backend, tool string) string {
+	return backend + ns.sep + tool
+}
+
+// split returns the backend and tool of a qualified name, or ok false if it
+// doesn't name a known backend.
+func (ns *toolNamespace) split(name string) (backend, tool string, ok bool) {
+	backend, tool, found := strings.Cut(name, ns.sep)
+	if !found || !ns.backends[backend] || tool == "" {
+		return "", "", false
+	}
+	return backend, tool, true
+}
+
+// mergeLists combines the tools/list responses of several backends, keyed by
+// backend name, into one response to the request with id. Tool names are
+// qualified and the backends are listed in name order. A response without a
+// tool list, such as an error, contributes no tools.
+func (ns *toolNamespace) mergeLists(id json.RawMessage, responses map[string]json.RawMessage) (json.RawMessage, error) {
+	backends := make([]string, 0, len(responses))
+	for b := range responses {
+		backends = append(backends, b)
+	}
+	sort.Strings(backends)
+
+	merged := []map[string]json.RawMessage{}
+	for _, b := range backends {
+		var resp struct {
+			Result struct {
+				Tools []map[string]json.RawMessage `json:"tools"`
+			} `json:"result"`
+		}
+		if err := json.Unmarshal(responses[b], &resp); err != nil {
+			continue
+		}
+		for _, tool := range resp.Result.Tools {
+			var name string
+			if err := json.Unmarshal(tool["name"], &name); err != nil || name == "" {
+				continue
+			}
+			tool["name"], _ = json.Marshal(ns.qualify(b, name))
+			merged = append(merged, tool)
+		}
+	}
+
+	return json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      id,
+		"result":  map[string]interface{}{"tools": merged},
+	})
+}
+
+// routeCall returns the backend a tools/call is for and the message to send
+// it, with the tool name unqualified. It fails for calls to unknown backends.
+func (ns *toolNamespace) routeCall(msg json.RawMessage) (string, json.RawMessage, error) {
+	var call map[string]json.RawMessage
+	if err := json.Unmarshal(msg, &call); err != nil {
+		return "", nil, err
+	}
+	var params map[string]json.RawMessage
+	if err := json.Unmarshal(call["params"], &params); err != nil {
+		return "", nil, fmt.Errorf("invalid tools/call params: %w", err)
+	}
+	var name string
+	json.Unmarshal(params["name"], &name)
+
+	backend, tool, ok := ns.split(name)
+	if !ok {
+		return "", nil, fmt.Errorf("unknown tool %q", name)
+	}
+	params["name"], _ = json.Marshal(tool)
+
+	var err error
+	if call["params"], err = json.Marshal(params); err != nil {
+		return "", nil, err
+	}
+	out, err := json.Marshal(call)
+	return backend, out, err
+}
@@ -0,0 +1,118 @@
+package mcpproxy
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestNewToolNamespaceValidatesBackends(t *testing.T) {
+	for _, backends := range [][]string{{""}, {"oracle", "oracle"}, {"my__db"}} {
+		if _, err := newToolNamespace("", backends); err == nil {
+			t.Errorf("Expected an error for backends %q", backends)
+		}
+	}
+	if _, err := newToolNamespace(".", []string{"my__db"}); err != nil {
+		t.Errorf("Expected a backend name with the default separator to be fine with another one, got %v", err)
+	}
+}
+
+func TestToolNamespaceOverlappingTools(t *testing.T) {
+	ns, err := newToolNamespace("", []string{"oracle", "github"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	merged, err := ns.mergeLists(json.RawMessage(`7`), map[string]json.RawMessage{
+		"oracle": json.RawMessage(`{"jsonrpc":"2.0","id":1,"result":{"tools":[{"name":"search","description":"Search tables"},{"name":"run_sql"}]}}`),
+		"github": json.RawMessage(`{"jsonrpc":"2.0","id":1,"result":{"tools":[{"name":"search","description":"Search code"}]}}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var resp struct {
+		ID     int `json:"id"`
+		Result struct {
+			Tools []struct {
+				Name        string `json:"name"`
+				Description string `json:"description"`
+			} `json:"tools"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(merged, &resp); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	descriptions := map[string]string{}
+	for _, tool := range resp.Result.Tools {
+		names = append(names, tool.Name)
+		descriptions[tool.Name] = tool.Description
+	}
+	if want := []string{"github__search", "oracle__search", "oracle__run_sql"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("Expected tools %q, got %q", want, names)
+	}
+	if descriptions["github__search"] != "Search code" || descriptions["oracle__search"] != "Search tables" {
+		t.Errorf("Expected each tool to keep its backend's definition, got %v", descriptions)
+	}
+	if resp.ID != 7 {
+		t.Errorf("Expected the merged response to answer ID 7, got %d", resp.ID)
+	}
+
+	// Each qualified name routes back to its own backend's tool
+	for name, want := range map[string]string{"github__search": "github", "oracle__search": "oracle"} {
+		backend, msg, err := ns.routeCall(json.RawMessage(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"` + name + `","arguments":{"q":"x"}}}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if backend != want {
+			t.Errorf("%s: expected backend %s, got %s", name, want, backend)
+		}
+		var call struct {
+			ID     int `json:"id"`
+			Params struct {
+				Name      string          `json:"name"`
+				Arguments json.RawMessage `json:"arguments"`
+			} `json:"params"`
+		}
+		json.Unmarshal(msg, &call)
+		if call.Params.Name != "search" || string(call.Params.Arguments) != `{"q":"x"}` || call.ID != 2 {
+			t.Errorf("%s: expected an unqualified call with the same ID and arguments, got %s", name, msg)
+		}
+	}
+}
+
+func TestToolNamespaceSplit(t *testing.T) {
+	ns, _ := newToolNamespace("", []string{"oracle"})
+	tests := []struct {
+		name          string
+		backend, tool string
+		ok            bool
+	}{
+		{"oracle__run_sql", "oracle", "run_sql", true},
+		{"oracle__list__tables", "oracle", "list__tables", true},
+		{"github__search", "", "", false},
+		{"oracle__", "", "", false},
+		{"run_sql", "", "", false},
+	}
+	for _, tt := range tests {
+		backend, tool, ok := ns.split(tt.name)
+		if backend != tt.backend || tool != tt.tool || ok != tt.ok {
+			t.Errorf("split(%q) = %q, %q, %v, want %q, %q, %v", tt.name, backend, tool, ok, tt.backend, tt.tool, tt.ok)
+		}
+	}
+}
+
+func TestToolNamespaceMergeSkipsErrors(t *testing.T) {
+	ns, _ := newToolNamespace("", []string{"a", "b"})
+	merged, err := ns.mergeLists(json.RawMessage(`1`), map[string]json.RawMessage{
+		"a": json.RawMessage(`{"jsonrpc":"2.0","id":1,"error":{"code":-32603,"message":"down"}}`),
+		"b": json.RawMessage(`{"jsonrpc":"2.0","id":1,"result":{"tools":[{"name":"t"}]}}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"id":1,"jsonrpc":"2.0","result":{"tools":[{"name":"b__t"}]}}`; string(merged) != want {
+		t.Errorf("Expected %s, got %s", want, merged)
+	}
+}
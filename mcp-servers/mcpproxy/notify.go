@@ -0,0 +1,94 @@
+package mcpproxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// notificationBuffer is how many notifications a slow client may fall
+// behind by before further ones are dropped for it.
+const notificationBuffer = 16
+
+// notificationHub fans out server notifications to clients listening on the
+// SSE stream. A nil *notificationHub drops everything.
+type notificationHub struct {
+	mu   sync.Mutex
+	subs map[chan json.RawMessage]struct{}
+}
+
+func newNotificationHub() *notificationHub {
+	return &notificationHub{subs: make(map[chan json.RawMessage]struct{})}
+}
+
+// subscribe registers a listener. The returned cancel func must be called
+// once the listener is done.
+func (h *notificationHub) subscribe() (<-chan json.RawMessage, func()) {
+	ch := make(chan json.RawMessage, notificationBuffer)
+	h.mu.Lock()
+	h.subs[ch] = struct{}{}
+	h.mu.Unlock()
+	return ch, func() {
+		h.mu.Lock()
+		delete(h.subs, ch)
+		h.mu.Unlock()
+	}
+}
+
+// publish delivers msg to every listener without blocking and returns how
+// many received it.
+func (h *notificationHub) publish(msg json.RawMessage) int {
+	if h == nil {
+		return 0
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delivered := 0
+	for ch := range h.subs {
+		select {
+		case ch <- msg:
+			delivered++
+		default:
+		}
+	}
+	return delivered
+}
+
+// HandleNotifications streams server notifications to the client as
+// Server-Sent Events, as in the streamable HTTP transport's GET stream. With
+// sessions, the client receives its own session process's notifications.
+func (p *MCPProxy) HandleNotifications(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	target, release, err := p.sessionFor(r)
+	if err != nil {
+		writeJSONRPCError(w, http.StatusServiceUnavailable, nil, codeServerBusy, err.Error())
+		return
+	}
+	defer release()
+
+	ch, cancel := target.notifications.subscribe()
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	p.logger.debugf("Notification stream opened by %s", r.RemoteAddr)
+
+	for {
+		select {
+		case <-r.Context().Done():
+			p.logger.debugf("Notification stream closed by %s", r.RemoteAddr)
+			return
+		case msg := <-ch:
+			fmt.Fprintf(w, "event: message\ndata: %s\n\n", msg)
+			flusher.Flush()
+		}
+	}
+}
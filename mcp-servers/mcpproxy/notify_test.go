@@ -0,0 +1,108 @@
+package mcpproxy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNotificationHubDropsForSlowClients(t *testing.T) {
+	h := newNotificationHub()
+	ch, cancel := h.subscribe()
+	defer cancel()
+
+	for i := 0; i < notificationBuffer; i++ {
+		if n := h.publish(json.RawMessage(`{}`)); n != 1 {
+			t.Fatalf("Expected delivery to the listener, got %d", n)
+		}
+	}
+	if n := h.publish(json.RawMessage(`{}`)); n != 0 {
+		t.Errorf("Expected a full listener to be skipped, got %d deliveries", n)
+	}
+	if len(ch) != notificationBuffer {
+		t.Errorf("Expected %d buffered notifications, got %d", notificationBuffer, len(ch))
+	}
+
+	var nilHub *notificationHub
+	if n := nilHub.publish(json.RawMessage(`{}`)); n != 0 {
+		t.Errorf("Expected a nil hub to drop notifications, got %d", n)
+	}
+}
+
+func TestNotificationStreamForwardsListChanged(t *testing.T) {
+	proxy := &MCPProxy{
+		config:        Config{ServerName: "test"},
+		notifications: newNotificationHub(),
+	}
+	srv := httptest.NewServer(http.HandlerFunc(proxy.Handle))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got Content-Type %q", ct)
+	}
+
+	// Wait for the stream to subscribe before the server emits
+	deadline := time.Now().Add(5 * time.Second)
+	notification := json.RawMessage(`{"jsonrpc":"2.0","method":"notifications/tools/list_changed"}`)
+	for proxy.notifications.publish(notification) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Notification stream never subscribed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	var event []string
+	for len(event) < 2 {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			event = append(event, line)
+		}
+	}
+	if event[0] != "event: message" || event[1] != "data: "+string(notification) {
+		t.Errorf("Unexpected event: %q", event)
+	}
+}
+
+func TestLogSkippedNotifications(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	for _, tc := range []struct {
+		level  string
+		logged bool
+	}{{"", false}, {"debug", false}, {"info", true}, {"warn", true}} {
+		buf.Reset()
+		proxy := &MCPProxy{
+			config:        Config{ServerName: "test", LogSkippedNotifications: tc.level},
+			logger:        newLogger("test", "info"),
+			notifications: newNotificationHub(),
+		}
+		proxy.observeNotification(json.RawMessage(`{"jsonrpc":"2.0","method":"notifications/progress"}`))
+		proxy.observeNotification(json.RawMessage(`{"jsonrpc":"2.0","result":{}}`))
+
+		out := buf.String()
+		if got := strings.Contains(out, "Dropped notification notifications/progress"); got != tc.logged {
+			t.Errorf("LOG_SKIPPED_NOTIFICATIONS=%q: expected the method logged at info: %v, got %q", tc.level, tc.logged, out)
+		}
+		if got := strings.Contains(out, "neither id nor method"); got != tc.logged {
+			t.Errorf("LOG_SKIPPED_NOTIFICATIONS=%q: expected the message without a method flagged: %v, got %q", tc.level, tc.logged, out)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package mcpproxy
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// openAPISpec describes the proxy's management endpoints, on the main and
+// admin listeners, but not the MCP tools, which the MCP server owns. Keep it
+// in sync with newMux and newAdminMux.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// HandleOpenAPI serves the OpenAPI description of the management endpoints.
+func (p *MCPProxy) HandleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPISpec)
+}
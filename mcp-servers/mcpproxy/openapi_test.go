@@ -0,0 +1,78 @@
+package mcpproxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenAPISpec(t *testing.T) {
+	var spec struct {
+		OpenAPI string `json:"openapi"`
+		Paths   map[string]map[string]struct {
+			Tags []string `json:"tags"`
+		} `json:"paths"`
+	}
+	if err := json.Unmarshal(openAPISpec, &spec); err != nil {
+		t.Fatalf("Invalid spec: %v", err)
+	}
+	if spec.OpenAPI == "" {
+		t.Error("Expected an openapi version")
+	}
+
+	mounted := map[string]string{
+		"/healthz": "main", "/readyz": "main", "/metrics": "main", "/logs": "main", "/config": "main", "/openapi.json": "main", "/version": "main",
+		"/debug/cmd": "admin", "/debug/pending": "admin", "/debug/pprof/": "admin", "/debug/vars": "admin",
+		"/admin/restart": "admin", "/admin/drain": "admin", "/admin/reload": "admin", "/debug/recent": "admin",
+	}
+	for path, listener := range mounted {
+		ops, ok := spec.Paths[path]
+		if !ok {
+			t.Errorf("Expected %s in the spec", path)
+			continue
+		}
+		for method, op := range ops {
+			if len(op.Tags) != 1 || op.Tags[0] != listener {
+				t.Errorf("Expected %s %s to be tagged %s, got %v", method, path, listener, op.Tags)
+			}
+		}
+	}
+
+	// Every path in the spec is served
+	proxy := &MCPProxy{
+		config: Config{ServerName: "test", EnablePprof: true, AdminToken: "secret", EnableDiagnostics: true},
+		logger: newLogger("test", "warn"),
+		stderr: newLineBuffer(10),
+		recent: newRecentRequests(10),
+	}
+	mux := proxy.newMux()
+	admin := proxy.newAdminMux()
+	for path := range spec.Paths {
+		switch mounted[path] {
+		case "main":
+			if _, pattern := mux.Handler(httptest.NewRequest("GET", path, nil)); pattern != path {
+				t.Errorf("Expected %s to be routed on the main listener, got pattern %q", path, pattern)
+			}
+		case "admin":
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", path, nil)
+			r.Header.Set("Authorization", "Bearer secret")
+			admin.ServeHTTP(w, r)
+			if w.Code == http.StatusNotFound {
+				t.Errorf("Expected %s to be served on the admin listener", path)
+			}
+		default:
+			t.Errorf("Spec lists %s, which isn't mounted", path)
+		}
+	}
+}
+
+func TestHandleOpenAPI(t *testing.T) {
+	proxy := &MCPProxy{config: Config{ServerName: "test"}}
+	w := httptest.NewRecorder()
+	proxy.newMux().ServeHTTP(w, httptest.NewRequest("GET", "/openapi.json", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/json" || !json.Valid(w.Body.Bytes()) {
+		t.Errorf("Expected the spec as JSON, got %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+}
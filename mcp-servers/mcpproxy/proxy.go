@@ -4,196 +4,1078 @@ package mcpproxy
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"math"
+	"net"
 	"net/http"
 	"os"
 	"os/exec"
+	"os/signal"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 )
 
 // Config defines the configuration for an MCP proxy server.
 type Config struct {
 	// ServerName is used for logging (e.g., "github-mcp", "sqlcl")
+	// (default: the command's file name, env: MCP_SERVER_NAME)
 	ServerName string
 
 	// CommandPath is the default path to the MCP server binary
 	CommandPath string
 
-	// CommandArgs are the arguments to pass to the MCP server (e.g., "stdio", "-mcp")
+	// CommandArgs are the arguments to pass to the MCP server (e.g., "stdio", "-mcp").
+	// MCP_ARGS overrides them; see commandArgs for the accepted formats.
 	CommandArgs []string
 
-	// PathEnvVar is the environment variable name to override CommandPath (optional)
+	// PathEnvVar is the environment variable name to override CommandPath
+	// (optional). MCP_COMMAND, which every proxy accepts, takes precedence
 	PathEnvVar string
 
+	// BackendType is "stdio" to run CommandPath, or "http" to forward messages
+	// to an MCP server at UpstreamURL that speaks streamable HTTP
+	// (default: "stdio", env: MCP_BACKEND_TYPE)
+	BackendType string
+
+	// UpstreamURL is the MCP endpoint of the upstream server when BackendType
+	// is "http", e.g. http://mcp-server:8000/mcp (env: MCP_UPSTREAM_URL)
+	UpstreamURL string
+
+	// WorkDir is the working directory of the MCP server; it must exist
+	// (default: the proxy's working directory, env: MCP_CWD)
+	WorkDir string
+
+	// RunAsUID and RunAsGID are the user and group, by id or name, that the
+	// MCP server runs as, so it doesn't inherit root from the proxy. Both
+	// must exist and changing them requires the proxy to run as root; the
+	// group defaults to the user's primary group (env: MCP_RUN_AS_UID,
+	// MCP_RUN_AS_GID; unix only)
+	RunAsUID string
+	RunAsGID string
+
+	// Nice is the niceness, from -20 to 19, the MCP server runs at, e.g. 10
+	// to keep it from starving the proxy on a shared node; 0 keeps the
+	// proxy's. Negative values require CAP_SYS_NICE (env: MCP_NICE; Linux only)
+	Nice int
+
+	// CPUAffinity restricts the MCP server to a list of CPUs and ranges such
+	// as "0-3,6" (env: MCP_CPU_AFFINITY; Linux only)
+	CPUAffinity string
+
+	// ExtraEnv are KEY=VALUE pairs added to the MCP server's environment on top of
+	// the proxy's own (env: MCP_EXTRA_ENV, comma-separated or "@/path/to/file").
+	// Variables from MCP_ENV_FILE are added before these.
+	ExtraEnv []string
+
 	// Port is the HTTP port to listen on (default: "8080")
 	Port string
 
-	// EnableCORS adds CORS headers to responses
+	// ListenAddr is the host:port to listen on, e.g. "127.0.0.1:8080" to accept
+	// only local connections in a sidecar; overrides Port (env: LISTEN_ADDR)
+	ListenAddr string
+
+	// ListenUnix is the path of a Unix domain socket to serve on instead of TCP,
+	// so a sidecar needs no open port at all (env: LISTEN_UNIX)
+	ListenUnix string
+
+	// EnablePprof serves net/http/pprof under /debug/pprof/ and expvar under
+	// /debug/vars on a separate admin listener (default: false, env: ENABLE_PPROF)
+	EnablePprof bool
+
+	// AdminToken enables the /admin/restart, /admin/drain and /admin/reload
+	// endpoints on the admin listener and requires it as a bearer token for
+	// every admin request (env: ADMIN_TOKEN)
+	AdminToken string
+
+	// AdminAddr is the address of the admin listener. Profiles expose memory
+	// contents, so keep it on loopback (default: "127.0.0.1:6060", env: ADMIN_ADDR)
+	AdminAddr string
+
+	// AdminPort moves /healthz, /readyz, /metrics, /openapi.json and
+	// /version off the main listener to one on this port, on all interfaces
+	// so probes and scrapers can reach it, leaving the main port to MCP
+	// traffic, and serves /logs and /config there too. Profiling and the
+	// /admin/ endpoints stay on AdminAddr (env: ADMIN_PORT)
+	AdminPort string
+
+	// EnableDiagnostics serves /logs and /config on the main listener when
+	// AdminPort is unset. They show the MCP server's stderr and the effective
+	// configuration, so they require AdminToken as a bearer token
+	// (default: false, env: ENABLE_DIAGNOSTICS)
+	EnableDiagnostics bool
+
+	// QueueSize is the number of requests that may wait for the MCP server
+	// before new ones are rejected with HTTP 429 (default: 100, env: MCP_QUEUE_SIZE)
+	QueueSize int
+
+	// WarnMessageBytes is the size above which a message read from the MCP
+	// server is logged with a warning; a negative value disables the warning.
+	// Sizes are also exported as the mcp_server_message_bytes histogram
+	// (default: 1 MiB, env: MCP_WARN_MESSAGE_BYTES)
+	WarnMessageBytes int
+
+	// FastNotifications answers notifications with HTTP 202 as soon as they
+	// are queued, without waiting for them to be written to the MCP server.
+	// They are still written in order; a failed write is only logged
+	// (default: false, env: FAST_NOTIFICATIONS)
+	FastNotifications bool
+
+	// WriteTimeout bounds how long writing a message to the MCP server's stdin
+	// may block. A server that stops reading is killed and treated as exited,
+	// so it can't wedge every other request; a negative value disables the
+	// deadline (default: 30s, env: MCP_WRITE_TIMEOUT)
+	WriteTimeout time.Duration
+
+	// RequestTimeout bounds how long a client waits for the MCP server to
+	// answer a request before getting HTTP 504; the server is then sent
+	// notifications/cancelled. Clients can set their own with an
+	// X-Request-Timeout header, up to MaxRequestTimeout. 0 means no deadline
+	// (default: 0, env: MCP_REQUEST_TIMEOUT)
+	RequestTimeout time.Duration
+
+	// ToolTimeouts replaces RequestTimeout for calls to the named tools, with
+	// a "default" entry for other tools; other methods keep RequestTimeout.
+	// X-Request-Timeout still takes precedence (env: MCP_TOOL_TIMEOUTS, as
+	// comma-separated tool=duration pairs, e.g. "run_sql=300s,default=30s")
+	ToolTimeouts map[string]time.Duration
+
+	// MaxRequestTimeout caps the X-Request-Timeout a client may ask for; a
+	// negative value allows any (default: 10m, env: MCP_MAX_REQUEST_TIMEOUT)
+	MaxRequestTimeout time.Duration
+
+	// CancelKillGrace is how long the MCP server has to answer a request that
+	// timed out before its process is killed, failing every other request in
+	// flight. Until it answers, no other response can be read. Requests whose
+	// client disconnected or cancelled are only sent notifications/cancelled.
+	// 0 never kills (default: 0, env: MCP_CANCEL_KILL_GRACE)
+	CancelKillGrace time.Duration
+
+	// MaxRestarts is how many times in a row the MCP server process is
+	// restarted after it exits, waiting longer before each attempt; a request
+	// answered by the new process resets the count. Session processes are
+	// replaced on their next request instead. A negative value disables
+	// restarting (default: 5, env: MCP_MAX_RESTARTS)
+	MaxRestarts int
+
+	// StopSignal lists the steps taken to stop the MCP server process, on
+	// shutdown or before a restart, comma-separated: stdin-close, then
+	// signals such as SIGTERM or SIGINT. Its stdin is always closed first,
+	// and the server gets StopTimeout to exit after each step before the
+	// next; after the last it is killed (default: stdin-close,SIGTERM, env:
+	// MCP_STOP_SIGNAL)
+	StopSignal string
+
+	// StopTimeout is how long the MCP server gets to exit after each step
+	// of StopSignal (default: 5s, env: MCP_STOP_TIMEOUT)
+	StopTimeout time.Duration
+
+	// CrashWebhookURL is sent a JSON POST each time the MCP server process
+	// dies unexpectedly, with its exit code, the restart count and its last
+	// stderr lines (env: CRASH_WEBHOOK_URL)
+	CrashWebhookURL string
+
+	// EnableMetrics samples the MCP server process's CPU and resident memory
+	// for /metrics, on Linux (default: false, env: ENABLE_METRICS)
+	EnableMetrics bool
+
+	// ResourceSampleInterval is how often EnableMetrics samples the process
+	// (default: 15s, env: RESOURCE_SAMPLE_INTERVAL)
+	ResourceSampleInterval time.Duration
+
+	// MaxRSSBytes restarts the MCP server process once its resident memory
+	// has stayed above this many bytes for MaxRSSWindow, to contain leaks in
+	// long-lived servers. Linux only; 0 disables (default: 0,
+	// env: MCP_MAX_RSS_BYTES)
+	MaxRSSBytes int
+
+	// MaxRSSWindow is how long memory must stay above MaxRSSBytes, so spikes
+	// don't cause restarts (default: 1m, env: MCP_MAX_RSS_WINDOW)
+	MaxRSSWindow time.Duration
+
+	// HealthPingInterval is how often the MCP server process is sent a ping
+	// to catch it hanging while still running; 0 disables
+	// (default: 0, env: HEALTH_PING_INTERVAL)
+	HealthPingInterval time.Duration
+
+	// HealthPingTimeout is how long a health ping may take before it counts
+	// as failed. Pings wait behind requests in progress, so it must exceed
+	// the slowest expected request (default: 30s, env: HEALTH_PING_TIMEOUT)
+	HealthPingTimeout time.Duration
+
+	// HealthPingFailures is how many failed health pings in a row get the
+	// process killed and restarted (default: 3, env: HEALTH_PING_FAILURES)
+	HealthPingFailures int
+
+	// BreakerThreshold is the number of consecutive failed requests within
+	// BreakerWindow that opens the circuit breaker, after which requests get
+	// HTTP 503 for BreakerCooldown; a negative value disables the breaker
+	// (default: 5, env: BREAKER_THRESHOLD)
+	BreakerThreshold int
+
+	// BreakerWindow is the period in which failures count towards
+	// BreakerThreshold (default: 1m, env: BREAKER_WINDOW)
+	BreakerWindow time.Duration
+
+	// BreakerCooldown is how long the breaker stays open before a probe request
+	// is let through (default: 30s, env: BREAKER_COOLDOWN)
+	BreakerCooldown time.Duration
+
+	// MaxRequestBytes is the largest HTTP request body accepted; larger ones get
+	// HTTP 413 (default: 4 MiB, env: MAX_REQUEST_BYTES)
+	MaxRequestBytes int
+
+	// RateLimitRPS is the average number of requests per second each client
+	// may make, keyed by the X-Client-Id header from a trusted proxy (see
+	// TrustedProxies) or else the client IP; excess requests get HTTP 429.
+	// 0 disables rate limiting (default: 0, env: RATE_LIMIT_RPS)
+	RateLimitRPS float64
+
+	// RateLimitBurst is how many requests a client may make at once before
+	// RateLimitRPS applies (default: RateLimitRPS rounded up, env: RATE_LIMIT_BURST)
+	RateLimitBurst int
+
+	// ToolRateLimits limits tools/call requests to the named tools to the
+	// given average requests per second, across all clients, so an expensive
+	// tool can't overload the MCP server; a "default" entry gives each other
+	// tool the same limit of its own. Excess calls get HTTP 429 (env:
+	// MCP_TOOL_RATE_LIMITS, as comma-separated tool=rps pairs, e.g.
+	// "search_code=0.5,default=10")
+	ToolRateLimits map[string]float64
+
+	// ErrorCodes changes the JSON-RPC error codes of rejected requests, from
+	// CodeNotAllowed, CodeRateLimited or CodeUnauthorized to the code to send
+	// instead, also for RPCErrors from request middlewares (env:
+	// MCP_ERROR_CODES, as comma-separated name=code pairs with the names
+	// not_allowed, rate_limited and unauthorized, e.g. "rate_limited=-32029")
+	ErrorCodes map[int]int
+
+	// TrustedProxies are the IP addresses or CIDR ranges of load balancers
+	// whose X-Forwarded-For and X-Real-IP headers give the client IP for rate
+	// limiting and logs. Without any, those headers are ignored (env:
+	// TRUSTED_PROXIES, comma-separated)
+	TrustedProxies []string
+
+	// EnableCORS adds CORS headers to responses (env: ENABLE_CORS)
 	EnableCORS bool
 
+	// EnableCompression gzips responses for clients that send Accept-Encoding: gzip
+	// (default: false, env: ENABLE_COMPRESSION)
+	EnableCompression bool
+
+	// EnableH2C serves HTTP/2 without TLS to clients that use it with prior
+	// knowledge, so one connection can carry concurrent requests. HTTP/1.1 is
+	// still served. Needs a proxy built with Go 1.24 or later
+	// (default: false, env: ENABLE_H2C)
+	EnableH2C bool
+
+	// CompressionMinBytes is the smallest response that gets compressed; smaller ones
+	// aren't worth the overhead (default: 1024, env: COMPRESSION_MIN_BYTES)
+	CompressionMinBytes int
+
+	// StrictJSONRPC rejects messages whose "jsonrpc" member is missing or not
+	// "2.0", and notifications/* messages with an id or other methods without
+	// one, with an Invalid Request error (default: false, env: STRICT_JSONRPC)
+	StrictJSONRPC bool
+
+	// ToolsCacheTTL is how long a tools/list result is served from memory
+	// before asking the MCP server again. Each session process has its own
+	// cache; 0 disables caching (default: 0, env: TOOLS_CACHE_TTL)
+	ToolsCacheTTL time.Duration
+
+	// Delimiter separates messages on the MCP server's stdio: it is written
+	// after each message and read up to. The escapes \0, \n, \r, \t, \\ and
+	// \xNN are interpreted, and it must not occur inside a message
+	// (default: \n, env: MCP_DELIMITER)
+	Delimiter string
+
 	// SkipNotifications enables strict response ID matching when waiting for a response.
 	// When true: waits for a response with an ID matching the request ID (skipping mismatches)
 	// When false: returns the first response with any ID (suitable for sequential request/response)
 	// Note: Notifications (messages without ID) are always skipped regardless of this setting.
 	SkipNotifications bool
 
-	// ResponseMiddleware is called on each response before sending to client (optional)
-	// Use this for server-specific response processing (e.g., error detection)
+	// LogSkippedNotifications is the level, debug, info, warn or error, at
+	// which each message without an id read while waiting for a response is
+	// logged with its method, to spot servers that send responses without
+	// their id (default: debug, env: LOG_SKIPPED_NOTIFICATIONS)
+	LogSkippedNotifications string
+
+	// AllowedTools, when set, limits tools/list and tools/call to the named tools
+	// (env: MCP_ALLOWED_TOOLS, comma-separated)
+	AllowedTools []string
+
+	// DeniedTools hides and blocks the named tools, taking precedence over AllowedTools
+	// (env: MCP_DENIED_TOOLS, comma-separated)
+	DeniedTools []string
+
+	// CoalesceMethods are the methods, such as tools/list, whose identical
+	// requests in flight at the same time share one round trip to the MCP
+	// server. Only list read-only methods (env: MCP_COALESCE_METHODS,
+	// comma-separated)
+	CoalesceMethods []string
+
+	// LogLevel is the minimum level logged: debug, info, warn or error (default: "info", env: LOG_LEVEL).
+	// Per-message logs are emitted at debug; info covers lifecycle events only.
+	LogLevel string
+
+	// LogPayloads logs message bodies (with sensitive values redacted) instead of
+	// just their sizes (env: LOG_PAYLOADS)
+	LogPayloads bool
+
+	// RespectClientLogLevel sets the proxy's log level along with the MCP
+	// server's when a client sends logging/setLevel, e.g. to debug without a
+	// restart (default: false, env: RESPECT_CLIENT_LOG_LEVEL)
+	RespectClientLogLevel bool
+
+	// AccessLog logs one line per HTTP request with the client IP, HTTP and
+	// JSON-RPC methods, status and duration, whatever the log level
+	// (default: false, env: ACCESS_LOG)
+	AccessLog bool
+
+	// AuditLogFile is a file to which every tools/call is appended as a JSON
+	// line, with the tool, its arguments, the caller and the outcome
+	// (env: AUDIT_LOG_FILE)
+	AuditLogFile string
+
+	// AuditLogMaxBytes is the size at which the audit log is rotated to
+	// AuditLogFile.1; a negative value never rotates it (default: 100 MiB,
+	// env: AUDIT_LOG_MAX_BYTES)
+	AuditLogMaxBytes int
+
+	// AuditLogBackups is how many rotated audit logs are kept; a negative
+	// value keeps none (default: 5, env: AUDIT_LOG_BACKUPS)
+	AuditLogBackups int
+
+	// AuditRawArguments logs tool arguments as sent instead of masking them
+	// like logged payloads (default: false, env: AUDIT_LOG_RAW_ARGUMENTS)
+	AuditRawArguments bool
+
+	// RecentRequests is how many of the last requests and their responses,
+	// masked like logged payloads, are kept for /debug/recent on the admin
+	// listener, which requires AdminToken (default: 0, env: MCP_RECENT_REQUESTS)
+	RecentRequests int
+
+	// RedactKeys are the JSON key names whose values are masked in logged payloads
+	// (default: token, password, secret, authorization, connectString, apiKey;
+	// env: LOG_REDACT_KEYS, comma-separated)
+	RedactKeys []string
+
+	// LogRedactor masks server-specific secrets in logged payloads and stderr lines,
+	// such as credentials embedded in free text (optional). It runs after RedactKeys.
+	LogRedactor func(string) string
+
+	// StderrBufferLines is the number of recent MCP server stderr lines kept
+	// for the /logs endpoint (default: 200, env: STDERR_BUFFER_LINES)
+	StderrBufferLines int
+
+	// SessionFunc assigns HTTP requests to sessions (optional). Requests with a
+	// non-empty key are served by a dedicated MCP server process started with env
+	// added to its environment; an empty key uses the shared process. Use this when
+	// the server's identity, such as an API token, can only be set at startup.
+	SessionFunc func(r *http.Request) (key string, env []string)
+
+	// MaxSessions limits the number of session processes (default: 10, env: MCP_MAX_SESSIONS)
+	MaxSessions int
+
+	// MaxSessionsPerClient limits the session processes started for one
+	// client address. A client over it replaces its own least recently used
+	// idle session, so it can't evict other clients' sessions by sending new
+	// keys. Behind a load balancer it needs TrustedProxies, or every client
+	// shares the balancer's address. 0 means no limit
+	// (default: 0, env: MCP_MAX_SESSIONS_PER_CLIENT)
+	MaxSessionsPerClient int
+
+	// ReadyPattern is a regular expression matched against the MCP server's
+	// stderr lines. Until a line matches, readiness checks fail without
+	// sending it initialize, for servers that only answer once fully warmed
+	// up (env: MCP_READY_PROBE)
+	ReadyPattern string
+
+	// StartupDelay is how long after starting the MCP server readiness
+	// checks wait before sending it initialize (env: MCP_STARTUP_DELAY)
+	StartupDelay time.Duration
+
+	// ReadyRetries is how many more times a readiness check sends initialize
+	// when the MCP server fails it, for servers that take a while to answer
+	// after starting; a negative value never retries (default: 3,
+	// env: MCP_READY_RETRIES)
+	ReadyRetries int
+
+	// ReadyRetryBackoff is the wait before the first retry, doubled for each
+	// one after (default: 500ms, env: MCP_READY_RETRY_BACKOFF)
+	ReadyRetryBackoff time.Duration
+
+	// Instances is the number of copies of the MCP server to run, with each
+	// request sent to the one with the fewest requests in flight. Only
+	// suitable for stateless servers: consecutive requests from a client may
+	// reach different processes. Ignored when SessionFunc is set
+	// (default: 1, env: MCP_INSTANCES)
+	Instances int
+
+	// SessionIdleTimeout stops session processes that have been idle this long
+	// (default: 10m, env: MCP_SESSION_IDLE_TIMEOUT)
+	SessionIdleTimeout time.Duration
+
+	// RequestMiddlewares are applied in order to each message before it is
+	// sent to the MCP server, and may reject it; see RequestMiddleware (optional)
+	RequestMiddlewares []RequestMiddleware
+
+	// RedactContentBytes and RedactContentTypes remove the embedded contents
+	// of results, such as images and resource blobs, over this size or with
+	// one of these mimeTypes, e.g. "application/pdf" or "image/*", before
+	// the ResponseMiddlewares run; see ContentRedactionMiddleware
+	// (env: MCP_REDACT_CONTENT_BYTES, MCP_REDACT_CONTENT_TYPES, comma-separated)
+	RedactContentBytes int
+	RedactContentTypes []string
+
+	// DropRedactedContent leaves removed contents out instead of replacing
+	// them with a text saying what was removed (default: false,
+	// env: MCP_REDACT_CONTENT_DROP)
+	DropRedactedContent bool
+
+	// ResponseMiddlewares are applied in order to each response before it is
+	// sent to the client, e.g. for server-specific error detection (optional)
+	ResponseMiddlewares []ResponseMiddleware
+
+	// ResponseRetry, if set, is asked about each response to a client request
+	// after ResponseMiddlewares. If it returns true the request is sent again
+	// after delay, for transient failures such as rate limits, unless the
+	// client's deadline would pass first. Once MaxResponseRetries retries have
+	// been made the last response is returned (optional)
+	ResponseRetry func(response []byte) (delay time.Duration, retry bool)
+
+	// MaxResponseRetries bounds the retries ResponseRetry can ask for per
+	// request (default: 3)
+	MaxResponseRetries int
+
+	// Deprecated: ResponseMiddleware is applied after ResponseMiddlewares; add
+	// it to ResponseMiddlewares instead.
 	ResponseMiddleware func([]byte) []byte
 
-	// RequestMiddleware is called on each request before sending to MCP server (optional)
+	// Deprecated: RequestMiddleware is applied after RequestMiddlewares; add
+	// it to RequestMiddlewares instead.
 	RequestMiddleware func([]byte) []byte
 
 	// ExtraRoutes are additional HTTP routes to register (optional)
 	// Use this for things like deprecation notices on old endpoints
 	ExtraRoutes map[string]http.HandlerFunc
+
+	// Problems are configuration problems an adapter found in its own
+	// settings. NewMCPProxy reports them together with its own (optional)
+	Problems []Problem
+
+	// envErrs are the invalid environment values applyDefaults ignored
+	envErrs configErrors
 }
 
 // MCPProxy handles the communication between HTTP clients and stdio-based MCP servers.
 type MCPProxy struct {
-	config   Config
-	cmd      *exec.Cmd
-	stdin    io.WriteCloser
-	stdout   *bufio.Reader
-	requests chan *request
+	config     Config
+	logger     *logger
+	cmd        *exec.Cmd
+	reaped     chan struct{}   // closed once cmd has been waited for
+	stderrDone <-chan struct{} // closed once cmd's stderr has been read
+	procPID    atomic.Int32    // cmd's PID, readable without p.io
+	stdin      io.WriteCloser
+	writer     *bufio.Writer // buffers writes to stdin
+	stdout     *bufio.Reader
+	unread     []json.RawMessage // messages read from stdout but not handled yet
+	delimiter  []byte            // separates messages on stdio
+	initialize json.RawMessage   // last initialize the server answered, replayed after a restart; guarded by io
+	requests   chan *request
+	stderr     *lineBuffer
+	sessions   *sessionPool
+	instances  *instancePool
+	upstream   *upstream // set for Config.BackendType "http" instead of a process
+	breaker    *breaker
+	limiter    *rateLimiter
+	toolLimits *toolRateLimiter
+	audit      *auditLog        // nil unless Config.AuditLogFile is set
+	recent     *recentRequests  // nil unless Config.RecentRequests is set
+	resources  *resourceSampler // nil unless Config.EnableMetrics is set on Linux
+	pinger     *healthPinger    // nil unless Config.HealthPingInterval is set
+	trusted    trustedProxies
+
+	toolsCache    *toolsCache
+	notifications *notificationHub
+	progress      *progressStreams
+	flights       *flightGroup // nil unless Config.CoalesceMethods is set
+
+	// runtime holds the settings that can be reloaded; see current.
+	runtime atomic.Pointer[runtimeConfig]
+
+	// base is the Config NewMCPProxy was given, before the environment was
+	// applied, which a reload resolves again. exported holds the variables
+	// MCP_ENV_FILE set in the environment.
+	base     Config
+	exported envExports
+
+	// done is closed once stop begins, after which requests is closed too;
+	// queueMu keeps enqueue from sending in between. See enqueue.
+	done    chan struct{}
+	queueMu sync.RWMutex
+
+	// io serializes use of the process's pipes between processRequests and
+	// restart.
+	io sync.Mutex
+
+	// writeMu serializes writes to the process's stdin, which cancelRequest
+	// makes while process waits for a response.
+	writeMu sync.Mutex
+
+	// exited is set once reading from or writing to the MCP server fails,
+	// after which requests are failed immediately instead of queuing.
+	exited atomic.Bool
+
+	// restarting is set while the process is being replaced, by restart or
+	// after it exited; see superviseExit.
+	restarting atomic.Bool
+	restarts   atomic.Int32 // consecutive restarts after an exit
+	restartDue atomic.Int64 // UnixNano when the pending respawn begins
+	startups   startupTimes // of respawns, for restartETA
+	supervise  bool         // restart the process when it exits
+	stopped    atomic.Bool
+	health     health        // reported by /healthz
+	generation atomic.Uint64 // incremented for each new process
+	readiness  readinessGate
+	warm       atomic.Pointer[warmup]
+
+	// serverVersion is taken from the last initialize result, for /version.
+	serverVersion atomic.Pointer[serverVersion]
+
+	// pending holds the requests clients may cancel; see cancelPending.
+	pending pendingRequests
+
+	// draining rejects new requests while active ones finish; see handleDrain.
+	draining atomic.Bool
+	active   atomic.Int64
 }
 
 type request struct {
 	msg       json.RawMessage
 	isRequest bool
 	response  chan json.RawMessage
+
+	// correlationID identifies the HTTP request msg came from in the log.
+	correlationID string
+
+	// ctx is done once the client stops waiting for the response, because
+	// its deadline passed or it went away. nil means it waits indefinitely.
+	ctx context.Context
+
+	// unsent is set when msg could not be written to the MCP server, so
+	// sending it to a replacement process can't repeat its effects.
+	unsent bool
+
+	// err is set when an upstream HTTP server couldn't be reached.
+	err error
 }
 
-// MCPMessage is used to extract the ID from MCP messages.
+// MCPMessage is used to extract the ID and method from MCP messages.
 type MCPMessage struct {
-	ID interface{} `json:"id,omitempty"`
+	JSONRPC string      `json:"jsonrpc,omitempty"`
+	ID      interface{} `json:"id,omitempty"`
+	Method  string      `json:"method,omitempty"`
 }
 
 // NewMCPProxy creates a new MCP proxy with the given configuration.
 func NewMCPProxy(cfg Config) (*MCPProxy, error) {
-	// Apply defaults
-	if cfg.Port == "" {
-		cfg.Port = "8080"
+	// Every configuration problem is reported at once
+	var errs configErrors
+	base := cfg
+
+	// Load variables from a mounted secrets file before reading the rest of the
+	// configuration, so that when exported they can configure the proxy too
+	envFile := os.Getenv("MCP_ENV_FILE")
+	exported := make(envExports)
+	fileEnv, err := loadExportedEnvFile(envFile, exported)
+	errs.add(err, "mount the file or fix MCP_ENV_FILE, and use KEY=VALUE lines")
+
+	cfg.applyDefaults()
+	lg := newLogger(cfg.ServerName, cfg.LogLevel)
+	adapterLogger.Store(lg)
+
+	if envFile != "" && err == nil {
+		lg.infof("Loaded %d variables from %s: %s", len(fileEnv), envFile, strings.Join(envKeys(fileEnv), ", "))
+		cfg.ExtraEnv = append(fileEnv, cfg.ExtraEnv...)
 	}
 
-	// Check for path override from environment
-	cmdPath := cfg.CommandPath
-	if cfg.PathEnvVar != "" {
-		if envPath := os.Getenv(cfg.PathEnvVar); envPath != "" {
-			cmdPath = envPath
+	trusted, more := cfg.validate()
+	if errs = append(errs, more...); len(errs) > 0 {
+		return nil, errs.err()
+	}
+	if cfg.BackendType == backendHTTP && (cfg.SessionFunc != nil || cfg.Instances > 1) {
+		lg.warnf("Ignoring sessions and MCP_INSTANCES: the upstream HTTP server handles its own concurrency")
+		cfg.SessionFunc = nil
+		cfg.Instances = 1
+	}
+
+	if cfg.RedactContentBytes > 0 || len(cfg.RedactContentTypes) > 0 {
+		redact := ContentRedactionMiddleware(cfg.RedactContentBytes, cfg.RedactContentTypes, cfg.DropRedactedContent)
+		cfg.ResponseMiddlewares = append([]ResponseMiddleware{redact}, cfg.ResponseMiddlewares...)
+	}
+
+	cfg.logSummary(lg)
+
+	proxy, err := startProxy(cfg, lg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Instances > 1 {
+		if cfg.SessionFunc != nil {
+			lg.warnf("Ignoring MCP_INSTANCES=%d: this server runs a process per session", cfg.Instances)
+		} else if proxy.instances, err = newInstancePool(proxy, cfg.Instances); err != nil {
+			proxy.stop()
+			return nil, err
+		}
+	}
+	if len(cfg.CoalesceMethods) > 0 {
+		proxy.flights = newFlightGroup(cfg.CoalesceMethods)
+	}
+	proxy.limiter = newRateLimiter(cfg.RateLimitRPS, cfg.RateLimitBurst)
+	proxy.toolLimits = newToolRateLimiter(cfg.ToolRateLimits)
+	proxy.trusted = trusted
+	if cfg.AuditLogFile != "" {
+		if proxy.audit, err = newAuditLog(cfg.AuditLogFile, int64(cfg.AuditLogMaxBytes), cfg.AuditLogBackups, lg); err != nil {
+			proxy.stop()
+			return nil, err
 		}
 	}
+	if cfg.RecentRequests > 0 {
+		proxy.recent = newRecentRequests(cfg.RecentRequests)
+	}
+	proxy.breaker = newBreaker(cfg.BreakerThreshold, cfg.BreakerWindow, cfg.BreakerCooldown, lg)
+	if (cfg.EnableMetrics || cfg.MaxRSSBytes > 0) && proxy.upstream == nil {
+		proxy.resources = proxy.startResourceSampler(cfg.ResourceSampleInterval)
+	}
+	if cfg.HealthPingInterval > 0 && proxy.upstream == nil {
+		proxy.pinger = proxy.startHealthPinger(cfg.HealthPingInterval)
+	}
+	proxy.registerMetrics()
+	proxy.supervise = cfg.MaxRestarts > 0
+	proxy.base = base
+	proxy.exported = exported
+
+	if cfg.SessionFunc != nil {
+		proxy.sessions = newSessionPool(proxy)
+	}
+	return proxy, nil
+}
+
+// startProxy starts the MCP server process described by cfg, or connects to
+// its upstream HTTP server, and the goroutine that feeds it requests. cfg must
+// already be resolved by NewMCPProxy.
+func startProxy(cfg Config, lg *logger) (*MCPProxy, error) {
+	// Keep the most recent stderr lines for /logs, across restarts
+	stderrLines := newLineBuffer(cfg.StderrBufferLines)
 
-	log.Printf("[%s] Starting MCP server at: %s", cfg.ServerName, cmdPath)
+	// Validated by NewMCPProxy
+	delimiter, _ := parseDelimiter(cfg.Delimiter)
 
-	cmd := exec.Command(cmdPath, cfg.CommandArgs...)
-	cmd.Env = append(os.Environ())
+	proxy := &MCPProxy{
+		config:    cfg,
+		logger:    lg,
+		requests:  make(chan *request, cfg.QueueSize),
+		done:      make(chan struct{}),
+		stderr:    stderrLines,
+		delimiter: delimiter,
+
+		toolsCache:    newToolsCache(cfg.ToolsCacheTTL),
+		notifications: newNotificationHub(),
+		progress:      newProgressStreams(),
+	}
+	proxy.runtime.Store(newRuntimeConfig(cfg))
 
-	stdin, err := cmd.StdinPipe()
+	if cfg.BackendType == backendHTTP {
+		lg.infof("Forwarding to upstream MCP server at %s", redactURL(cfg.UpstreamURL))
+		proxy.upstream = newUpstream(cfg.UpstreamURL)
+		go proxy.forwardRequests()
+		return proxy, nil
+	}
+
+	warm := newWarmup(cfg, lg)
+	cmd, stdin, stdout, stderrDone, err := spawn(cfg, lg, stderrLines, warm)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get stdin pipe: %w", err)
+		return nil, err
 	}
+	proxy.warm.Store(warm)
+	proxy.setProcess(cmd, stdin, stdout, stderrDone)
 
-	stdout, err := cmd.StdoutPipe()
+	go proxy.processRequests()
+	return proxy, nil
+}
+
+// spawn starts an MCP server process, logging its stderr and adding it to
+// stderrLines, and watching it for the ready pattern of warm. stderrDone is
+// closed once all of stderr has been read.
+func spawn(cfg Config, lg *logger, stderrLines *lineBuffer, warm *warmup) (cmd *exec.Cmd, stdin io.WriteCloser, stdout io.Reader, stderrDone <-chan struct{}, err error) {
+	lg.infof("Starting MCP server at: %s", cfg.CommandPath)
+
+	cmd = exec.Command(cfg.CommandPath, cfg.CommandArgs...)
+	cmd.Dir = cfg.WorkDir
+	// Validated by NewMCPProxy
+	if uid, gid, ok, _ := runAsIDs(cfg.RunAsUID, cfg.RunAsGID); ok {
+		setRunAs(cmd, uid, gid)
+	}
+	cmd.Env = subprocessEnv(cfg)
+
+	stdin, err = cmd.StdinPipe()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get stdout pipe: %w", err)
+		return nil, nil, nil, nil, fmt.Errorf("failed to get stdin pipe: %w", err)
 	}
 
-	stderr, err := cmd.StderrPipe()
+	stdout, err = cmd.StdoutPipe()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get stderr pipe: %w", err)
+		return nil, nil, nil, nil, fmt.Errorf("failed to get stdout pipe: %w", err)
 	}
 
-	// Log stderr from the MCP server
+	// Unlike with StderrPipe, cmd.Wait doesn't close this pipe, so the last
+	// lines of a crashing server are read before it is
+	stderr, stderrWriter, err := os.Pipe()
+	if err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("failed to get stderr pipe: %w", err)
+	}
+	cmd.Stderr = stderrWriter
+
+	if err := startScheduled(cmd, cfg, lg); err != nil {
+		stderr.Close()
+		stderrWriter.Close()
+		return nil, nil, nil, nil, fmt.Errorf("failed to start MCP server: %w", err)
+	}
+	stderrWriter.Close()
+
+	// Log stderr from the MCP server and keep the most recent lines for /logs
+	done := make(chan struct{})
 	go func() {
+		defer close(done)
+		defer warm.stderrClosed()
+		defer stderr.Close()
 		scanner := bufio.NewScanner(stderr)
 		for scanner.Scan() {
-			log.Printf("[%s stderr] %s", cfg.ServerName, scanner.Text())
+			warm.observe(scanner.Text())
+			line := cfg.redactText(scanner.Text())
+			stderrLines.add(line)
+			if lg.enabled(levelInfo) {
+				log.Printf("[%s stderr] %s", cfg.ServerName, line)
+			}
 		}
 	}()
 
-	if err := cmd.Start(); err != nil {
-		return nil, fmt.Errorf("failed to start MCP server: %w", err)
+	lg.infof("Started MCP server (PID: %d)", cmd.Process.Pid)
+	return cmd, stdin, stdout, done, nil
+}
+
+// setProcess points the proxy at a started MCP server process. p.io must be
+// held unless the proxy isn't processing requests yet.
+func (p *MCPProxy) setProcess(cmd *exec.Cmd, stdin io.WriteCloser, stdout io.Reader, stderrDone <-chan struct{}) {
+	p.cmd = cmd
+	p.reaped = make(chan struct{})
+	p.stderrDone = stderrDone
+	p.procPID.Store(int32(cmd.Process.Pid))
+	p.stdin = stdin
+	p.writer = bufio.NewWriter(stdin)
+	p.stdout = bufio.NewReader(stdout)
+	p.unread = nil
+}
+
+// stop shuts down the MCP server process. Queued requests are drained first;
+// the process is killed if it doesn't exit shortly after its stdin is closed.
+func (p *MCPProxy) stop() {
+	p.stopped.Store(true)
+	p.health.fail(stateDead, errStopped)
+	p.instances.stopAll()
+	p.sessions.stopAll()
+	p.pinger.stop()
+	p.queueMu.Lock()
+	close(p.done)
+	close(p.requests)
+	p.queueMu.Unlock()
+	p.audit.close()
+
+	if p.upstream != nil {
+		return
 	}
+	p.io.Lock()
+	defer p.io.Unlock()
+	p.terminate()
+}
 
-	log.Printf("[%s] Started MCP server (PID: %d)", cfg.ServerName, cmd.Process.Pid)
+// restart replaces the MCP server process with a new one. The request in
+// progress completes first and queued requests wait for the new process.
+func (p *MCPProxy) restart() error {
+	p.restartDue.Store(time.Now().UnixNano())
+	p.restarting.Store(true)
+	p.health.set(stateDegraded)
+	defer p.restarting.Store(false)
+	return p.respawn()
+}
 
-	proxy := &MCPProxy{
-		config:   cfg,
-		cmd:      cmd,
-		stdin:    stdin,
-		stdout:   bufio.NewReader(stdout),
-		requests: make(chan *request, 100),
+// respawn terminates the MCP server process and starts a new one, unless the
+// proxy has been stopped.
+func (p *MCPProxy) respawn() error {
+	p.io.Lock()
+	defer p.io.Unlock()
+
+	if p.stopped.Load() {
+		return errStopped
+	}
+	start := time.Now()
+	if p.upstream != nil {
+		// There is no process; start a new upstream session instead
+		p.upstream.reset()
+		p.generation.Add(1)
+		p.health.set(stateStarting)
+		p.toolsCache.invalidate()
+		return nil
+	}
+	p.terminate()
+	warm := newWarmup(p.config, p.logger)
+	cmd, stdin, stdout, stderrDone, err := spawn(p.config, p.logger, p.stderr, warm)
+	if err != nil {
+		p.markExited(err)
+		return err
 	}
+	p.warm.Store(warm)
+	p.setProcess(cmd, stdin, stdout, stderrDone)
+	p.exited.Store(false)
+	p.generation.Add(1)
+	p.health.set(stateStarting)
+	p.toolsCache.invalidate()
 
-	go proxy.processRequests()
-	return proxy, nil
+	// Queued requests wait for p.io, so the new process is initialized first
+	p.replayInitialize()
+	p.startups.add(time.Since(start))
+	return nil
+}
+
+// validateCommand checks that the MCP server binary exists and is executable.
+// Bare command names are looked up in PATH.
+func validateCommand(path string) error {
+	if path == "" {
+		return fmt.Errorf("MCP server command is not configured")
+	}
+	if _, err := exec.LookPath(path); err != nil {
+		return fmt.Errorf("MCP server command %q not found or not executable: %w", path, err)
+	}
+	return nil
+}
+
+var (
+	errQueueFull   = errors.New("request queue is full")
+	errQueueClosed = errors.New(errShuttingDown)
+)
+
+// enqueue hands req to the goroutine that sends requests to the MCP server,
+// without blocking: it fails with errQueueFull if the queue is full and with
+// errQueueClosed once the proxy is stopping, instead of sending on the closed
+// channel.
+func (p *MCPProxy) enqueue(req *request) error {
+	p.queueMu.RLock()
+	defer p.queueMu.RUnlock()
+	select {
+	case <-p.done:
+		return errQueueClosed
+	default:
+	}
+	select {
+	case p.requests <- req:
+		return nil
+	default:
+		return errQueueFull
+	}
 }
 
 func (p *MCPProxy) processRequests() {
 	for req := range p.requests {
-		msg := req.msg
+		p.process(req)
+	}
+}
+
+// process sends one request to the MCP server and delivers its response.
+func (p *MCPProxy) process(req *request) {
+	defer close(req.response)
 
-		// Apply request middleware if configured
-		if p.config.RequestMiddleware != nil {
-			msg = p.config.RequestMiddleware(msg)
+	p.io.Lock()
+	defer p.io.Unlock()
+
+	// Apply request middlewares, which may answer the request themselves
+	msg, rejection, rejected := p.config.applyRequestMiddlewares(req.msg)
+	if rejected {
+		p.logger.debugf("[%s] Request middleware rejected message %s", req.correlationID, formatID(messageID(req.msg)))
+		if rejection != nil {
+			req.response <- rejection
 		}
+		return
+	}
 
-		log.Printf("[%s] Sending: %s", p.config.ServerName, string(msg))
+	if p.logger.enabled(levelDebug) {
+		p.logger.debugf("[%s] Sending: %s", req.correlationID, p.payloadForLog(msg))
+	}
 
-		// Write to stdio (newline-delimited JSON)
-		if _, err := p.stdin.Write(append(msg, '\n')); err != nil {
-			log.Printf("[%s] Error writing to stdin: %v", p.config.ServerName, err)
-			close(req.response)
-			continue
+	// Fail fast once the MCP server is gone rather than writing to a dead pipe
+	if p.exited.Load() {
+		req.unsent = true
+		return
+	}
+
+	// Don't start work nobody is waiting for
+	if req.ctx != nil && req.ctx.Err() != nil {
+		p.logger.debugf("[%s] Dropping request %s: the client stopped waiting", req.correlationID, formatID(messageID(msg)))
+		return
+	}
+
+	// Write to stdio (newline-delimited JSON)
+	if err := p.writeMessage(msg); err != nil {
+		p.markExited(fmt.Errorf("error writing to stdin: %w", err))
+		req.unsent = true
+		return
+	}
+
+	// Only read response if this is a request (has ID), not a notification
+	if !req.isRequest {
+		return
+	}
+
+	// Cancel the request on the server if the client stops waiting
+	stop := func() bool { return false }
+	answered := make(chan struct{})
+	if req.ctx != nil {
+		cmd, w := p.cmd, p.writer
+		stop = context.AfterFunc(req.ctx, func() { p.cancelRequest(cmd, w, msg, context.Cause(req.ctx), answered) })
+	}
+
+	// Use the potentially middleware-modified msg for ID matching
+	response, err := p.readResponse(msg, req.correlationID)
+	stop()
+	close(answered)
+	if err != nil {
+		p.markExited(err)
+		return
+	}
+
+	p.restarts.Store(0)
+	p.rememberInitialize(msg, response)
+	p.observeInitialize(msg, response)
+
+	req.response <- p.config.applyResponseMiddlewares(response)
+}
+
+// observeNotification handles a server notification read while waiting for a
+// response: it is forwarded to clients on the notification stream, and a
+// changed tool list invalidates the tools/list cache. Either way it is logged
+// at Config.LogSkippedNotifications, since a response the server sent
+// without its id would end up here.
+func (p *MCPProxy) observeNotification(msg json.RawMessage) {
+	var n MCPMessage
+	json.Unmarshal(msg, &n)
+	if n.Method == "notifications/tools/list_changed" {
+		p.logger.infof("MCP server reported a changed tool list")
+		p.toolsCache.invalidate()
+	}
+
+	level := p.config.skippedNotificationLevel()
+	if n.Method == "notifications/progress" && p.progress.deliver(msg) {
+		p.logger.logf(level, "Forwarded notification %s to the event stream of its request", n.Method)
+		return
+	}
+	if n.Method == "" && p.logger.enabled(level) {
+		p.logger.logf(level, "MCP server sent a message with neither id nor method, possibly a response without its id: %s", p.payloadForLog(msg))
+	}
+	if delivered := p.notifications.publish(msg); delivered > 0 {
+		p.logger.logf(level, "Forwarded notification %s to %d client(s)", n.Method, delivered)
+	} else {
+		p.logger.logf(level, "Dropped notification %s: no clients listening", n.Method)
+	}
+}
+
+// markExited records that the MCP server can no longer be reached. The stdio
+// stream can't be resynchronized after a failed read or write, so this is
+// permanent for the process; session processes are replaced on next use.
+// p.io must be held.
+func (p *MCPProxy) markExited(err error) {
+	if p.exited.CompareAndSwap(false, true) {
+		p.logger.errorf("MCP server is no longer reachable, failing requests: %v", err)
+		p.superviseExit(err)
+		// A process that failed to start or was already stopped didn't crash
+		if p.cmd != nil && p.cmd.ProcessState == nil {
+			p.alertCrash(p.cmd, p.reaped, p.stderrDone, err)
 		}
+	}
+}
 
-		// Only read response if this is a request (has ID), not a notification
-		if req.isRequest {
-			// Use the potentially middleware-modified msg for ID matching
-			response, err := p.readResponse(msg)
-			if err != nil {
-				log.Printf("[%s] Error reading response: %v", p.config.ServerName, err)
-				close(req.response)
-				continue
-			}
+// writeMessage writes msg to the MCP server, giving up after
+// Config.WriteTimeout. Pipe writes can't be interrupted, so the write runs in
+// its own goroutine and the process is killed on timeout to release it.
+func (p *MCPProxy) writeMessage(msg json.RawMessage) error {
+	timeout := p.current().writeTimeout
+	if timeout <= 0 {
+		p.writeMu.Lock()
+		defer p.writeMu.Unlock()
+		return writeLine(p.writer, msg, p.delimiter)
+	}
 
-			// Apply response middleware if configured
-			if p.config.ResponseMiddleware != nil {
-				response = p.config.ResponseMiddleware(response)
-			}
+	// The goroutine may outlive a timeout, so it must not see a restart's writer
+	w := p.writer
+	done := make(chan error, 1)
+	go func() {
+		p.writeMu.Lock()
+		defer p.writeMu.Unlock()
+		done <- writeLine(w, msg, p.delimiter)
+	}()
 
-			req.response <- response
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		if p.cmd != nil && p.cmd.Process != nil {
+			p.cmd.Process.Kill()
 		}
-		close(req.response)
+		return fmt.Errorf("write did not complete within %s", timeout)
+	}
+}
+
+// writeLine writes msg and its delimiter, a newline if empty, in a single
+// flush, without copying msg.
+func writeLine(w *bufio.Writer, msg json.RawMessage, delimiter []byte) error {
+	w.Write(msg)
+	if len(delimiter) == 0 {
+		w.WriteByte('\n')
 	}
+	w.Write(delimiter)
+	return w.Flush()
 }
 
-func (p *MCPProxy) readResponse(originalRequest json.RawMessage) (json.RawMessage, error) {
+func (p *MCPProxy) readResponse(originalRequest json.RawMessage, cid string) (json.RawMessage, error) {
 	// Parse the request to get its ID for matching
-	var reqMsg MCPMessage
-	json.Unmarshal(originalRequest, &reqMsg)
-	requestID := reqMsg.ID
+	requestID := messageID(originalRequest)
 
 	for {
-		line, err := p.stdout.ReadBytes('\n')
+		responseData, err := p.readMessage()
 		if err != nil {
-			return nil, fmt.Errorf("error reading from MCP server: %w", err)
+			return nil, err
 		}
 
-		responseData := line[:len(line)-1]
-		log.Printf("[%s] Received: %s", p.config.ServerName, string(responseData))
-
-		// Parse the response to check if it has an ID
-		var respMsg MCPMessage
-		json.Unmarshal(responseData, &respMsg)
+		if p.logger.enabled(levelDebug) {
+			p.logger.debugf("[%s] Received: %s", cid, p.payloadForLog(responseData))
+		}
 
-		// Always skip notifications (messages without ID)
+		// Always skip notifications (messages without an id member)
 		// Notifications are server-initiated messages that don't correspond to any request
-		if respMsg.ID == nil {
-			log.Printf("[%s] Skipping notification while waiting for response", p.config.ServerName)
+		if !hasID(responseData) {
+			p.observeNotification(responseData)
 			continue
 		}
 
@@ -205,17 +1087,89 @@ func (p *MCPProxy) readResponse(originalRequest json.RawMessage) (json.RawMessag
 
 		// When SkipNotifications is enabled, also verify the response ID matches the request ID
 		// This handles servers that may send multiple responses or out-of-order responses
-		if respMsg.ID == requestID || formatID(respMsg.ID) == formatID(requestID) {
+		responseID := messageID(responseData)
+		if sameID(responseID, requestID) {
 			return responseData, nil
 		}
 
 		// Mismatched ID - log warning and return anyway to prevent hanging
-		log.Printf("[%s] Warning: received response with unexpected ID %v (expected %v)",
-			p.config.ServerName, respMsg.ID, requestID)
+		p.logger.warnf("[%s] Received response with unexpected ID %s (expected %s)", cid, formatID(responseID), formatID(requestID))
 		return responseData, nil
 	}
 }
 
+// idMismatch describes why a message for method should or should not have an
+// id: MCP notifications are the notifications/* methods and every other
+// method is a request. It returns "" if the message is consistent, or has no
+// method, like a client's response to a server request.
+func idMismatch(method string, isRequest bool) string {
+	switch {
+	case method == "":
+		return ""
+	case strings.HasPrefix(method, "notifications/") && isRequest:
+		return fmt.Sprintf("notification %q must not have an id", method)
+	case !strings.HasPrefix(method, "notifications/") && !isRequest:
+		return fmt.Sprintf("request %q must have an id", method)
+	}
+	return ""
+}
+
+// calledTool returns the name of the tool msg calls, and false if msg isn't a
+// tools/call request.
+func calledTool(msg json.RawMessage) (string, bool) {
+	var call struct {
+		Method string `json:"method"`
+		Params struct {
+			Name string `json:"name"`
+		} `json:"params"`
+	}
+	if err := json.Unmarshal(msg, &call); err != nil || call.Method != "tools/call" {
+		return "", false
+	}
+	return call.Params.Name, true
+}
+
+// hasID reports whether msg has an "id" member. Unlike checking MCPMessage.ID,
+// this distinguishes an explicit "id": null, which per JSON-RPC still expects a
+// response, from a notification that has no id at all.
+func hasID(msg json.RawMessage) bool {
+	return rawID(msg) != nil
+}
+
+// rawID returns the raw "id" member of msg, "null" for an explicit null, or nil
+// if there is none. Only the id is copied, so large results aren't duplicated
+// just to look at their id.
+func rawID(msg json.RawMessage) json.RawMessage {
+	var m struct {
+		ID json.RawMessage `json:"id"`
+	}
+	if err := json.Unmarshal(msg, &m); err != nil {
+		return nil
+	}
+	return m.ID
+}
+
+// messageID returns the id of a JSON-RPC message, or nil if it has none.
+// Numeric IDs are decoded as json.Number so large integers keep their exact value.
+func messageID(msg json.RawMessage) interface{} {
+	raw := rawID(msg)
+	if raw == nil {
+		return nil
+	}
+	var id interface{}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	dec.Decode(&id)
+	return id
+}
+
+// sameID reports whether two IDs are equal. Both sides are normalized through
+// formatID, so a float64 and a json.Number with the same value match, while a
+// string and a number never do: per JSON-RPC, 1 and "1" are different IDs.
+func sameID(a, b interface{}) bool {
+	return formatID(a) == formatID(b)
+}
+
 // formatID converts an interface{} ID to a comparable string.
 func formatID(id interface{}) string {
 	if id == nil {
@@ -227,89 +1181,608 @@ func formatID(id interface{}) string {
 
 // Handle is the HTTP handler for MCP requests.
 func (p *MCPProxy) Handle(w http.ResponseWriter, r *http.Request) {
+	r = p.withClientIP(r)
+	if p.config.AccessLog {
+		aw := &accessWriter{ResponseWriter: w}
+		defer p.logAccess(r, aw, time.Now())
+		w = aw
+	}
+
 	// Handle CORS if enabled
-	if p.config.EnableCORS {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	if p.applyCORS(w, r) {
+		return
+	}
+	rc := p.current()
 
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
+	// A GET opens the stream of server notifications; anything but a POST
+	// past this point would only fail to decode
+	switch r.Method {
+	case http.MethodGet:
+		p.HandleNotifications(w, r)
+		return
+	case http.MethodPost:
+	case http.MethodOptions:
+		w.Header().Set("Allow", rpcMethods)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	default:
+		w.Header().Set("Allow", rpcMethods)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Count the request before checking draining, so handleDrain can't miss it
+	p.active.Add(1)
+	defer p.active.Add(-1)
+	if p.draining.Load() {
+		writeJSONRPCError(w, http.StatusServiceUnavailable, nil, codeServerUnavailable, errDraining)
+		return
 	}
 
-	log.Printf("[%s] HTTP request from %s %s", p.config.ServerName, r.RemoteAddr, r.URL.Path)
+	start := time.Now()
+	cid := newCorrelationID()
+	w.Header().Set(correlationIDHeader, cid)
+	p.logger.debugf("[%s] HTTP request from %s %s", cid, r.RemoteAddr, r.URL.Path)
 
-	// Read HTTP JSON body
+	// Read HTTP JSON body, refusing oversized ones before they are buffered
 	var msg json.RawMessage
+	if p.config.MaxRequestBytes > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, int64(p.config.MaxRequestBytes))
+	}
 	if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
-		log.Printf("[%s] Failed to decode HTTP body: %v", p.config.ServerName, err)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			p.logger.warnf("[%s] Rejecting HTTP body over %d bytes", cid, tooLarge.Limit)
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		p.logger.warnf("[%s] Failed to decode HTTP body: %v", cid, err)
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	log.Printf("[%s] Received HTTP request: %s", p.config.ServerName, string(msg))
+	if p.logger.enabled(levelDebug) {
+		p.logger.debugf("[%s] Received HTTP request: %s", cid, p.payloadForLog(msg))
+	}
 
-	// Check if this is a request (has ID) or notification (no ID)
+	// Check if this is a request (has an id member, even null) or notification (no id)
 	var mcpMsg MCPMessage
 	json.Unmarshal(msg, &mcpMsg)
-	isRequest := mcpMsg.ID != nil
+	isRequest := hasID(msg)
+	noteMethod(w, mcpMsg.Method)
+
+	// A client that prefers Server-Sent Events gets the response on an event
+	// stream, after the progress notifications for its request
+	var stream *eventStreamWriter
+	if isRequest && prefersEventStream(r) {
+		stream = &eventStreamWriter{ResponseWriter: w}
+		w = stream
+	}
+
+	if p.audit != nil && mcpMsg.Method == "tools/call" {
+		aw := keepBody(w)
+		w = aw
+		defer p.auditToolCall(r, msg, aw, start)
+	}
+	if p.recent != nil {
+		aw := keepBody(w)
+		w = aw
+		defer p.recordRecent(cid, mcpMsg.Method, msg, aw, start)
+	}
+	defer p.logSpan(r, mcpMsg.Method, messageID(msg), start)
+
+	if ok, wait := p.limiter.allow(clientKey(r)); !ok {
+		p.logger.debugf("[%s] Rate limit exceeded for %s", cid, clientKey(r))
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		writeJSONRPCError(w, http.StatusTooManyRequests, mcpMsg.ID, p.config.errorCode(CodeRateLimited), "rate limit exceeded")
+		return
+	}
+	if tool, ok := calledTool(msg); ok {
+		if ok, wait := p.toolLimits.allow(tool); !ok {
+			p.logger.debugf("[%s] Rate limit exceeded for tool %q", cid, tool)
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			writeJSONRPCError(w, http.StatusTooManyRequests, mcpMsg.ID, p.config.errorCode(CodeRateLimited), fmt.Sprintf("rate limit exceeded for tool %q", tool))
+			return
+		}
+	}
+
+	if rc.strictJSONRPC && mcpMsg.JSONRPC != "2.0" {
+		p.logger.warnf("[%s] Rejecting message with jsonrpc version %q", cid, mcpMsg.JSONRPC)
+		writeJSONRPCError(w, http.StatusBadRequest, mcpMsg.ID, CodeInvalidRequest, "Invalid Request")
+		return
+	}
+	if reason := idMismatch(mcpMsg.Method, isRequest); rc.strictJSONRPC && reason != "" {
+		p.logger.warnf("[%s] Rejecting message: %s", cid, reason)
+		writeJSONRPCError(w, http.StatusBadRequest, mcpMsg.ID, CodeInvalidRequest, "Invalid Request: "+reason)
+		return
+	}
+
+	// Reject calls to tools that are not exposed by this proxy
+	if name := rc.tools.blockedTool(msg); name != "" {
+		p.logger.warnf("[%s] Blocked call to disallowed tool %q", cid, name)
+		writeJSONRPCError(w, http.StatusOK, mcpMsg.ID, p.config.errorCode(CodeNotAllowed), fmt.Sprintf("tool %q is not allowed", name))
+		return
+	}
+
+	// Follow the client's logging/setLevel, which is also forwarded
+	if isRequest && mcpMsg.Method == "logging/setLevel" && p.config.RespectClientLogLevel {
+		p.mirrorLogLevel(msg)
+	}
+
+	// A client cancelling one of its requests in flight
+	if !isRequest && mcpMsg.Method == "notifications/cancelled" && p.cancelPending(r, msg) {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	// Stop sending requests to an MCP server that keeps failing
+	if !p.breaker.allow() {
+		writeJSONRPCError(w, http.StatusServiceUnavailable, mcpMsg.ID, codeServerUnavailable, errBreakerOpen)
+		return
+	}
+	healthy := true
+	defer func() { p.breaker.record(healthy) }()
+
+	// Pick the MCP server process for this request
+	target, release, err := p.sessionFor(r)
+	if err != nil {
+		p.logger.errorf("[%s] Failed to get session: %v", cid, err)
+		healthy = err == errTooManySessions
+		writeJSONRPCError(w, http.StatusServiceUnavailable, mcpMsg.ID, codeServerBusy, err.Error())
+		return
+	}
+	defer release()
+
+	// Rather than wait for a restart in progress, clients are told when to
+	// come back; requests already sent wait for it, see retry
+	if target.exited.Load() || target.restarting.Load() {
+		healthy = false
+		target.writeUnavailable(w, mcpMsg.ID)
+		return
+	}
+
+	// Answer repeated tools/list requests from the target's cache
+	cacheable := isRequest && target.toolsCache != nil && cacheableToolsList(msg)
+	if cacheable {
+		if cached := target.toolsCache.get(); cached != nil {
+			p.logger.debugf("[%s] Serving tools/list from cache", cid)
+			p.writeResponse(w, r, rc.tools.filterList(withID(cached, rawID(msg))))
+			return
+		}
+	}
+
+	// Requests wait until their deadline, until the client goes away or
+	// until it cancels them
+	ctx := r.Context()
+	if timeout := p.requestTimeout(r, msg); timeout > 0 && isRequest {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	if isRequest {
+		var cancel context.CancelCauseFunc
+		ctx, cancel = context.WithCancelCause(ctx)
+		defer cancel(nil)
+		defer p.pending.add(&pendingEntry{
+			cancel:        cancel,
+			client:        p.pendingOwner(r),
+			id:            messageID(msg),
+			method:        mcpMsg.Method,
+			correlationID: cid,
+			since:         start,
+		})()
+	}
+
+	// Share the response to an identical request in flight; if it fails,
+	// send this one after all
+	var flight *flightCall
+	if isRequest && p.flights.coalesces(mcpMsg.Method) {
+		key := p.flightKey(target, msg)
+		call, leader := p.flights.join(key)
+		if leader {
+			flight = call
+			defer p.flights.finish(key, call)
+		} else if response := call.wait(ctx); response != nil {
+			p.logger.debugf("[%s] Sharing the response to an identical %s request", cid, mcpMsg.Method)
+			response = withID(response, rawID(msg))
+			if mcpMsg.Method == "tools/list" {
+				response = rc.tools.filterList(response)
+			}
+			p.writeResponse(w, r, response)
+			return
+		}
+	}
 
 	// Send request to MCP server
 	req := &request{
-		msg:       msg,
-		isRequest: isRequest,
-		response:  make(chan json.RawMessage, 1),
+		msg:           msg,
+		isRequest:     isRequest,
+		response:      make(chan json.RawMessage, 1),
+		correlationID: cid,
+	}
+	if isRequest {
+		req.ctx = ctx
+	}
+	var progress <-chan json.RawMessage
+	if token := progressToken(msg); stream != nil && token != "" {
+		var done func()
+		progress, done = target.progress.add(token)
+		defer done()
+	}
+	switch err := target.enqueue(req); err {
+	case errQueueFull:
+		// The queue is saturated; reject instead of piling up blocked connections
+		p.logger.warnf("[%s] Request queue full (%d), rejecting request", cid, cap(target.requests))
+		writeJSONRPCError(w, http.StatusTooManyRequests, mcpMsg.ID, codeServerBusy, "server busy")
+		return
+	case errQueueClosed:
+		writeJSONRPCError(w, http.StatusServiceUnavailable, mcpMsg.ID, codeServerUnavailable, errShuttingDown)
+		return
 	}
-	p.requests <- req
 
 	// Wait for response (only if it's a request)
 	if isRequest {
-		response, ok := <-req.response
+		var response json.RawMessage
+		var ok bool
+	wait:
+		for {
+			select {
+			case response, ok = <-req.response:
+				break wait
+			case <-ctx.Done():
+				break wait
+			case n := <-progress:
+				stream.send(n)
+			}
+		}
+		if !ok && req.unsent {
+			response, ok = p.retry(ctx, r, msg, cid)
+		}
+		if !ok && errors.Is(context.Cause(ctx), errClientCancelled) {
+			writeJSONRPCError(w, http.StatusOK, mcpMsg.ID, codeRequestCancelled, errClientCancelled.Error())
+			return
+		}
+		if !ok && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			p.logger.warnf("[%s] Request %s timed out", cid, formatID(mcpMsg.ID))
+			healthy = false
+			writeJSONRPCError(w, http.StatusGatewayTimeout, mcpMsg.ID, codeRequestTimeout, errRequestTimeout)
+			return
+		}
+		if !ok && req.err != nil {
+			healthy = false
+			writeJSONRPCError(w, http.StatusBadGateway, mcpMsg.ID, codeServerUnavailable, req.err.Error())
+			return
+		}
 		if !ok {
-			log.Printf("[%s] Failed to get response from MCP server", p.config.ServerName)
-			http.Error(w, "Failed to get response", http.StatusInternalServerError)
+			p.logger.errorf("[%s] Failed to get response from MCP server", cid)
+			healthy = false
+			target.writeUnavailable(w, mcpMsg.ID)
 			return
 		}
 
-		log.Printf("[%s] Sending HTTP response: %s", p.config.ServerName, string(response))
+		if p.config.ResponseRetry != nil {
+			response = p.retryResponse(ctx, target, msg, response, cid)
+		}
+
+		if cacheable {
+			target.toolsCache.put(response)
+		}
+		if flight != nil {
+			flight.response = response
+		}
+		if mcpMsg.Method == "tools/list" {
+			response = rc.tools.filterList(response)
+		}
+
+		if p.logger.enabled(levelDebug) {
+			p.logger.debugf("[%s] Sending HTTP response: %s", cid, p.payloadForLog(response))
+		}
 
-		w.Header().Set("Content-Type", "application/json")
-		w.Write(response)
+		p.writeResponse(w, r, response)
+	} else if p.config.FastNotifications {
+		// Accept without waiting for the write; the queue keeps the order
+		go func() {
+			<-req.response
+			if req.err != nil || req.unsent {
+				p.logger.warnf("[%s] Notification %s was not delivered to the MCP server", cid, mcpMsg.Method)
+			}
+		}()
+		w.WriteHeader(http.StatusAccepted)
 	} else {
-		// For notifications, wait for processing to complete and return 202 Accepted
-		<-req.response
-		log.Printf("[%s] Notification processed", p.config.ServerName)
+		// For notifications, wait for processing to complete and return 202
+		// Accepted, unless the client goes away first. The notification is
+		// still written, since it may already have been
+		select {
+		case <-req.response:
+		case <-r.Context().Done():
+			p.logger.debugf("[%s] Client disconnected before notification %s was processed", cid, mcpMsg.Method)
+			return
+		}
+		if req.err != nil {
+			healthy = false
+			writeJSONRPCError(w, http.StatusBadGateway, nil, codeServerUnavailable, req.err.Error())
+			return
+		}
+		if req.unsent {
+			healthy = false
+			target.writeUnavailable(w, nil)
+			return
+		}
+		p.logger.debugf("[%s] Notification processed", cid)
 		w.WriteHeader(http.StatusAccepted)
 	}
 }
 
+// retry sends a request that could not be written to the MCP server once
+// more, to its replacement: a new session process, or the main process once a
+// restart in progress completes. It waits up to Config.WriteTimeout for the
+// replacement and gives up when ctx is done. Notifications are never retried,
+// since a partial write may already have had an effect.
+func (p *MCPProxy) retry(ctx context.Context, r *http.Request, msg json.RawMessage, cid string) (json.RawMessage, bool) {
+	wait := ctx
+	if timeout := p.current().writeTimeout; timeout > 0 {
+		var cancel context.CancelFunc
+		wait, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	for {
+		target, release, err := p.sessionFor(r)
+		if err != nil {
+			return nil, false
+		}
+		if !target.exited.Load() {
+			defer release()
+			p.logger.warnf("[%s] Retrying a request that could not be sent to the MCP server", cid)
+			req := &request{msg: msg, isRequest: true, response: make(chan json.RawMessage, 1), ctx: ctx, correlationID: cid}
+			if target.enqueue(req) != nil {
+				return nil, false
+			}
+			select {
+			case response, ok := <-req.response:
+				return response, ok
+			case <-ctx.Done():
+				return nil, false
+			}
+		}
+		release()
+
+		// Nothing will replace the process unless it is being restarted
+		if !target.restarting.Load() {
+			return nil, false
+		}
+		select {
+		case <-wait.Done():
+			return nil, false
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+// retryResponse sends msg to target again for as long as
+// Config.ResponseRetry asks for it, up to Config.MaxResponseRetries times,
+// and returns the last response. It stops early, returning the response it
+// has, if ctx would be done before the next attempt or the request can't be
+// queued.
+func (p *MCPProxy) retryResponse(ctx context.Context, target *MCPProxy, msg, response json.RawMessage, cid string) json.RawMessage {
+	for attempt := 1; attempt <= p.config.MaxResponseRetries; attempt++ {
+		delay, retry := p.config.ResponseRetry(response)
+		if !retry {
+			return response
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			p.logger.debugf("[%s] Not retrying: the request deadline is within %s", cid, delay)
+			return response
+		}
+		p.logger.infof("[%s] Retrying the request in %s (attempt %d of %d)", cid, delay, attempt, p.config.MaxResponseRetries)
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return response
+		}
+
+		req := &request{msg: msg, isRequest: true, response: make(chan json.RawMessage, 1), ctx: ctx, correlationID: cid}
+		if target.enqueue(req) != nil {
+			return response
+		}
+		select {
+		case next, ok := <-req.response:
+			if !ok {
+				return response
+			}
+			response = next
+		case <-ctx.Done():
+			return response
+		}
+	}
+	return response
+}
+
+// writeResponse writes a JSON-RPC response to the client and flushes it. The
+// Content-Length lets large results go out in one piece instead of chunked.
+func (p *MCPProxy) writeResponse(w http.ResponseWriter, r *http.Request, response json.RawMessage) {
+	w.Header().Set("Content-Type", "application/json")
+	if p.config.EnableCompression {
+		w.Header().Add("Vary", "Accept-Encoding")
+	}
+	if p.shouldCompress(r, len(response)) {
+		noteResponse(w, response)
+		p.writeGzip(w, response)
+		return
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// writeJSONRPCError writes a JSON-RPC error response with the given HTTP status.
+func writeJSONRPCError(w http.ResponseWriter, status int, id interface{}, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      id,
+		"error": map[string]interface{}{
+			"code":    code,
+			"message": message,
+		},
+	})
+}
+
 // Run starts the MCP proxy server with the given configuration.
 // This is a convenience function that creates the proxy and starts the HTTP server.
 func Run(cfg Config) error {
-	if cfg.Port == "" {
-		cfg.Port = "8080"
-	}
-
-	log.Printf("[%s] MCP Streamable HTTP Proxy starting...", cfg.ServerName)
+	// NewMCPProxy keeps cfg as given for reloads, so only a copy is defaulted
+	// for the startup message
+	named := cfg
+	named.applyDefaults()
+	newLogger(named.ServerName, named.LogLevel).infof("MCP Streamable HTTP Proxy starting...")
 
 	proxy, err := NewMCPProxy(cfg)
 	if err != nil {
 		return fmt.Errorf("failed to create proxy: %w", err)
 	}
+	// The listeners' settings may come from MCP_ENV_FILE, which NewMCPProxy
+	// loads, so read them from the proxy's configuration rather than cfg
+	cfg = proxy.config
+
+	var management net.Listener
+	if cfg.AdminPort != "" {
+		if management, err = net.Listen("tcp", ":"+cfg.AdminPort); err != nil {
+			proxy.stop()
+			return fmt.Errorf("failed to listen on ADMIN_PORT: %w", err)
+		}
+	}
+
+	if cfg.EnablePprof || cfg.AdminToken != "" {
+		go proxy.serveAdmin()
+	}
+	if management != nil {
+		go proxy.serveManagement(management)
+	}
+	go proxy.reloadOnSignal()
+
+	addr := cfg.listenAddr()
+	ln, err := proxy.config.listen()
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+	if cfg.ListenUnix != "" {
+		proxy.logger.infof("Listening on unix socket %s", cfg.ListenUnix)
+	} else {
+		proxy.logger.infof("Listening on %s", addr)
+		proxy.logger.infof("HTTP endpoint: http://%s/", endpointHost(addr))
+	}
+
+	srv := proxy.newServer()
+	done := make(chan struct{})
+	go proxy.shutdownOnSignal(srv, done)
+
+	if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	<-done
+	return nil
+}
+
+// newServer returns the HTTP server for the main listener.
+func (p *MCPProxy) newServer() *http.Server {
+	srv := &http.Server{Handler: p.newMux()}
+	if p.config.EnableH2C {
+		if enableH2C(srv) {
+			p.logger.infof("Serving HTTP/2 without TLS (h2c) alongside HTTP/1.1")
+		} else {
+			p.logger.warnf("Ignoring ENABLE_H2C: this proxy was built with %s, h2c needs Go 1.24 or later", runtime.Version())
+		}
+	}
+	return srv
+}
+
+// newMux returns the handler for the main listener. It deliberately doesn't
+// use http.DefaultServeMux, where net/http/pprof and expvar register
+// themselves; those are only served by the admin listener.
+func (p *MCPProxy) newMux() *http.ServeMux {
+	mux := http.NewServeMux()
 
 	// Register extra routes first (so they take precedence over the catch-all)
-	for path, handler := range cfg.ExtraRoutes {
-		log.Printf("[%s] Registering extra route: %s", cfg.ServerName, path)
-		http.HandleFunc(path, handler)
+	for path, handler := range p.config.ExtraRoutes {
+		p.logger.infof("Registering extra route: %s", path)
+		mux.HandleFunc(path, p.withCORS(handler))
+	}
+
+	// Register the diagnostic endpoints, unless they have a port of their
+	// own, and the main handler, which applies CORS itself
+	if p.config.AdminPort == "" {
+		p.registerManagement(mux)
+		if p.config.EnableDiagnostics && p.config.AdminToken != "" {
+			p.registerDiagnostics(mux, func(h http.HandlerFunc) http.Handler {
+				return requireToken(p.config.AdminToken, h)
+			})
+		}
 	}
+	mux.HandleFunc("/", p.Handle)
+	return mux
+}
 
-	// Register the main handler
-	http.HandleFunc("/", proxy.Handle)
+// newManagementMux returns the handler for the listener on Config.AdminPort.
+func (p *MCPProxy) newManagementMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	p.registerManagement(mux)
+	p.registerDiagnostics(mux, func(h http.HandlerFunc) http.Handler { return h })
+	return mux
+}
+
+// registerManagement registers the health, metrics and description endpoints.
+func (p *MCPProxy) registerManagement(mux *http.ServeMux) {
+	mux.HandleFunc("/metrics", p.withCORS(readOnly(defaultRegistry.ServeHTTP)))
+	mux.HandleFunc("/healthz", p.withCORS(readOnly(p.HandleHealth)))
+	mux.HandleFunc("/readyz", p.withCORS(readOnly(p.HandleReady)))
+	mux.HandleFunc("/openapi.json", p.withCORS(readOnly(p.HandleOpenAPI)))
+	mux.HandleFunc("/version", p.withCORS(readOnly(p.HandleVersion)))
+}
+
+// registerDiagnostics registers /logs and /config, which show the MCP
+// server's stderr and the effective configuration, each guarded by guard.
+// They get no CORS headers, so web pages can't read them.
+func (p *MCPProxy) registerDiagnostics(mux *http.ServeMux, guard func(http.HandlerFunc) http.Handler) {
+	mux.Handle("/logs", guard(readOnly(p.HandleLogs)))
+	mux.Handle("/config", guard(readOnly(p.HandleConfig)))
+}
+
+// rpcMethods is the Allow header of the MCP JSON-RPC endpoint.
+const rpcMethods = "GET, POST, OPTIONS"
 
-	log.Printf("[%s] Listening on port %s", cfg.ServerName, cfg.Port)
-	log.Printf("[%s] HTTP endpoint: http://localhost:%s/", cfg.ServerName, cfg.Port)
+// readOnly answers GET and HEAD with next, net/http dropping the body of a
+// HEAD response, and any other method with HTTP 405.
+func readOnly(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// shutdownOnSignal gracefully stops srv and the MCP server on SIGINT or
+// SIGTERM, then closes done. Shutting down closes the listener, which also
+// removes a Unix socket file.
+func (p *MCPProxy) shutdownOnSignal(srv *http.Server, done chan<- struct{}) {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	p.logger.infof("Received %s, shutting down", <-sig)
 
-	return http.ListenAndServe(":"+cfg.Port, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		p.logger.warnf("HTTP server shutdown: %v", err)
+	}
+	p.stop()
+	close(done)
 }
@@ -1,14 +1,20 @@
 package mcpproxy
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
+	"log"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strconv"
 	"strings"
+	"syscall"
 	"testing"
+	"time"
 )
 
 func TestFormatID(t *testing.T) {
@@ -34,6 +40,122 @@ func TestFormatID(t *testing.T) {
 	}
 }
 
+func TestSameID(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b interface{}
+		want bool
+	}{
+		{"equal strings", "req-1", "req-1", true},
+		{"different strings", "req-1", "req-2", false},
+		{"float64 vs json.Number", float64(1), json.Number("1"), true},
+		{"float ids", float64(1.5), json.Number("1.5"), true},
+		{"different numbers", json.Number("1"), json.Number("2"), false},
+		{"string echo of numeric id", json.Number("1"), "1", false},
+		{"large ints keep precision", json.Number("9007199254740993"), json.Number("9007199254740992"), false},
+		{"both nil", nil, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sameID(tt.a, tt.b); got != tt.want {
+				t.Errorf("sameID(%#v, %#v) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMessageID(t *testing.T) {
+	tests := []struct {
+		msg  string
+		want interface{}
+	}{
+		{`{"id":1}`, json.Number("1")},
+		{`{"id":"abc"}`, "abc"},
+		{`{"id":null}`, nil},
+		{`{"method":"x"}`, nil},
+		{`not json`, nil},
+	}
+
+	for _, tt := range tests {
+		if got := messageID(json.RawMessage(tt.msg)); got != tt.want {
+			t.Errorf("messageID(%s) = %#v, want %#v", tt.msg, got, tt.want)
+		}
+	}
+}
+
+func TestReadResponseIDMatching(t *testing.T) {
+	tests := []struct {
+		name    string
+		request string
+		stdout  string
+		want    string
+	}{
+		{
+			name:    "string id skips mismatched response",
+			request: `{"jsonrpc":"2.0","id":"b","method":"x"}`,
+			stdout:  "{\"jsonrpc\":\"2.0\",\"id\":\"b\",\"result\":1}\n",
+			want:    `{"jsonrpc":"2.0","id":"b","result":1}`,
+		},
+		{
+			name:    "numeric id",
+			request: `{"jsonrpc":"2.0","id":7,"method":"x"}`,
+			stdout:  "{\"jsonrpc\":\"2.0\",\"method\":\"notifications/progress\"}\n{\"jsonrpc\":\"2.0\",\"id\":7,\"result\":1}\n",
+			want:    `{"jsonrpc":"2.0","id":7,"result":1}`,
+		},
+		{
+			name:    "string echo of numeric id is returned with a warning",
+			request: `{"jsonrpc":"2.0","id":7,"method":"x"}`,
+			stdout:  "{\"jsonrpc\":\"2.0\",\"id\":\"7\",\"result\":1}\n",
+			want:    `{"jsonrpc":"2.0","id":"7","result":1}`,
+		},
+		{
+			name:    "final response without a newline",
+			request: `{"jsonrpc":"2.0","id":7,"method":"x"}`,
+			stdout:  "{\"jsonrpc\":\"2.0\",\"method\":\"notifications/progress\"}\n{\"jsonrpc\":\"2.0\",\"id\":7,\"result\":1}",
+			want:    `{"jsonrpc":"2.0","id":7,"result":1}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxy := &MCPProxy{
+				config: Config{ServerName: "test", SkipNotifications: true},
+				stdout: bufio.NewReader(strings.NewReader(tt.stdout)),
+			}
+			got, err := proxy.readResponse(json.RawMessage(tt.request), "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("readResponse() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResponseWithoutNewlineBeforeExit(t *testing.T) {
+	// The server answers without a trailing newline, then closes stdout and exits
+	proxy, err := NewMCPProxy(Config{
+		ServerName:  "test",
+		CommandPath: "sh",
+		CommandArgs: []string{"-c", `read -r line; printf '%s' "$line"`},
+		MaxRestarts: -1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+
+	w := ping(proxy)
+	if w.Code != http.StatusOK || w.Body.String() != `{"jsonrpc":"2.0","id":1,"method":"ping"}` {
+		t.Errorf("Expected the unterminated response, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := ping(proxy); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 once the server has exited, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
 func TestMCPMessageParsing(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -101,6 +223,35 @@ func TestConfigDefaults(t *testing.T) {
 	}
 }
 
+func TestConfigServerName(t *testing.T) {
+	t.Setenv("TEST_MCP_PATH", "")
+	tests := []struct {
+		name    string
+		cfg     Config
+		env     string
+		envPath string
+		want    string
+	}{
+		{"set in code", Config{ServerName: "github-mcp", CommandPath: "/server/github-mcp-server"}, "", "", "github-mcp"},
+		{"env override", Config{ServerName: "github-mcp", CommandPath: "/server/github-mcp-server"}, "github-a", "", "github-a"},
+		{"command basename", Config{CommandPath: "/opt/sqlcl/bin/sql"}, "", "", "sql"},
+		{"path env basename", Config{CommandPath: "/opt/sqlcl/bin/sql", PathEnvVar: "TEST_MCP_PATH"}, "", "/usr/local/bin/sql-dev", "sql-dev"},
+		{"bare command", Config{CommandPath: "cat"}, "", "", "cat"},
+		{"fallback", Config{}, "", "", "mcp-server"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("MCP_SERVER_NAME", tt.env)
+			t.Setenv("TEST_MCP_PATH", tt.envPath)
+			cfg := tt.cfg
+			cfg.applyDefaults()
+			if cfg.ServerName != tt.want {
+				t.Errorf("ServerName = %q, want %q", cfg.ServerName, tt.want)
+			}
+		})
+	}
+}
+
 func TestConfigPathEnvOverride(t *testing.T) {
 	// Set up test environment variable
 	os.Setenv("TEST_MCP_PATH", "/custom/path")
@@ -276,6 +427,26 @@ func TestHandleRequest(t *testing.T) {
 	}
 }
 
+func TestHandleWritesResponseDirectly(t *testing.T) {
+	expectedResponse := `{"jsonrpc":"2.0","id":1,"result":{"content":[]}}`
+	proxy := &MCPProxy{config: Config{ServerName: "test"}, requests: make(chan *request, 1)}
+	drainRequests(proxy, func(json.RawMessage) json.RawMessage { return json.RawMessage(expectedResponse) })
+	defer close(proxy.requests)
+
+	w := httptest.NewRecorder()
+	proxy.Handle(w, httptest.NewRequest("POST", "/", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/call"}`)))
+
+	if got := w.Header().Get("Content-Length"); got != strconv.Itoa(len(expectedResponse)) {
+		t.Errorf("Expected Content-Length %d, got %q", len(expectedResponse), got)
+	}
+	if !w.Flushed {
+		t.Error("Expected response to be flushed")
+	}
+	if w.Body.String() != expectedResponse {
+		t.Errorf("Expected response %q, got %q", expectedResponse, w.Body.String())
+	}
+}
+
 func TestHandleNotification(t *testing.T) {
 	proxy := NewMockMCPProxy(Config{
 		ServerName: "test",
@@ -307,6 +478,116 @@ func TestHandleInvalidJSON(t *testing.T) {
 	}
 }
 
+// The tests above exercise MockMCPProxy; these cover the same paths through
+// the real Handle.
+
+func TestHandleCORSPreflight(t *testing.T) {
+	proxy := &MCPProxy{config: Config{ServerName: "test", EnableCORS: true}, requests: make(chan *request, 1)}
+
+	w := httptest.NewRecorder()
+	proxy.Handle(w, httptest.NewRequest("OPTIONS", "/", nil))
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 for OPTIONS, got %d", w.Code)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Methods"); !strings.Contains(got, "POST") {
+		t.Errorf("Expected POST to be allowed, got %q", got)
+	}
+	if len(proxy.requests) != 0 {
+		t.Error("Expected the preflight not to reach the MCP server")
+	}
+}
+
+func TestHandleNotificationAccepted(t *testing.T) {
+	proxy := &MCPProxy{config: Config{ServerName: "test"}, requests: make(chan *request, 1)}
+	forwarded := make(chan json.RawMessage, 1)
+	go func() {
+		for req := range proxy.requests {
+			forwarded <- req.msg
+			close(req.response)
+		}
+	}()
+	defer close(proxy.requests)
+
+	body := `{"jsonrpc":"2.0","method":"notifications/initialized"}`
+	w := httptest.NewRecorder()
+	proxy.Handle(w, httptest.NewRequest("POST", "/", strings.NewReader(body)))
+
+	if w.Code != http.StatusAccepted {
+		t.Errorf("Expected status 202 for a notification, got %d", w.Code)
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("Expected an empty body, got %q", w.Body.String())
+	}
+	if got := string(<-forwarded); got != body {
+		t.Errorf("Expected the notification to be forwarded as is, got %q", got)
+	}
+}
+
+func TestHandleDecodeErrors(t *testing.T) {
+	for _, body := range []string{`not valid json`, ``, `{"jsonrpc":"2.0","id":1`, `[`} {
+		proxy := &MCPProxy{config: Config{ServerName: "test"}, requests: make(chan *request, 1)}
+
+		w := httptest.NewRecorder()
+		proxy.Handle(w, httptest.NewRequest("POST", "/", strings.NewReader(body)))
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Body %q: expected status 400, got %d", body, w.Code)
+		}
+		if len(proxy.requests) != 0 {
+			t.Errorf("Body %q: expected nothing to be forwarded", body)
+		}
+	}
+}
+
+func TestHandleSkipsServerNotifications(t *testing.T) {
+	// A server that sends notifications before each response, which echoes
+	// the request
+	proxy, err := NewMCPProxy(Config{
+		ServerName:  "test",
+		CommandPath: "sh",
+		CommandArgs: []string{"-c", `while read line; do
+			echo '{"jsonrpc":"2.0","method":"notifications/progress","params":{"progress":1}}'
+			echo '{"jsonrpc":"2.0","method":"notifications/message","params":{"level":"info"}}'
+			echo "$line"
+		done`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+
+	for _, body := range []string{
+		`{"jsonrpc":"2.0","id":1,"method":"ping"}`,
+		`{"jsonrpc":"2.0","id":"two","method":"ping"}`,
+	} {
+		w := httptest.NewRecorder()
+		proxy.Handle(w, httptest.NewRequest("POST", "/", strings.NewReader(body)))
+		if w.Code != http.StatusOK || w.Body.String() != body {
+			t.Errorf("Expected the response matching %s, got %d: %s", body, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestReadResponseReturnsFirstIDBearingMessage(t *testing.T) {
+	// Without SkipNotifications the ID isn't checked: requests are sent one
+	// at a time, so the next message with an id is taken as the response
+	proxy := &MCPProxy{
+		config: Config{ServerName: "test"},
+		stdout: bufio.NewReader(strings.NewReader(
+			"{\"jsonrpc\":\"2.0\",\"method\":\"notifications/progress\"}\n" +
+				"{\"jsonrpc\":\"2.0\",\"id\":999,\"result\":{}}\n" +
+				"{\"jsonrpc\":\"2.0\",\"id\":1,\"result\":{}}\n")),
+	}
+	got, err := proxy.readResponse(json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"ping"}`), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"jsonrpc":"2.0","id":999,"result":{}}`; string(got) != want {
+		t.Errorf("readResponse() = %s, want %s", got, want)
+	}
+}
+
 func TestRequestMiddlewareIDChange(t *testing.T) {
 	// This tests that if RequestMiddleware modifies the request ID,
 	// readResponse uses the modified ID for matching, not the original
@@ -436,17 +717,35 @@ func TestIsRequestDetection(t *testing.T) {
 			isRequest: false,
 		},
 		{
-			name:      "notification with null id",
+			name:      "request with null id",
 			json:      `{"jsonrpc":"2.0","id":null,"method":"test"}`,
+			isRequest: true,
+		},
+		{
+			name:      "request with zero id",
+			json:      `{"jsonrpc":"2.0","id":0,"method":"test"}`,
+			isRequest: true,
+		},
+		{
+			name:      "request with empty string id",
+			json:      `{"jsonrpc":"2.0","id":"","method":"test"}`,
+			isRequest: true,
+		},
+		{
+			name:      "id only in params",
+			json:      `{"jsonrpc":"2.0","method":"test","params":{"id":1}}`,
+			isRequest: false,
+		},
+		{
+			name:      "invalid JSON",
+			json:      `{"id":1`,
 			isRequest: false,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			var msg MCPMessage
-			json.Unmarshal([]byte(tt.json), &msg)
-			isRequest := msg.ID != nil
+			isRequest := hasID(json.RawMessage(tt.json))
 
 			if isRequest != tt.isRequest {
 				t.Errorf("Expected isRequest=%v, got %v", tt.isRequest, isRequest)
@@ -454,3 +753,643 @@ func TestIsRequestDetection(t *testing.T) {
 		})
 	}
 }
+
+func TestHandleQueueFull(t *testing.T) {
+	proxy := &MCPProxy{
+		config:   Config{ServerName: "test"},
+		requests: make(chan *request, 1),
+	}
+	// Fill the queue; nothing is draining it
+	proxy.requests <- &request{}
+
+	req := httptest.NewRequest("POST", "/", strings.NewReader(`{"jsonrpc":"2.0","id":7,"method":"tools/list"}`))
+	w := httptest.NewRecorder()
+	proxy.Handle(w, req)
+
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status 429 when queue is full, got %d", w.Code)
+	}
+
+	var resp struct {
+		ID    interface{} `json:"id"`
+		Error struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode error response: %v", err)
+	}
+	if resp.Error.Code != -32000 || resp.Error.Message != "server busy" {
+		t.Errorf("Unexpected error %+v", resp.Error)
+	}
+	if resp.ID != float64(7) {
+		t.Errorf("Expected id 7 to be echoed, got %v", resp.ID)
+	}
+}
+
+func TestFastNotifications(t *testing.T) {
+	// Nothing drains the queue, like a writer stuck on a server that stopped reading
+	proxy := &MCPProxy{
+		config:   Config{ServerName: "test", FastNotifications: true},
+		logger:   newLogger("test", "warn"),
+		requests: make(chan *request, 2),
+	}
+
+	done := make(chan int)
+	go func() {
+		w := httptest.NewRecorder()
+		proxy.Handle(w, httptest.NewRequest("POST", "/", strings.NewReader(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)))
+		done <- w.Code
+	}()
+	select {
+	case code := <-done:
+		if code != http.StatusAccepted {
+			t.Errorf("Expected status 202, got %d", code)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Notification waited for the stalled writer")
+	}
+
+	select {
+	case req := <-proxy.requests:
+		if !strings.Contains(string(req.msg), "notifications/initialized") {
+			t.Errorf("Unexpected message queued: %s", req.msg)
+		}
+		close(req.response)
+	default:
+		t.Error("Expected the notification to be queued")
+	}
+}
+
+func TestNotificationClientDisconnect(t *testing.T) {
+	// Nothing drains the queue, so the notification is never processed
+	proxy := &MCPProxy{
+		config:   Config{ServerName: "test"},
+		logger:   newLogger("test", "warn"),
+		requests: make(chan *request, 2),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := httptest.NewRequest("POST", "/", strings.NewReader(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)).WithContext(ctx)
+	done := make(chan struct{})
+	go func() {
+		proxy.Handle(httptest.NewRecorder(), r)
+		close(done)
+	}()
+
+	// Wait for the notification to be queued, then disconnect
+	req := <-proxy.requests
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Handler stayed blocked after the client disconnected")
+	}
+
+	// Processing it later doesn't block either
+	close(req.response)
+}
+
+func TestHandleAfterShutdown(t *testing.T) {
+	proxy := &MCPProxy{
+		config:   Config{ServerName: "test"},
+		requests: make(chan *request, 1),
+		done:     make(chan struct{}),
+	}
+	// Shut down the queue the way stop does
+	close(proxy.done)
+	close(proxy.requests)
+
+	req := httptest.NewRequest("POST", "/", strings.NewReader(`{"jsonrpc":"2.0","id":7,"method":"tools/list"}`))
+	w := httptest.NewRecorder()
+	proxy.Handle(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected status 503 after shutdown, got %d", w.Code)
+	}
+	var resp struct {
+		Error struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode error response: %v", err)
+	}
+	if resp.Error.Code != codeServerUnavailable || resp.Error.Message != "server shutting down" {
+		t.Errorf("Unexpected error %+v", resp.Error)
+	}
+}
+
+func TestConfigQueueSizeFromEnv(t *testing.T) {
+	os.Setenv("MCP_QUEUE_SIZE", "5")
+	defer os.Unsetenv("MCP_QUEUE_SIZE")
+
+	cfg := Config{QueueSize: 50}
+	cfg.applyDefaults()
+	if cfg.QueueSize != 5 {
+		t.Errorf("Expected queue size 5 from env, got %d", cfg.QueueSize)
+	}
+
+	os.Unsetenv("MCP_QUEUE_SIZE")
+	cfg = Config{}
+	cfg.applyDefaults()
+	if cfg.QueueSize != 100 {
+		t.Errorf("Expected default queue size 100, got %d", cfg.QueueSize)
+	}
+}
+
+// drainRequests stands in for processRequests, answering each queued request
+// with respond(msg) so Handle can be exercised without a subprocess.
+func drainRequests(p *MCPProxy, respond func(msg json.RawMessage) json.RawMessage) {
+	go func() {
+		for req := range p.requests {
+			if req.isRequest {
+				req.response <- respond(req.msg)
+			}
+			close(req.response)
+		}
+	}()
+}
+
+func TestValidateCommand(t *testing.T) {
+	dir := t.TempDir()
+
+	executable := dir + "/server"
+	if err := os.WriteFile(executable, []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	notExecutable := dir + "/config.txt"
+	if err := os.WriteFile(notExecutable, []byte("data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{"executable file", executable, false},
+		{"command in PATH", "sh", false},
+		{"missing file", dir + "/missing", true},
+		{"not executable", notExecutable, true},
+		{"directory", dir, true},
+		{"empty", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateCommand(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateCommand(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			}
+			if err != nil && tt.path != "" && !strings.Contains(err.Error(), tt.path) {
+				t.Errorf("Expected error to name the command, got %q", err)
+			}
+		})
+	}
+}
+
+func TestNewMCPProxyMissingCommand(t *testing.T) {
+	_, err := NewMCPProxy(Config{
+		ServerName:  "test",
+		CommandPath: "/opt/oracle/sqlcl/bin/sql-does-not-exist",
+	})
+	if err == nil {
+		t.Fatal("Expected an error for a missing command")
+	}
+	if !strings.Contains(err.Error(), "not found or not executable") {
+		t.Errorf("Unexpected error %q", err)
+	}
+}
+
+func TestHandleNullIDExpectsResponse(t *testing.T) {
+	proxy := &MCPProxy{
+		config:   Config{ServerName: "test"},
+		requests: make(chan *request, 1),
+	}
+	drainRequests(proxy, func(json.RawMessage) json.RawMessage {
+		return json.RawMessage(`{"jsonrpc":"2.0","id":null,"result":{}}`)
+	})
+	defer close(proxy.requests)
+
+	req := httptest.NewRequest("POST", "/", strings.NewReader(`{"jsonrpc":"2.0","id":null,"method":"ping"}`))
+	w := httptest.NewRecorder()
+	proxy.Handle(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 with a response for id:null, got %d", w.Code)
+	}
+	if w.Body.String() != `{"jsonrpc":"2.0","id":null,"result":{}}` {
+		t.Errorf("Unexpected body %q", w.Body.String())
+	}
+}
+
+func BenchmarkHandleLargeResponse(b *testing.B) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	rows := strings.Repeat(`{"ID":12345,"NAME":"some customer name","CITY":"Raleigh"},`, 200000)
+	response := json.RawMessage(`{"jsonrpc":"2.0","id":1,"result":{"content":[{"type":"text","text":"` +
+		strings.ReplaceAll(rows, `"`, `\"`) + `"}]}}`)
+	stdout := bytes.Repeat(append(response, '\n'), b.N)
+
+	proxy := &MCPProxy{
+		config:   Config{ServerName: "bench", SkipNotifications: true, LogPayloads: true},
+		writer:   bufio.NewWriter(io.Discard),
+		stdout:   bufio.NewReader(bytes.NewReader(stdout)),
+		requests: make(chan *request, 1),
+	}
+	go proxy.processRequests()
+	defer close(proxy.requests)
+
+	body := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"run-sql"}}`
+	b.SetBytes(int64(len(response)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w := httptest.NewRecorder()
+		proxy.Handle(w, httptest.NewRequest("POST", "/", strings.NewReader(body)))
+		if w.Body.Len() != len(response) {
+			b.Fatalf("Expected %d bytes, got %d", len(response), w.Body.Len())
+		}
+	}
+}
+
+func TestWriteMessage(t *testing.T) {
+	var buf bytes.Buffer
+	proxy := &MCPProxy{writer: bufio.NewWriter(&buf)}
+
+	// A message with spare capacity must not have the delimiter written into it
+	backing := make([]byte, 0, 64)
+	msg := json.RawMessage(append(backing, `{"id":1}`...))
+	if err := proxy.writeMessage(msg); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != "{\"id\":1}\n" {
+		t.Errorf("Expected newline-delimited message, got %q", got)
+	}
+	if got := backing[:len(msg)+1]; got[len(msg)] != 0 {
+		t.Errorf("writeMessage modified the message's backing array: %q", got)
+	}
+}
+
+func BenchmarkProcessRequestsWrite(b *testing.B) {
+	proxy := &MCPProxy{
+		config:   Config{ServerName: "bench"},
+		writer:   bufio.NewWriter(io.Discard),
+		requests: make(chan *request, 1),
+	}
+	go proxy.processRequests()
+	defer close(proxy.requests)
+
+	msg := json.RawMessage(`{"jsonrpc":"2.0","method":"notifications/progress","params":{"progress":1}}`)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		req := &request{msg: msg, response: make(chan json.RawMessage, 1)}
+		proxy.requests <- req
+		<-req.response
+	}
+}
+
+func TestHandleFailsFastWhenServerDies(t *testing.T) {
+	proxy, err := NewMCPProxy(Config{ServerName: "test", CommandPath: "cat", MaxRestarts: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+
+	call := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		done := make(chan struct{})
+		go func() {
+			proxy.Handle(w, httptest.NewRequest("POST", "/", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`)))
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Request blocked after the MCP server died")
+		}
+		return w
+	}
+
+	if w := call(); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 while the server is running, got %d", w.Code)
+	}
+
+	proxy.cmd.Process.Kill()
+
+	// The first request after the kill fails on the dead pipe, later ones
+	// without touching it
+	for i := 0; i < 2; i++ {
+		w := call()
+		if w.Code != http.StatusServiceUnavailable {
+			t.Fatalf("Request %d: expected 503 after the server died, got %d", i, w.Code)
+		}
+		var resp struct {
+			Error struct {
+				Code int `json:"code"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Error.Code != codeServerUnavailable {
+			t.Errorf("Request %d: expected JSON-RPC error %d, got %s", i, codeServerUnavailable, w.Body.String())
+		}
+	}
+	if !proxy.exited.Load() {
+		t.Error("Expected the proxy to be marked as exited")
+	}
+}
+
+// brokenPipe fails every write as if the MCP server had just exited.
+type brokenPipe struct{ onWrite func() }
+
+func (b brokenPipe) Write([]byte) (int, error) {
+	b.onWrite()
+	return 0, syscall.EPIPE
+}
+
+func TestHandleRetriesWriteDuringRestart(t *testing.T) {
+	proxy, err := NewMCPProxy(Config{ServerName: "test", CommandPath: "cat"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+
+	// The next write fails just as the process is being restarted
+	proxy.io.Lock()
+	proxy.writer = bufio.NewWriter(brokenPipe{onWrite: func() {
+		proxy.restarting.Store(true)
+		go proxy.restart()
+	}})
+	proxy.io.Unlock()
+
+	w := httptest.NewRecorder()
+	proxy.Handle(w, httptest.NewRequest("POST", "/", strings.NewReader(`{"jsonrpc":"2.0","id":7,"method":"ping"}`)))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"id":7`) {
+		t.Errorf("Expected the request to be replayed on the new process, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandleDoesNotRetryNotifications(t *testing.T) {
+	proxy, err := NewMCPProxy(Config{ServerName: "test", CommandPath: "cat"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+
+	writes := 0
+	proxy.io.Lock()
+	proxy.writer = bufio.NewWriter(brokenPipe{onWrite: func() {
+		writes++
+		proxy.restarting.Store(true)
+		go proxy.restart()
+	}})
+	proxy.io.Unlock()
+
+	w := httptest.NewRecorder()
+	proxy.Handle(w, httptest.NewRequest("POST", "/", strings.NewReader(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 for a notification that failed to send, got %d", w.Code)
+	}
+	if writes != 1 {
+		t.Errorf("Expected a single write attempt, got %d", writes)
+	}
+}
+
+func TestWriteTimeoutFailsStuckServer(t *testing.T) {
+	// A server that never reads its stdin; once the pipe buffer is full,
+	// writes block
+	proxy, err := NewMCPProxy(Config{
+		ServerName:   "test",
+		CommandPath:  "sh",
+		CommandArgs:  []string{"-c", "sleep 60"},
+		WriteTimeout: 100 * time.Millisecond,
+		MaxRestarts:  -1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+
+	body := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"sql":"` + strings.Repeat("x", 1<<20) + `"}}`
+	w := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		proxy.Handle(w, httptest.NewRequest("POST", "/", strings.NewReader(body)))
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Request blocked on a server that stopped reading stdin")
+	}
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 after the write timed out, got %d", w.Code)
+	}
+	if !proxy.exited.Load() {
+		t.Error("Expected the proxy to be marked as exited")
+	}
+}
+
+func TestHandleStrictJSONRPC(t *testing.T) {
+	tests := []struct {
+		name       string
+		strict     bool
+		body       string
+		wantStatus int
+	}{
+		{"valid version", true, `{"jsonrpc":"2.0","id":1,"method":"ping"}`, http.StatusOK},
+		{"missing version", true, `{"id":1,"method":"ping"}`, http.StatusBadRequest},
+		{"wrong version", true, `{"jsonrpc":"1.0","id":1,"method":"ping"}`, http.StatusBadRequest},
+		{"numeric version", true, `{"jsonrpc":2.0,"id":1,"method":"ping"}`, http.StatusBadRequest},
+		{"lenient by default", false, `{"jsonrpc":"1.0","id":1,"method":"ping"}`, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxy := &MCPProxy{
+				config:   Config{ServerName: "test", StrictJSONRPC: tt.strict},
+				requests: make(chan *request, 1),
+			}
+			drainRequests(proxy, func(json.RawMessage) json.RawMessage {
+				return json.RawMessage(`{"jsonrpc":"2.0","id":1,"result":{}}`)
+			})
+			defer close(proxy.requests)
+
+			w := httptest.NewRecorder()
+			proxy.Handle(w, httptest.NewRequest("POST", "/", strings.NewReader(tt.body)))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantStatus == http.StatusBadRequest {
+				want := `{"error":{"code":-32600,"message":"Invalid Request"},"id":1,"jsonrpc":"2.0"}`
+				if got := strings.TrimSpace(w.Body.String()); got != want {
+					t.Errorf("Expected %s, got %s", want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleStrictMethodID(t *testing.T) {
+	tests := []struct {
+		name       string
+		strict     bool
+		body       string
+		wantStatus int
+		wantID     string
+	}{
+		{"request without id", true, `{"jsonrpc":"2.0","method":"tools/call","params":{"name":"x"}}`, http.StatusBadRequest, "null"},
+		{"notification with id", true, `{"jsonrpc":"2.0","id":3,"method":"notifications/initialized"}`, http.StatusBadRequest, "3"},
+		{"request", true, `{"jsonrpc":"2.0","id":1,"method":"initialize"}`, http.StatusOK, ""},
+		{"notification", true, `{"jsonrpc":"2.0","method":"notifications/initialized"}`, http.StatusAccepted, ""},
+		{"lenient by default", false, `{"jsonrpc":"2.0","id":3,"method":"notifications/initialized"}`, http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxy := &MCPProxy{
+				config:   Config{ServerName: "test", StrictJSONRPC: tt.strict},
+				requests: make(chan *request, 1),
+			}
+			var forwarded int
+			drainRequests(proxy, func(json.RawMessage) json.RawMessage {
+				forwarded++
+				return json.RawMessage(`{"jsonrpc":"2.0","id":1,"result":{}}`)
+			})
+
+			w := httptest.NewRecorder()
+			proxy.Handle(w, httptest.NewRequest("POST", "/", strings.NewReader(tt.body)))
+			close(proxy.requests)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusBadRequest {
+				return
+			}
+			if forwarded != 0 {
+				t.Error("Expected the mismatched message not to be forwarded")
+			}
+			var resp struct {
+				ID    json.RawMessage `json:"id"`
+				Error struct {
+					Code    int    `json:"code"`
+					Message string `json:"message"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Failed to decode error response: %v", err)
+			}
+			if resp.Error.Code != CodeInvalidRequest || !strings.HasPrefix(resp.Error.Message, "Invalid Request: ") {
+				t.Errorf("Unexpected error %+v", resp.Error)
+			}
+			if string(resp.ID) != tt.wantID {
+				t.Errorf("Expected id %s, got %s", tt.wantID, resp.ID)
+			}
+		})
+	}
+}
+
+func TestHandleRejectsOversizedBody(t *testing.T) {
+	proxy := &MCPProxy{
+		config:   Config{ServerName: "test", MaxRequestBytes: 1024},
+		requests: make(chan *request, 1),
+	}
+
+	body := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"sql":"` + strings.Repeat("x", 2048) + `"}}`
+	w := httptest.NewRecorder()
+	proxy.Handle(w, httptest.NewRequest("POST", "/", strings.NewReader(body)))
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413 for a body over the limit, got %d", w.Code)
+	}
+	if len(proxy.requests) != 0 {
+		t.Error("Expected the oversized request not to be forwarded")
+	}
+}
+
+func FuzzHandle(f *testing.F) {
+	for _, seed := range []struct{ body, response string }{
+		{`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`, `{"jsonrpc":"2.0","id":1,"result":{"tools":[{"name":"a"}]}}`},
+		{`{"jsonrpc":"2.0","id":"x","method":"tools/call","params":{"name":"a","arguments":{}}}`, `{"jsonrpc":"2.0","id":"x","result":{}}`},
+		{`{"jsonrpc":"2.0","method":"notifications/initialized"}`, ``},
+		{`{"jsonrpc":"2.0","id":null,"method":"ping"}`, `not json`},
+		{`[{"jsonrpc":"2.0","id":1}]`, `{"result":{"tools":"oops"}}`},
+		{`{"id":1e400,"params":{"name":{}}}`, `{}`},
+		{``, ``},
+	} {
+		f.Add([]byte(seed.body), []byte(seed.response))
+	}
+
+	f.Fuzz(func(t *testing.T, body, response []byte) {
+		proxy := &MCPProxy{
+			config:   Config{ServerName: "test", AllowedTools: []string{"a"}, MaxRequestBytes: 1 << 20},
+			requests: make(chan *request, 1),
+		}
+		drainRequests(proxy, func(json.RawMessage) json.RawMessage { return response })
+		defer close(proxy.requests)
+
+		w := httptest.NewRecorder()
+		proxy.Handle(w, httptest.NewRequest("POST", "/", bytes.NewReader(body)))
+		if w.Code < 100 || w.Code > 599 {
+			t.Errorf("Invalid HTTP status %d for body %q", w.Code, body)
+		}
+	})
+}
+
+func FuzzMessageID(f *testing.F) {
+	for _, seed := range []string{`1`, `"abc"`, `null`, `-0`, `1.5e3`, `12345678901234567890`, `"é"`, `{"a":1}`, `[1,"2"]`, `true`} {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, id []byte) {
+		msg := json.RawMessage(`{"jsonrpc":"2.0","id":` + string(id) + `,"method":"x"}`)
+		if !json.Valid(msg) {
+			// Must not panic on malformed messages
+			hasID(msg)
+			formatID(messageID(msg))
+			return
+		}
+		if !hasID(msg) {
+			t.Fatalf("hasID(%s) = false", msg)
+		}
+
+		got := messageID(msg)
+		if !sameID(got, got) {
+			t.Errorf("sameID is not reflexive for %s", id)
+		}
+		if got == nil {
+			return
+		}
+
+		// formatID produces the ID as JSON, which identifies the same ID
+		formatted := formatID(got)
+		again := messageID(json.RawMessage(`{"id":` + formatted + `}`))
+		if !sameID(got, again) {
+			t.Errorf("ID %s formatted as %s no longer matches", id, formatted)
+		}
+	})
+}
+
+func TestHandleMethodNotAllowed(t *testing.T) {
+	proxy := &MCPProxy{config: Config{ServerName: "test"}, logger: newLogger("test", "warn")}
+	for _, method := range []string{"PUT", "DELETE", "PATCH", "HEAD"} {
+		w := httptest.NewRecorder()
+		proxy.Handle(w, httptest.NewRequest(method, "/", strings.NewReader(`{}`)))
+		if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != "GET, POST, OPTIONS" {
+			t.Errorf("Expected %s to get 405 with an Allow header, got %d %q", method, w.Code, w.Header().Get("Allow"))
+		}
+	}
+
+	w := httptest.NewRecorder()
+	proxy.Handle(w, httptest.NewRequest("OPTIONS", "/", nil))
+	if w.Code != http.StatusNoContent || w.Header().Get("Allow") == "" {
+		t.Errorf("Expected OPTIONS to be answered with the allowed methods, got %d %q", w.Code, w.Header().Get("Allow"))
+	}
+}
@@ -0,0 +1,159 @@
+package mcpproxy
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// rateLimiter is a per-client token bucket limiter. Each client may make
+// burst requests at once and rps requests per second on average, so a
+// runaway agent loop can't monopolize the MCP server.
+//
+// A nil *rateLimiter allows everything.
+type rateLimiter struct {
+	rps   float64
+	burst float64
+	now   func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastPrune time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter returns a limiter, or nil if rps disables rate limiting.
+func newRateLimiter(rps float64, burst int) *rateLimiter {
+	if rps <= 0 {
+		return nil
+	}
+	if burst <= 0 {
+		burst = int(math.Ceil(rps))
+	}
+	return &rateLimiter{
+		rps:     rps,
+		burst:   float64(burst),
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// allow takes a token from key's bucket. If none is available it returns
+// false and how long until one will be.
+func (l *rateLimiter) allow(key string) (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.prune(now)
+
+	b := l.buckets[key]
+	if b == nil {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rps)
+	b.last = now
+
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.rps * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// prune drops buckets that have refilled completely, since a new bucket would
+// be identical. It runs at most once a minute. l.mu must be held.
+func (l *rateLimiter) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < time.Minute {
+		return
+	}
+	l.lastPrune = now
+	full := time.Duration(l.burst / l.rps * float64(time.Second))
+	for key, b := range l.buckets {
+		if now.Sub(b.last) > full {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// toolRateLimiter limits calls to each tool across all clients. Tools
+// without a limit of their own share the default limiter, in buckets keyed by
+// tool name.
+//
+// A nil *toolRateLimiter allows everything.
+type toolRateLimiter struct {
+	tools    map[string]*rateLimiter
+	fallback *rateLimiter
+}
+
+// newToolRateLimiter returns a limiter for the requests per second in rps,
+// keyed by tool name with "default" for the rest, or nil if rps is empty.
+func newToolRateLimiter(rps map[string]float64) *toolRateLimiter {
+	if len(rps) == 0 {
+		return nil
+	}
+	l := &toolRateLimiter{tools: make(map[string]*rateLimiter)}
+	for tool, r := range rps {
+		if tool == "default" {
+			l.fallback = newRateLimiter(r, 0)
+		} else {
+			l.tools[tool] = newRateLimiter(r, 0)
+		}
+	}
+	return l
+}
+
+// allow takes a token from tool's bucket, like rateLimiter.allow.
+func (l *toolRateLimiter) allow(tool string) (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+	if limiter, ok := l.tools[tool]; ok {
+		return limiter.allow(tool)
+	}
+	return l.fallback.allow(tool)
+}
+
+// parseToolRateLimits parses MCP_TOOL_RATE_LIMITS, comma-separated tool=rps
+// pairs such as "search_code=0.5,default=10".
+func parseToolRateLimits(s string) (map[string]float64, error) {
+	pairs, err := parseToolPairs(s)
+	if err != nil {
+		return nil, err
+	}
+	limits := make(map[string]float64, len(pairs))
+	for tool, value := range pairs {
+		rps, err := strconv.ParseFloat(value, 64)
+		if err != nil || rps <= 0 {
+			return nil, fmt.Errorf("invalid rate %q for tool %q: must be a positive number of requests per second", value, tool)
+		}
+		limits[tool] = rps
+	}
+	return limits, nil
+}
+
+// clientKey identifies the client for rate limiting: the X-Client-Id header
+// if a trusted proxy passed it on, otherwise the client IP. A client connecting
+// directly can't get a fresh bucket by changing the header.
+func clientKey(r *http.Request) string {
+	if id, ok := r.Context().Value(clientIDKey{}).(string); ok {
+		return "id:" + id
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
@@ -0,0 +1,162 @@
+package mcpproxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterBucket(t *testing.T) {
+	now := time.Now()
+	l := newRateLimiter(2, 3)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.allow("a"); !ok {
+			t.Fatalf("Request %d: expected burst to be allowed", i)
+		}
+	}
+	ok, wait := l.allow("a")
+	if ok {
+		t.Fatal("Expected request beyond the burst to be limited")
+	}
+	if wait != 500*time.Millisecond {
+		t.Errorf("Expected to wait 500ms for the next token at 2 rps, got %s", wait)
+	}
+
+	// Other clients have their own bucket
+	if ok, _ := l.allow("b"); !ok {
+		t.Error("Expected a different client to be allowed")
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if ok, _ := l.allow("a"); !ok {
+		t.Error("Expected a token to be refilled after 500ms")
+	}
+}
+
+func TestRateLimiterPrunesFullBuckets(t *testing.T) {
+	now := time.Now()
+	l := newRateLimiter(1, 1)
+	l.now = func() time.Time { return now }
+
+	l.allow("a")
+	now = now.Add(2 * time.Minute)
+	l.allow("b")
+	if _, ok := l.buckets["a"]; ok {
+		t.Error("Expected the refilled bucket to be pruned")
+	}
+}
+
+func TestClientKey(t *testing.T) {
+	r := httptest.NewRequest("POST", "/", nil)
+	r.RemoteAddr = "10.0.0.1:51234"
+	if got := clientKey(r); got != "ip:10.0.0.1" {
+		t.Errorf("Expected the client IP, got %q", got)
+	}
+	r.Header.Set("X-Client-Id", "agent-7")
+	if got := clientKey(r); got != "ip:10.0.0.1" {
+		t.Errorf("Expected X-Client-Id from an untrusted client to be ignored, got %q", got)
+	}
+
+	trusted, _ := parseTrustedProxies([]string{"10.0.0.1"})
+	if got := clientKey((&MCPProxy{trusted: trusted}).withClientIP(r)); got != "id:agent-7" {
+		t.Errorf("Expected X-Client-Id from a trusted proxy to take precedence, got %q", got)
+	}
+}
+
+func TestHandleRateLimited(t *testing.T) {
+	proxy := &MCPProxy{
+		config:   Config{ServerName: "test"},
+		requests: make(chan *request, 1),
+		limiter:  newRateLimiter(1, 2),
+	}
+	drainRequests(proxy, func(json.RawMessage) json.RawMessage {
+		return json.RawMessage(`{"jsonrpc":"2.0","id":1,"result":{}}`)
+	})
+	defer close(proxy.requests)
+
+	call := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		proxy.Handle(w, httptest.NewRequest("POST", "/", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`)))
+		return w
+	}
+	for i := 0; i < 2; i++ {
+		if w := call(); w.Code != http.StatusOK {
+			t.Fatalf("Request %d: expected 200 within the burst, got %d", i, w.Code)
+		}
+	}
+
+	w := call()
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 once the bucket is empty, got %d", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Expected Retry-After: 1, got %q", got)
+	}
+}
+
+func TestParseToolRateLimits(t *testing.T) {
+	got, err := parseToolRateLimits("search_code=0.5, default=10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got["search_code"] != 0.5 || got["default"] != 10 {
+		t.Errorf("Unexpected limits %v", got)
+	}
+	for _, bad := range []string{"search_code", "search_code=fast", "search_code=0", "=1"} {
+		if _, err := parseToolRateLimits(bad); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
+
+func TestHandleToolRateLimited(t *testing.T) {
+	proxy := &MCPProxy{
+		config:     Config{ServerName: "test"},
+		requests:   make(chan *request, 1),
+		toolLimits: newToolRateLimiter(map[string]float64{"search_code": 1, "default": 2}),
+	}
+	drainRequests(proxy, func(json.RawMessage) json.RawMessage {
+		return json.RawMessage(`{"jsonrpc":"2.0","id":1,"result":{}}`)
+	})
+	defer close(proxy.requests)
+
+	call := func(tool string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		body := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"` + tool + `"}}`
+		proxy.Handle(w, httptest.NewRequest("POST", "/", strings.NewReader(body)))
+		return w
+	}
+
+	if w := call("search_code"); w.Code != http.StatusOK {
+		t.Fatalf("Expected the first search_code call to be allowed, got %d", w.Code)
+	}
+	w := call("search_code")
+	if w.Code != http.StatusTooManyRequests || !strings.Contains(w.Body.String(), `rate limit exceeded for tool \"search_code\"`) {
+		t.Fatalf("Expected 429 once search_code's bucket is empty, got %d: %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("Expected a Retry-After header")
+	}
+
+	// Other tools have their own buckets at the default rate
+	for _, tool := range []string{"get_issue", "get_issue", "list_repos", "list_repos"} {
+		if w := call(tool); w.Code != http.StatusOK {
+			t.Errorf("Expected %s to be allowed, got %d", tool, w.Code)
+		}
+	}
+	if w := call("get_issue"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected get_issue to be limited after its burst, got %d", w.Code)
+	}
+
+	// Other methods aren't limited per tool
+	w = httptest.NewRecorder()
+	proxy.Handle(w, httptest.NewRequest("POST", "/", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected tools/list to be allowed, got %d", w.Code)
+	}
+}
@@ -0,0 +1,174 @@
+package mcpproxy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// readyWait bounds how long /readyz waits for a readiness check in progress.
+const readyWait = 5 * time.Second
+
+// readinessGate runs one readiness check at a time; see checkReadiness.
+type readinessGate struct {
+	mu      sync.Mutex
+	running chan struct{} // closed when the check in progress finishes
+}
+
+// checkReadiness starts a readiness check unless one is in progress, and
+// returns a channel closed when it finishes.
+func (p *MCPProxy) checkReadiness() <-chan struct{} {
+	g := &p.readiness
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.running == nil {
+		done := make(chan struct{})
+		g.running = done
+		go func() {
+			p.probeReadiness()
+			g.mu.Lock()
+			g.running = nil
+			g.mu.Unlock()
+			close(done)
+		}()
+	}
+	return g.running
+}
+
+// probeReadiness sends initialize and tools/list to the MCP server and marks
+// it ready once both return valid results, unless the process was replaced in
+// the meantime.
+func (p *MCPProxy) probeReadiness() {
+	generation := p.generation.Load()
+	if err := p.probe(); err != nil {
+		p.logger.warnf("MCP server is not ready: %v", err)
+		return
+	}
+	if p.generation.Load() == generation && p.health.state.CompareAndSwap(int32(stateStarting), int32(stateReady)) {
+		p.logger.infof("MCP server is ready")
+	}
+}
+
+func (p *MCPProxy) probe() error {
+	if warm := p.warm.Load(); warm != nil {
+		select {
+		case <-warm.done:
+		case <-time.After(readyWait):
+			return errors.New("still warming up")
+		}
+	}
+
+	if err := p.probeInitialize(); err != nil {
+		return fmt.Errorf("initialize: %w", err)
+	}
+	if _, err := p.call(json.RawMessage(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)); err != nil {
+		return fmt.Errorf("notifications/initialized: %w", err)
+	}
+
+	resp, err := p.call(json.RawMessage(`{"jsonrpc":"2.0","id":"mcpproxy-ready-2","method":"tools/list"}`))
+	if err != nil {
+		return fmt.Errorf("tools/list: %w", err)
+	}
+	tools, err := resultField(resp, "tools")
+	if err != nil {
+		return fmt.Errorf("tools/list: %w", err)
+	}
+	var list []json.RawMessage
+	if err := json.Unmarshal(tools, &list); err != nil {
+		return fmt.Errorf("tools/list: tools is not a list")
+	}
+	return nil
+}
+
+// probeInitialize sends the readiness check's initialize, retrying up to
+// Config.ReadyRetries times with a backoff starting at
+// Config.ReadyRetryBackoff and doubling, since a server that is still
+// starting may fail it. It gives up early once the process is replaced or
+// stopped.
+func (p *MCPProxy) probeInitialize() error {
+	generation := p.generation.Load()
+	backoff := p.config.ReadyRetryBackoff
+	for attempt := 0; ; attempt++ {
+		resp, err := p.call(json.RawMessage(`{"jsonrpc":"2.0","id":"mcpproxy-ready-1","method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"mcpproxy","version":"1.0.0"}}}`))
+		if err == nil {
+			_, err = resultField(resp, "protocolVersion")
+		}
+		if err == nil || attempt >= p.config.ReadyRetries || p.generation.Load() != generation {
+			return err
+		}
+
+		p.logger.debugf("Readiness initialize failed, retrying in %s: %v", backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-p.done:
+			return err
+		}
+		backoff *= 2
+	}
+}
+
+// call sends msg to the MCP server through the request queue and returns its
+// response, or nil for a notification.
+func (p *MCPProxy) call(msg json.RawMessage) (json.RawMessage, error) {
+	if p.stopped.Load() {
+		return nil, errStopped
+	}
+	req := &request{
+		msg:       msg,
+		isRequest: hasID(msg),
+		response:  make(chan json.RawMessage, 1),
+	}
+	if err := p.enqueue(req); err != nil {
+		return nil, err
+	}
+
+	response, ok := <-req.response
+	if req.unsent || (req.isRequest && !ok) {
+		return nil, errors.New(p.exitMessage())
+	}
+	return response, nil
+}
+
+// resultField returns the named member of a JSON-RPC response's result.
+func resultField(resp json.RawMessage, name string) (json.RawMessage, error) {
+	var r struct {
+		Result map[string]json.RawMessage `json:"result"`
+		Error  *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(resp, &r); err != nil {
+		return nil, fmt.Errorf("invalid response: %w", err)
+	}
+	if r.Error != nil {
+		return nil, fmt.Errorf("error response: %s", r.Error.Message)
+	}
+	field, ok := r.Result[name]
+	if !ok {
+		return nil, fmt.Errorf("result has no %s", name)
+	}
+	return field, nil
+}
+
+// HandleReady serves /readyz: HTTP 200 once the proxy's own initialize and
+// tools/list requests have succeeded on the current MCP server process, 503
+// before. The first poll after a start or restart runs the check, waiting up
+// to readyWait for it; success is kept until the process is replaced.
+func (p *MCPProxy) HandleReady(w http.ResponseWriter, r *http.Request) {
+	if p.health.get() == stateStarting {
+		select {
+		case <-p.checkReadiness():
+		case <-r.Context().Done():
+		case <-time.After(readyWait):
+		}
+	}
+
+	status := http.StatusOK
+	if p.health.get() != stateReady {
+		status = http.StatusServiceUnavailable
+	}
+	p.writeHealth(w, status)
+}
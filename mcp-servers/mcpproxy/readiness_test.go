@@ -0,0 +1,149 @@
+package mcpproxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// mcpServer answers every request with an empty result, and tools/list with
+// an empty tool list.
+var mcpServer = Config{
+	ServerName:  "test",
+	CommandPath: "sh",
+	CommandArgs: []string{"-c", `
+while read -r line; do
+	case "$line" in *'"id"'*) ;; *) continue ;; esac
+	id=$(printf '%s' "$line" | sed -E 's/.*"id":("[^"]*"|[0-9]+).*/\1/')
+	case "$line" in
+	*'"tools/list"'*) echo "{\"jsonrpc\":\"2.0\",\"id\":$id,\"result\":{\"tools\":[]}}" ;;
+	*'"initialize"'*) echo "{\"jsonrpc\":\"2.0\",\"id\":$id,\"result\":{\"protocolVersion\":\"2025-03-26\"}}" ;;
+	*) echo "{\"jsonrpc\":\"2.0\",\"id\":$id,\"result\":{}}" ;;
+	esac
+done`},
+}
+
+func getReady(t *testing.T, p *MCPProxy) (int, healthBody) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	p.newMux().ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+	var body healthBody
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Invalid /readyz body %q: %v", w.Body.String(), err)
+	}
+	return w.Code, body
+}
+
+func TestReadyAfterCheck(t *testing.T) {
+	proxy, err := NewMCPProxy(mcpServer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+
+	if code, body := getReady(t, proxy); code != http.StatusOK || body.State != "ready" {
+		t.Fatalf("Expected 200 ready once initialize and tools/list succeeded, got %d %+v", code, body)
+	}
+
+	// A restart re-arms the check
+	if err := proxy.restart(); err != nil {
+		t.Fatal(err)
+	}
+	if _, body := getHealth(t, proxy); body.State != "starting" {
+		t.Errorf("Expected starting after a restart, got %+v", body)
+	}
+	if code, _ := getReady(t, proxy); code != http.StatusOK {
+		t.Errorf("Expected the new process to become ready, got %d", code)
+	}
+
+	// Client requests still work after the proxy's own initialize
+	if w := ping(proxy); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"result"`) {
+		t.Errorf("Expected a result for a client request, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestNotReadyWithoutValidResults(t *testing.T) {
+	// cat echoes the requests back, which are not results
+	proxy, err := NewMCPProxy(Config{ServerName: "test", CommandPath: "cat", ReadyRetries: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+
+	for i := 0; i < 2; i++ {
+		if code, body := getReady(t, proxy); code != http.StatusServiceUnavailable || body.State != "starting" {
+			t.Errorf("Poll %d: expected 503 starting, got %d %+v", i, code, body)
+		}
+	}
+	if code, _ := getHealth(t, proxy); code != http.StatusOK {
+		t.Errorf("Expected /healthz to stay 200 while not ready, got %d", code)
+	}
+}
+
+func TestReadyRetriesInitialize(t *testing.T) {
+	// Fails the first initialize, as a server still starting up might
+	slowStart := Config{
+		ServerName:        "test",
+		CommandPath:       "sh",
+		ReadyRetryBackoff: 10 * time.Millisecond,
+		CommandArgs: []string{"-c", `
+n=0
+while read -r line; do
+	case "$line" in *'"id"'*) ;; *) continue ;; esac
+	id=$(printf '%s' "$line" | sed -E 's/.*"id":("[^"]*"|[0-9]+).*/\1/')
+	case "$line" in
+	*'"initialize"'*)
+		n=$((n+1))
+		if [ $n = 1 ]; then
+			echo "{\"jsonrpc\":\"2.0\",\"id\":$id,\"error\":{\"code\":-32603,\"message\":\"not started\"}}"
+		else
+			echo "{\"jsonrpc\":\"2.0\",\"id\":$id,\"result\":{\"protocolVersion\":\"2025-03-26\"}}"
+		fi ;;
+	*'"tools/list"'*) echo "{\"jsonrpc\":\"2.0\",\"id\":$id,\"result\":{\"tools\":[]}}" ;;
+	esac
+done`},
+	}
+
+	proxy, err := NewMCPProxy(slowStart)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+	if code, body := getReady(t, proxy); code != http.StatusOK || body.State != "ready" {
+		t.Errorf("Expected ready once a retried initialize succeeded, got %d %+v", code, body)
+	}
+
+	slowStart.ReadyRetries = -1
+	proxy, err = NewMCPProxy(slowStart)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+	if code, _ := getReady(t, proxy); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected not ready without retries, got %d", code)
+	}
+}
+
+func TestReadyIgnoresReplacedProcess(t *testing.T) {
+	// The process is replaced while the check waits for tools/list
+	var proxy *MCPProxy
+	cfg := mcpServer
+	cfg.ResponseMiddleware = func(msg []byte) []byte {
+		if strings.Contains(string(msg), `"tools"`) {
+			proxy.generation.Add(1)
+		}
+		return msg
+	}
+	proxy, err := NewMCPProxy(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+
+	if code, body := getReady(t, proxy); code != http.StatusServiceUnavailable || body.State != "starting" {
+		t.Errorf("Expected a check of a replaced process not to count, got %d %+v", code, body)
+	}
+}
@@ -0,0 +1,92 @@
+package mcpproxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// recentPayloadBytes caps each redacted request and response kept for
+// /debug/recent, so the ring's memory is bounded whatever flows through.
+const recentPayloadBytes = 16 << 10
+
+// recentEntry is a request and the response it got, as served at /debug/recent.
+type recentEntry struct {
+	Time          string  `json:"time"`
+	CorrelationID string  `json:"correlation_id"`
+	Method        string  `json:"method,omitempty"`
+	HTTPStatus    int     `json:"http_status"`
+	DurationMS    float64 `json:"duration_ms"`
+	Request       string  `json:"request"`
+	Response      string  `json:"response"`
+}
+
+// recentRequests keeps the last requests and responses in a ring.
+type recentRequests struct {
+	mu      sync.Mutex
+	entries []recentEntry
+	next    int
+	full    bool
+}
+
+func newRecentRequests(n int) *recentRequests {
+	return &recentRequests{entries: make([]recentEntry, n)}
+}
+
+func (rr *recentRequests) add(e recentEntry) {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	rr.entries[rr.next] = e
+	rr.next = (rr.next + 1) % len(rr.entries)
+	if rr.next == 0 {
+		rr.full = true
+	}
+}
+
+// list returns the kept entries, oldest first.
+func (rr *recentRequests) list() []recentEntry {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	if !rr.full {
+		return append([]recentEntry{}, rr.entries[:rr.next]...)
+	}
+	return append(append([]recentEntry{}, rr.entries[rr.next:]...), rr.entries[:rr.next]...)
+}
+
+// recordRecent keeps a request answered on w, both masked like logged
+// payloads whatever Config.LogPayloads is.
+func (p *MCPProxy) recordRecent(cid, method string, msg json.RawMessage, w *accessWriter, start time.Time) {
+	status := w.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	response := "" // e.g. a notification's 202
+	if len(w.body) > 0 {
+		response = p.redactForRecent(w.body)
+	}
+	p.recent.add(recentEntry{
+		Time:          start.UTC().Format(time.RFC3339Nano),
+		CorrelationID: cid,
+		Method:        method,
+		HTTPStatus:    status,
+		DurationMS:    float64(time.Since(start).Microseconds()) / 1000,
+		Request:       p.redactForRecent(msg),
+		Response:      response,
+	})
+}
+
+func (p *MCPProxy) redactForRecent(msg []byte) string {
+	s := p.config.redactText(redactPayload(msg, p.config.RedactKeys))
+	if len(s) > recentPayloadBytes {
+		s = fmt.Sprintf("%s… <%d bytes>", s[:recentPayloadBytes], len(s))
+	}
+	return s
+}
+
+// handleRecent serves the kept requests and responses as JSON, oldest first.
+func (p *MCPProxy) handleRecent(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p.recent.list())
+}
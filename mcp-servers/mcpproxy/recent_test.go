@@ -0,0 +1,82 @@
+package mcpproxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRecentRequestsRing(t *testing.T) {
+	rr := newRecentRequests(3)
+	if got := rr.list(); len(got) != 0 {
+		t.Fatalf("Expected no entries, got %v", got)
+	}
+	for i := 0; i < 5; i++ {
+		rr.add(recentEntry{CorrelationID: fmt.Sprint(i)})
+	}
+	var ids []string
+	for _, e := range rr.list() {
+		ids = append(ids, e.CorrelationID)
+	}
+	if strings.Join(ids, ",") != "2,3,4" {
+		t.Errorf("Expected the last 3 entries oldest first, got %v", ids)
+	}
+}
+
+func TestDebugRecent(t *testing.T) {
+	proxy, err := NewMCPProxy(Config{
+		ServerName:     "test",
+		CommandPath:    "cat",
+		AdminToken:     "s3cret",
+		RecentRequests: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+
+	proxy.Handle(httptest.NewRecorder(), httptest.NewRequest("POST", "/", strings.NewReader(
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"login","arguments":{"password":"hunter2"}}}`)))
+	proxy.Handle(httptest.NewRecorder(), httptest.NewRequest("POST", "/", strings.NewReader(
+		`{"jsonrpc":"2.0","id":2,"method":"ping","params":{"pad":"`+strings.Repeat("x", recentPayloadBytes)+`"}}`)))
+
+	req := httptest.NewRequest("GET", "/debug/recent", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	w := httptest.NewRecorder()
+	proxy.newAdminMux().ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", w.Code)
+	}
+	var entries []recentEntry
+	if err := json.Unmarshal(w.Body.Bytes(), &entries); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+
+	call := entries[0]
+	if call.Method != "tools/call" || call.HTTPStatus != http.StatusOK || call.CorrelationID == "" {
+		t.Errorf("Unexpected entry %+v", call)
+	}
+	if strings.Contains(call.Request, "hunter2") || strings.Contains(call.Response, "hunter2") {
+		t.Errorf("Expected the password masked, got %q and %q", call.Request, call.Response)
+	}
+	if !strings.Contains(call.Request, redactedValue) || !strings.Contains(call.Response, redactedValue) {
+		t.Errorf("Expected the request and response kept, got %q and %q", call.Request, call.Response)
+	}
+
+	if big := entries[1]; len(big.Request) > recentPayloadBytes+100 || len(big.Response) > recentPayloadBytes+100 {
+		t.Errorf("Expected large payloads cut, got %d and %d bytes", len(big.Request), len(big.Response))
+	}
+}
+
+func TestRecentRequestsRequireAdminToken(t *testing.T) {
+	_, err := NewMCPProxy(Config{ServerName: "test", CommandPath: "cat", RecentRequests: 10})
+	if err == nil || !strings.Contains(err.Error(), "ADMIN_TOKEN") {
+		t.Errorf("Expected MCP_RECENT_REQUESTS to require ADMIN_TOKEN, got %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package mcpproxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// defaultRedactKeys are the key names whose values are masked in logged payloads.
+// Matching is case-insensitive on the whole name or a "_" or "-" separated suffix,
+// so "token" also covers "access_token" but not "progressToken" or "max_tokens".
+var defaultRedactKeys = []string{"token", "password", "secret", "authorization", "connectString", "apiKey"}
+
+const redactedValue = "[REDACTED]"
+
+// payloadForLog returns the message as it should appear in the logs: a size
+// summary unless LogPayloads is enabled, in which case sensitive values are
+// masked and Config.LogRedactor is applied.
+func (p *MCPProxy) payloadForLog(msg []byte) string {
+	if !p.config.LogPayloads {
+		return fmt.Sprintf("<%d bytes>", len(msg))
+	}
+	return p.config.redactText(redactPayload(msg, p.config.RedactKeys))
+}
+
+// redactText applies the server-specific LogRedactor, if any.
+func (c *Config) redactText(s string) string {
+	if c.LogRedactor == nil {
+		return s
+	}
+	return c.LogRedactor(s)
+}
+
+// redactPayload masks the values of sensitive keys anywhere in a JSON message.
+// Messages that aren't valid JSON are summarized by size rather than logged raw,
+// since there's no reliable way to find secrets in them.
+func redactPayload(msg []byte, keys []string) string {
+	var v interface{}
+	if err := json.Unmarshal(msg, &v); err != nil {
+		return fmt.Sprintf("<%d bytes, not JSON>", len(msg))
+	}
+	out, err := json.Marshal(redactValue(v, keys))
+	if err != nil {
+		return fmt.Sprintf("<%d bytes>", len(msg))
+	}
+	return string(out)
+}
+
+func redactValue(v interface{}, keys []string) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, child := range val {
+			if isSensitiveKey(k, keys) {
+				val[k] = redactedValue
+			} else {
+				val[k] = redactValue(child, keys)
+			}
+		}
+	case []interface{}:
+		for i, child := range val {
+			val[i] = redactValue(child, keys)
+		}
+	}
+	return v
+}
+
+func isSensitiveKey(key string, keys []string) bool {
+	key = strings.ToLower(key)
+	for _, k := range keys {
+		k = strings.ToLower(k)
+		if key == k || strings.HasSuffix(key, "_"+k) || strings.HasSuffix(key, "-"+k) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+package mcpproxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRedactPayload(t *testing.T) {
+	tests := []struct {
+		name     string
+		msg      string
+		contains []string
+		hidden   []string
+	}{
+		{
+			name:     "nested arguments",
+			msg:      `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"connect","arguments":{"user":"scott","password":"tiger"}}}`,
+			contains: []string{`"user":"scott"`, `"password":"[REDACTED]"`},
+			hidden:   []string{"tiger"},
+		},
+		{
+			name:     "suffix and case-insensitive match",
+			msg:      `{"params":{"GITHUB_PERSONAL_ACCESS_TOKEN":"ghp_abc","Authorization":"Bearer xyz"}}`,
+			contains: []string{redactedValue},
+			hidden:   []string{"ghp_abc", "Bearer xyz"},
+		},
+		{
+			name:     "names merely containing a key",
+			msg:      `{"params":{"_meta":{"progressToken":7},"max_tokens":100,"tokenCount":42}}`,
+			contains: []string{`"progressToken":7`, `"max_tokens":100`, `"tokenCount":42`},
+			hidden:   []string{redactedValue},
+		},
+		{
+			name:   "array of objects",
+			msg:    `{"items":[{"connectString":"scott/tiger@db"},{"name":"ok"}]}`,
+			hidden: []string{"scott/tiger@db"},
+		},
+		{
+			name:     "not JSON",
+			msg:      `password=tiger`,
+			contains: []string{"14 bytes, not JSON"},
+			hidden:   []string{"tiger"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := redactPayload([]byte(tt.msg), defaultRedactKeys)
+			for _, s := range tt.contains {
+				if !strings.Contains(got, s) {
+					t.Errorf("Expected %q in %q", s, got)
+				}
+			}
+			for _, s := range tt.hidden {
+				if strings.Contains(got, s) {
+					t.Errorf("Expected %q to be redacted from %q", s, got)
+				}
+			}
+		})
+	}
+}
+
+func TestPayloadForLogGate(t *testing.T) {
+	msg := []byte(`{"token":"ghp_abc"}`)
+
+	proxy := &MCPProxy{config: Config{RedactKeys: defaultRedactKeys}}
+	if got := proxy.payloadForLog(msg); got != "<19 bytes>" {
+		t.Errorf("Expected size summary when LogPayloads is off, got %q", got)
+	}
+
+	proxy.config.LogPayloads = true
+	if got := proxy.payloadForLog(msg); got != `{"token":"[REDACTED]"}` {
+		t.Errorf("Expected redacted payload when LogPayloads is on, got %q", got)
+	}
+}
+
+func TestHandleDoesNotLogSecrets(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	proxy := &MCPProxy{
+		config:   Config{ServerName: "test", LogPayloads: true, RedactKeys: defaultRedactKeys},
+		logger:   newLogger("test", "debug"),
+		requests: make(chan *request, 1),
+	}
+	drainRequests(proxy, func(json.RawMessage) json.RawMessage {
+		return json.RawMessage(`{"jsonrpc":"2.0","id":1,"result":{"token":"s3cr3t-response"}}`)
+	})
+	defer close(proxy.requests)
+
+	body := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"arguments":{"password":"s3cr3t-request"}}}`
+	proxy.Handle(httptest.NewRecorder(), httptest.NewRequest("POST", "/", strings.NewReader(body)))
+
+	if !strings.Contains(buf.String(), "[REDACTED]") {
+		t.Fatalf("Expected redacted payloads in debug logs, got:\n%s", buf.String())
+	}
+	if strings.Contains(buf.String(), "s3cr3t") {
+		t.Errorf("Secret leaked into logs:\n%s", buf.String())
+	}
+}
+
+func TestPayloadForLogAppliesLogRedactor(t *testing.T) {
+	proxy := &MCPProxy{config: Config{
+		LogPayloads: true,
+		RedactKeys:  defaultRedactKeys,
+		LogRedactor: func(s string) string { return strings.ReplaceAll(s, "tiger", "***") },
+	}}
+
+	got := proxy.payloadForLog([]byte(`{"params":{"sql":"CREATE USER scott IDENTIFIED BY tiger"}}`))
+	if strings.Contains(got, "tiger") || !strings.Contains(got, "***") {
+		t.Errorf("Expected LogRedactor to run on logged payloads, got %q", got)
+	}
+}
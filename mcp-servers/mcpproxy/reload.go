@@ -0,0 +1,150 @@
+package mcpproxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
+	"time"
+)
+
+// runtimeConfig holds the settings that can change while the proxy runs.
+// A reload swaps in a new one rather than modifying it.
+type runtimeConfig struct {
+	config Config // the configuration the settings were taken from
+
+	tools         *toolFilter
+	enableCORS    bool
+	strictJSONRPC bool
+	writeTimeout  time.Duration
+}
+
+func newRuntimeConfig(cfg Config) *runtimeConfig {
+	return &runtimeConfig{
+		config:        cfg,
+		tools:         newToolFilter(cfg.AllowedTools, cfg.DeniedTools),
+		enableCORS:    cfg.EnableCORS,
+		strictJSONRPC: cfg.StrictJSONRPC,
+		writeTimeout:  cfg.WriteTimeout,
+	}
+}
+
+// current returns the proxy's runtime settings, falling back to p.config for
+// proxies that weren't created by startProxy.
+func (p *MCPProxy) current() *runtimeConfig {
+	if rc := p.runtime.Load(); rc != nil {
+		return rc
+	}
+	return newRuntimeConfig(p.config)
+}
+
+// reloadResult lists the settings a reload changed, and those that changed
+// but only take effect when the proxy is restarted.
+type reloadResult struct {
+	Reloaded        []string `json:"reloaded"`
+	RestartRequired []string `json:"restart_required"`
+}
+
+// reload re-reads MCP_ENV_FILE and the environment and applies the settings
+// in runtimeConfig and the log level. Variables from the env file only reach
+// the MCP server when its process is restarted.
+func (p *MCPProxy) reload() (reloadResult, error) {
+	res := reloadResult{Reloaded: []string{}, RestartRequired: []string{}}
+
+	if _, err := loadExportedEnvFile(os.Getenv("MCP_ENV_FILE"), p.exported); err != nil {
+		return res, err
+	}
+	// Start from the Config the proxy was given, so that a variable removed
+	// from the environment or env file falls back to its default, and reject
+	// anything startup would have rejected
+	next := p.base
+	next.applyDefaults()
+	if _, errs := next.validate(); len(errs) > 0 {
+		return res, errs.err()
+	}
+	prev := p.current().config
+
+	changed := func(name string, from, to interface{}) bool {
+		if reflect.DeepEqual(from, to) {
+			return false
+		}
+		p.logger.infof("Reload: %s changed from %v to %v", name, from, to)
+		return true
+	}
+
+	for _, s := range []struct {
+		name     string
+		from, to interface{}
+	}{
+		{"MCP_ALLOWED_TOOLS", prev.AllowedTools, next.AllowedTools},
+		{"MCP_DENIED_TOOLS", prev.DeniedTools, next.DeniedTools},
+		{"ENABLE_CORS", prev.EnableCORS, next.EnableCORS},
+		{"STRICT_JSONRPC", prev.StrictJSONRPC, next.StrictJSONRPC},
+		{"MCP_WRITE_TIMEOUT", prev.WriteTimeout, next.WriteTimeout},
+		{"LOG_LEVEL", prev.LogLevel, next.LogLevel},
+	} {
+		if changed(s.name, s.from, s.to) {
+			res.Reloaded = append(res.Reloaded, s.name)
+		}
+	}
+
+	for _, s := range []struct {
+		name     string
+		from, to interface{}
+	}{
+		{"command", p.config.CommandPath, next.CommandPath},
+		{"MCP_ARGS", p.config.CommandArgs, next.CommandArgs},
+		{"MCP_CWD", p.config.WorkDir, next.WorkDir},
+		{"MCP_QUEUE_SIZE", p.config.QueueSize, next.QueueSize},
+		{"listen address", p.config.listenAddr(), next.listenAddr()},
+		{"MCP_MAX_SESSIONS", p.config.MaxSessions, next.MaxSessions},
+		{"MCP_MAX_SESSIONS_PER_CLIENT", p.config.MaxSessionsPerClient, next.MaxSessionsPerClient},
+	} {
+		if changed(s.name, s.from, s.to) {
+			p.logger.warnf("Reload: %s requires a restart to take effect", s.name)
+			res.RestartRequired = append(res.RestartRequired, s.name)
+		}
+	}
+
+	if level, err := parseLogLevel(next.LogLevel); err == nil {
+		p.logger.setLevel(level)
+	}
+	rc := newRuntimeConfig(next)
+	p.runtime.Store(rc)
+	p.sessions.setRuntime(rc)
+	p.instances.setRuntime(rc)
+	return res, nil
+}
+
+// reloadOnSignal reloads the configuration on every SIGHUP.
+func (p *MCPProxy) reloadOnSignal() {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGHUP)
+	for range sig {
+		p.logger.infof("Received SIGHUP, reloading configuration")
+		if _, err := p.reload(); err != nil {
+			p.logger.errorf("Reload failed, keeping the current configuration: %v", err)
+		}
+	}
+}
+
+// handleReload reloads the configuration and reports what changed.
+func (p *MCPProxy) handleReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	p.logger.infof("Reload requested by %s", r.RemoteAddr)
+	res, err := p.reload()
+	if err != nil {
+		p.logger.errorf("Reload failed, keeping the current configuration: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
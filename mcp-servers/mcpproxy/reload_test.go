@@ -0,0 +1,135 @@
+package mcpproxy
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReloadAppliesAllowedTools(t *testing.T) {
+	envFile := filepath.Join(t.TempDir(), "env")
+	if err := os.WriteFile(envFile, []byte("MCP_ALLOWED_TOOLS=read_file,write_file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("MCP_ENV_FILE", envFile)
+	t.Setenv("MCP_ENV_FILE_EXPORT", "true")
+	t.Setenv("MCP_ALLOWED_TOOLS", "")
+
+	proxy, err := NewMCPProxy(Config{ServerName: "test", CommandPath: "cat", AdminToken: "s3cret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+
+	callCode := func() int {
+		w := httptest.NewRecorder()
+		body := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"write_file","arguments":{}}}`
+		proxy.Handle(w, httptest.NewRequest("POST", "/", strings.NewReader(body)))
+		var resp struct {
+			Error struct {
+				Code int `json:"code"`
+			} `json:"error"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return resp.Error.Code
+	}
+	if code := callCode(); code != 0 {
+		t.Fatalf("Expected write_file to be allowed before the reload, got error %d", code)
+	}
+
+	if err := os.WriteFile(envFile, []byte("MCP_ALLOWED_TOOLS=read_file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	w := adminPost(t, proxy.newAdminMux(), "/admin/reload")
+	var res reloadResult
+	json.Unmarshal(w.Body.Bytes(), &res)
+	if len(res.Reloaded) != 1 || res.Reloaded[0] != "MCP_ALLOWED_TOOLS" || len(res.RestartRequired) != 0 {
+		t.Errorf("Expected only MCP_ALLOWED_TOOLS to be reloaded, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if code := callCode(); code != CodeNotAllowed {
+		t.Errorf("Expected write_file to be rejected after the reload, got error %d", code)
+	}
+}
+
+func TestReloadReportsRestartRequired(t *testing.T) {
+	t.Setenv("MCP_ARGS", "")
+	proxy, err := NewMCPProxy(Config{ServerName: "test", CommandPath: "cat"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+
+	t.Setenv("MCP_ARGS", "-u")
+	res, err := proxy.reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.RestartRequired) != 1 || res.RestartRequired[0] != "MCP_ARGS" {
+		t.Errorf("Expected MCP_ARGS to require a restart, got %+v", res)
+	}
+	if len(res.Reloaded) != 0 {
+		t.Errorf("Expected nothing to be reloaded, got %v", res.Reloaded)
+	}
+}
+
+func TestReloadClearsRemovedVariables(t *testing.T) {
+	envFile := filepath.Join(t.TempDir(), "env")
+	if err := os.WriteFile(envFile, []byte("MCP_DENIED_TOOLS=write_file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("MCP_ENV_FILE", envFile)
+	t.Setenv("MCP_ENV_FILE_EXPORT", "true")
+	t.Setenv("MCP_DENIED_TOOLS", "")
+	t.Setenv("MCP_ALLOWED_TOOLS", "read_file,write_file")
+
+	proxy, err := NewMCPProxy(Config{ServerName: "test", CommandPath: "cat"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+
+	if err := os.WriteFile(envFile, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	os.Unsetenv("MCP_ALLOWED_TOOLS")
+	res, err := proxy.reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Reloaded) != 2 {
+		t.Errorf("Expected MCP_ALLOWED_TOOLS and MCP_DENIED_TOOLS to be reloaded, got %v", res.Reloaded)
+	}
+	if v, ok := os.LookupEnv("MCP_DENIED_TOOLS"); !ok || v != "" {
+		t.Errorf("Expected MCP_DENIED_TOOLS to be restored to its previous value, got %q", v)
+	}
+	cfg := proxy.current().config
+	if len(cfg.AllowedTools) != 0 || len(cfg.DeniedTools) != 0 {
+		t.Errorf("Expected the tool filters to be cleared, got allowed %v and denied %v", cfg.AllowedTools, cfg.DeniedTools)
+	}
+	if !proxy.current().tools.allows("write_file") {
+		t.Error("Expected write_file to be allowed after the reload")
+	}
+}
+
+func TestReloadRejectsInvalidSettings(t *testing.T) {
+	t.Setenv("MCP_TOOL_TIMEOUTS", "")
+	t.Setenv("MCP_ALLOWED_TOOLS", "")
+	proxy, err := NewMCPProxy(Config{ServerName: "test", CommandPath: "cat"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+
+	t.Setenv("MCP_TOOL_TIMEOUTS", "run_sql=forever")
+	t.Setenv("MCP_ALLOWED_TOOLS", "read_file")
+	if _, err := proxy.reload(); err == nil || !strings.Contains(err.Error(), "MCP_TOOL_TIMEOUTS") {
+		t.Errorf("Expected the reload to be rejected for MCP_TOOL_TIMEOUTS, got %v", err)
+	}
+	if cfg := proxy.current().config; len(cfg.AllowedTools) != 0 {
+		t.Errorf("Expected the rejected reload to change nothing, got allowed tools %v", cfg.AllowedTools)
+	}
+}
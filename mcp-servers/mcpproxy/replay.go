@@ -0,0 +1,55 @@
+package mcpproxy
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// replayID is the id of the initialize request replayed to a restarted MCP
+// server, so its response is never mistaken for a client's.
+var replayID = json.RawMessage(`"mcpproxy-replay"`)
+
+// rememberInitialize keeps msg if it is an initialize request the MCP server
+// answered without an error, for replayInitialize. p.io must be held.
+func (p *MCPProxy) rememberInitialize(msg, response json.RawMessage) {
+	if !bytes.Contains(msg, []byte(`"initialize"`)) {
+		return
+	}
+	var m MCPMessage
+	if json.Unmarshal(msg, &m) != nil || m.Method != "initialize" {
+		return
+	}
+	if outcome, _, _ := responseOutcome(response); outcome == "error" {
+		return
+	}
+	p.initialize = msg
+}
+
+// replayInitialize sends the last initialize request the previous process
+// answered, from a client or the readiness check, to its replacement, followed
+// by notifications/initialized, so clients that initialized before a restart
+// can carry on without doing it again. p.io must be held.
+func (p *MCPProxy) replayInitialize() {
+	if p.initialize == nil {
+		return
+	}
+	msg := withID(p.initialize, replayID)
+	if err := p.writeMessage(msg); err != nil {
+		p.logger.warnf("Failed to replay initialize to the restarted MCP server: %v", err)
+		return
+	}
+	response, err := p.readResponse(msg, "")
+	if err != nil {
+		p.logger.warnf("Failed to replay initialize to the restarted MCP server: %v", err)
+		return
+	}
+	if outcome, _, message := responseOutcome(response); outcome == "error" {
+		p.logger.warnf("Restarted MCP server rejected the replayed initialize: %s", message)
+		return
+	}
+	if err := p.writeMessage(json.RawMessage(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)); err != nil {
+		p.logger.warnf("Failed to replay notifications/initialized to the restarted MCP server: %v", err)
+		return
+	}
+	p.logger.infof("Replayed initialize to the restarted MCP server")
+}
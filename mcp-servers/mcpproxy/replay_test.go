@@ -0,0 +1,69 @@
+package mcpproxy
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// statefulServer answers tools/call with an error until it has been sent
+// initialize, like a server that keeps per-connection state.
+var statefulServer = Config{
+	ServerName:  "test",
+	CommandPath: "sh",
+	CommandArgs: []string{"-c", `init=0
+while read -r line; do
+  case "$line" in
+    *'"method":"initialize"'*) init=1; echo '{"jsonrpc":"2.0","id":1,"result":{"protocolVersion":"2025-03-26"}}' ;;
+    *'"method":"notifications/'*) ;;
+    *) if [ $init = 1 ]; then echo '{"jsonrpc":"2.0","id":2,"result":{"content":[]}}'; else echo '{"jsonrpc":"2.0","id":2,"error":{"code":-32002,"message":"not initialized"}}'; fi ;;
+  esac
+done`},
+}
+
+func TestReplayInitializeAfterRestart(t *testing.T) {
+	proxy, err := NewMCPProxy(statefulServer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+
+	post := func(body string) string {
+		w := httptest.NewRecorder()
+		proxy.Handle(w, httptest.NewRequest("POST", "/", strings.NewReader(body)))
+		return w.Body.String()
+	}
+	callTool := `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"x"}}`
+
+	if got := post(callTool); !strings.Contains(got, "not initialized") {
+		t.Fatalf("Expected the server to require initialize, got %s", got)
+	}
+	post(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`)
+	post(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+	if got := post(callTool); strings.Contains(got, "error") {
+		t.Fatalf("Expected tools/call to succeed once initialized, got %s", got)
+	}
+
+	pid := proxy.pid()
+	if err := proxy.restart(); err != nil {
+		t.Fatal(err)
+	}
+	if proxy.pid() == pid {
+		t.Fatal("Expected a new process")
+	}
+	if got := post(callTool); strings.Contains(got, "error") {
+		t.Errorf("Expected tools/call to succeed after the restart without initializing again, got %s", got)
+	}
+}
+
+func TestReplayInitializeSkipsRejected(t *testing.T) {
+	proxy := &MCPProxy{}
+	proxy.rememberInitialize([]byte(`{"jsonrpc":"2.0","id":1,"method":"initialize"}`), []byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"bad version"}}`))
+	if proxy.initialize != nil {
+		t.Error("Expected a rejected initialize not to be replayed")
+	}
+	proxy.rememberInitialize([]byte(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"initialize"}}`), []byte(`{"jsonrpc":"2.0","id":1,"result":{}}`))
+	if proxy.initialize != nil {
+		t.Error("Expected only initialize requests to be replayed")
+	}
+}
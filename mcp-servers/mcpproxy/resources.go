@@ -0,0 +1,177 @@
+package mcpproxy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// clockTicks is the unit of the CPU times in /proc/<pid>/stat. It is 100
+// (USER_HZ) on every Linux architecture Go supports, and there is no sysconf
+// in the standard library to ask.
+const clockTicks = 100
+
+// resourceSampler periodically reads the MCP server process's CPU and memory
+// use from /proc, for Config.EnableMetrics and Config.MaxRSSBytes. It only
+// runs on Linux.
+type resourceSampler struct {
+	cpu atomic.Uint64 // float64 bits: CPU cores used over the last interval
+	rss atomic.Uint64 // resident memory in bytes
+
+	memoryRestarts atomic.Int64
+	overSince      time.Time // when rss went over Config.MaxRSSBytes; only used by run
+}
+
+// procStats is a process's cumulative CPU time and resident memory.
+type procStats struct {
+	cpu time.Duration
+	rss uint64
+}
+
+// startResourceSampler starts sampling the MCP server process every
+// interval, or returns nil where /proc isn't available.
+func (p *MCPProxy) startResourceSampler(interval time.Duration) *resourceSampler {
+	if runtime.GOOS != "linux" {
+		p.logger.infof("Not sampling MCP server resource usage: only supported on Linux")
+		return nil
+	}
+	s := &resourceSampler{}
+	go s.run(p, interval)
+	return s
+}
+
+func (s *resourceSampler) run(p *MCPProxy, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var lastPID int
+	var last procStats
+	var lastTime time.Time
+	for ; !p.stopped.Load(); <-ticker.C {
+		pid := int(p.procPID.Load())
+		stats, err := readProcStats(pid)
+		now := time.Now()
+		if err != nil {
+			// The process exited and hasn't been replaced yet
+			p.logger.debugf("Failed to sample MCP server resource usage: %v", err)
+			s.cpu.Store(0)
+			s.rss.Store(0)
+			lastPID = 0
+			continue
+		}
+		s.rss.Store(stats.rss)
+		if s.overLimit(p, stats.rss, now) {
+			s.restartForMemory(p, stats.rss)
+			lastPID = 0
+			continue
+		}
+		if pid == lastPID {
+			used := (stats.cpu - last.cpu).Seconds() / now.Sub(lastTime).Seconds()
+			s.cpu.Store(math.Float64bits(math.Max(used, 0)))
+		} else {
+			// A new process has no earlier sample to compare with
+			s.cpu.Store(0)
+		}
+		lastPID, last, lastTime = pid, stats, now
+	}
+}
+
+// overLimit reports whether the process has used more than
+// Config.MaxRSSBytes for Config.MaxRSSWindow, so a short spike doesn't
+// restart it.
+func (s *resourceSampler) overLimit(p *MCPProxy, rss uint64, now time.Time) bool {
+	limit := p.config.MaxRSSBytes
+	if limit <= 0 || rss <= uint64(limit) {
+		s.overSince = time.Time{}
+		return false
+	}
+	if s.overSince.IsZero() {
+		p.logger.warnf("MCP server is using %d bytes of memory, over MCP_MAX_RSS_BYTES=%d", rss, limit)
+		s.overSince = now
+	}
+	return now.Sub(s.overSince) >= p.config.MaxRSSWindow
+}
+
+// restartForMemory restarts the MCP server once the request in progress
+// completes; queued requests wait for the new process.
+func (s *resourceSampler) restartForMemory(p *MCPProxy, rss uint64) {
+	s.overSince = time.Time{}
+	p.logger.warnf("Restarting MCP server: %d bytes of memory for over %s", rss, p.config.MaxRSSWindow)
+	err := p.restart()
+	s.memoryRestarts.Add(1)
+	if err != nil && !errors.Is(err, errStopped) {
+		p.logger.errorf("Restarting MCP server failed: %v", err)
+	}
+}
+
+func (s *resourceSampler) cpuUsage() float64 {
+	return math.Float64frombits(s.cpu.Load())
+}
+
+func (s *resourceSampler) residentBytes() float64 {
+	return float64(s.rss.Load())
+}
+
+// readProcStats reads the CPU time of pid from /proc/<pid>/stat and its
+// resident memory from /proc/<pid>/statm.
+func readProcStats(pid int) (procStats, error) {
+	if pid <= 0 {
+		return procStats{}, fmt.Errorf("no process")
+	}
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return procStats{}, err
+	}
+	cpu, err := parseProcStat(stat)
+	if err != nil {
+		return procStats{}, err
+	}
+	statm, err := os.ReadFile(fmt.Sprintf("/proc/%d/statm", pid))
+	if err != nil {
+		return procStats{}, err
+	}
+	pages, err := parseProcStatm(statm)
+	if err != nil {
+		return procStats{}, err
+	}
+	return procStats{cpu: cpu, rss: pages * uint64(os.Getpagesize())}, nil
+}
+
+// parseProcStat returns the user plus system CPU time in a /proc/<pid>/stat
+// line. The command name in parentheses may contain spaces, so fields are
+// counted from the last ')'.
+func parseProcStat(stat []byte) (time.Duration, error) {
+	i := bytes.LastIndexByte(stat, ')')
+	if i < 0 {
+		return 0, fmt.Errorf("malformed /proc stat")
+	}
+	// Fields after the name start at field 3 (state); utime and stime are
+	// fields 14 and 15
+	fields := strings.Fields(string(stat[i+1:]))
+	if len(fields) < 13 {
+		return 0, fmt.Errorf("malformed /proc stat")
+	}
+	utime, err1 := strconv.ParseUint(fields[11], 10, 64)
+	stime, err2 := strconv.ParseUint(fields[12], 10, 64)
+	if err1 != nil || err2 != nil {
+		return 0, fmt.Errorf("malformed /proc stat")
+	}
+	return time.Duration(utime+stime) * time.Second / clockTicks, nil
+}
+
+// parseProcStatm returns the resident set size in pages from a
+// /proc/<pid>/statm line.
+func parseProcStatm(statm []byte) (uint64, error) {
+	fields := strings.Fields(string(statm))
+	if len(fields) < 2 {
+		return 0, fmt.Errorf("malformed /proc statm")
+	}
+	return strconv.ParseUint(fields[1], 10, 64)
+}
@@ -0,0 +1,121 @@
+package mcpproxy
+
+import (
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseProcStat(t *testing.T) {
+	// The command name may contain spaces and parentheses
+	stat := []byte("4242 (java (sqlcl) x) S 1 4242 4242 0 -1 4194560 1000 0 0 0 250 50 0 0 20 0 30 0 100 1000000 5000 18446744073709551615\n")
+	cpu, err := parseProcStat(stat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cpu != 3*time.Second {
+		t.Errorf("Expected 300 ticks of CPU to be 3s, got %s", cpu)
+	}
+	if _, err := parseProcStat([]byte("4242 (java) S 1")); err == nil {
+		t.Error("Expected an error for a truncated line")
+	}
+
+	pages, err := parseProcStatm([]byte("250000 1200 300 10 0 9000 0\n"))
+	if err != nil || pages != 1200 {
+		t.Errorf("Expected 1200 resident pages, got %d, %v", pages, err)
+	}
+}
+
+func TestResourceMetrics(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("resource sampling reads /proc")
+	}
+	cfg := slowEchoServer
+	cfg.EnableMetrics = true
+	cfg.ResourceSampleInterval = 10 * time.Millisecond
+	proxy, err := NewMCPProxy(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for proxy.resources.residentBytes() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if rss := proxy.resources.residentBytes(); rss < float64(os.Getpagesize()) {
+		t.Errorf("Expected the process's resident memory, got %g", rss)
+	}
+
+	w := httptest.NewRecorder()
+	defaultRegistry.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	for _, want := range []string{"# TYPE mcp_process_cpu_usage gauge", "# TYPE mcp_process_resident_memory_bytes gauge"} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("Expected %q, got:\n%s", want, w.Body.String())
+		}
+	}
+}
+
+func TestResourceSamplerDisabledByDefault(t *testing.T) {
+	proxy, err := NewMCPProxy(slowEchoServer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+	if proxy.resources != nil {
+		t.Error("Expected no resource sampler without EnableMetrics")
+	}
+}
+
+func TestOverLimitNeedsSustainedUsage(t *testing.T) {
+	p := &MCPProxy{config: Config{MaxRSSBytes: 1000, MaxRSSWindow: time.Minute}}
+	s := &resourceSampler{}
+	now := time.Now()
+
+	if s.overLimit(p, 2000, now) {
+		t.Error("Expected no restart on the first sample over the limit")
+	}
+	if s.overLimit(p, 500, now.Add(30*time.Second)) {
+		t.Error("Expected no restart under the limit")
+	}
+	// The spike ended, so the window starts again
+	if s.overLimit(p, 2000, now.Add(40*time.Second)) || s.overLimit(p, 2000, now.Add(90*time.Second)) {
+		t.Error("Expected no restart before the window has passed")
+	}
+	if !s.overLimit(p, 2000, now.Add(100*time.Second)) {
+		t.Error("Expected a restart after a minute over the limit")
+	}
+}
+
+func TestRestartOverMemoryLimit(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("resource sampling reads /proc")
+	}
+	cfg := slowEchoServer
+	cfg.MaxRSSBytes = 1
+	cfg.MaxRSSWindow = 20 * time.Millisecond
+	cfg.ResourceSampleInterval = 10 * time.Millisecond
+	proxy, err := NewMCPProxy(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+	firstPID := proxy.pid()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for proxy.resources.memoryRestarts.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if proxy.resources.memoryRestarts.Load() == 0 {
+		t.Fatal("Expected a restart for exceeding the memory limit")
+	}
+	if proxy.pid() == firstPID {
+		t.Error("Expected a new process")
+	}
+	if w := ping(proxy); w.Code != 200 {
+		t.Errorf("Expected the new process to answer, got %d", w.Code)
+	}
+}
@@ -0,0 +1,51 @@
+package mcpproxy
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+)
+
+// runAsIDs returns the uid and gid the MCP server runs as, from
+// Config.RunAsUID and Config.RunAsGID, which may be ids or names. An unset
+// gid is the user's primary group and an unset uid the proxy's own. ok is
+// false if neither is set. Both must exist, and changing them requires the
+// proxy to run as root.
+func runAsIDs(uid, gid string) (u, g uint32, ok bool, err error) {
+	if uid == "" && gid == "" {
+		return 0, 0, false, nil
+	}
+	if !canRunAs {
+		return 0, 0, false, fmt.Errorf("running the MCP server as another user isn't supported on this platform")
+	}
+
+	u, g = uint32(os.Getuid()), uint32(os.Getgid())
+	if uid != "" {
+		usr, err := user.LookupId(uid)
+		if err != nil {
+			if usr, err = user.Lookup(uid); err != nil {
+				return 0, 0, false, fmt.Errorf("user %q: %w", uid, err)
+			}
+		}
+		n, _ := strconv.ParseUint(usr.Uid, 10, 32)
+		u = uint32(n)
+		n, _ = strconv.ParseUint(usr.Gid, 10, 32)
+		g = uint32(n)
+	}
+	if gid != "" {
+		grp, err := user.LookupGroupId(gid)
+		if err != nil {
+			if grp, err = user.LookupGroup(gid); err != nil {
+				return 0, 0, false, fmt.Errorf("group %q: %w", gid, err)
+			}
+		}
+		n, _ := strconv.ParseUint(grp.Gid, 10, 32)
+		g = uint32(n)
+	}
+
+	if os.Geteuid() != 0 && (u != uint32(os.Getuid()) || g != uint32(os.Getgid())) {
+		return 0, 0, false, fmt.Errorf("the proxy must run as root to start the MCP server as uid %d, gid %d", u, g)
+	}
+	return u, g, true, nil
+}
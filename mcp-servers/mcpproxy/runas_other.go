@@ -0,0 +1,11 @@
+//go:build !unix
+
+package mcpproxy
+
+import "os/exec"
+
+// canRunAs reports that processes can't be started as another user.
+const canRunAs = false
+
+// setRunAs is never called, since runAsIDs fails without canRunAs.
+func setRunAs(cmd *exec.Cmd, uid, gid uint32) {}
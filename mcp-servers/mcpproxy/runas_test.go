@@ -0,0 +1,67 @@
+package mcpproxy
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRunAsIDs(t *testing.T) {
+	if _, _, ok, err := runAsIDs("", ""); ok || err != nil {
+		t.Errorf("Expected nothing to change without a uid or gid, got %v, %v", ok, err)
+	}
+	if _, _, _, err := runAsIDs("no-such-user", ""); err == nil {
+		t.Error("Expected an error for a user that doesn't exist")
+	}
+	if _, _, _, err := runAsIDs("", "no-such-group"); err == nil {
+		t.Error("Expected an error for a group that doesn't exist")
+	}
+	if os.Geteuid() != 0 {
+		if _, _, _, err := runAsIDs("0", ""); err == nil || !strings.Contains(err.Error(), "must run as root") {
+			t.Errorf("Expected an error for switching user without root, got %v", err)
+		}
+		return
+	}
+	u, g, ok, err := runAsIDs("nobody", "")
+	if err != nil {
+		t.Skipf("No nobody user: %v", err)
+	}
+	if !ok || u == 0 || g == 0 {
+		t.Errorf("Expected nobody's uid and primary gid, got %d, %d, %v", u, g, ok)
+	}
+	if _, g2, _, err := runAsIDs("nobody", "0"); err != nil || g2 != 0 {
+		t.Errorf("Expected the gid to override the primary group, got %d, %v", g2, err)
+	}
+}
+
+func TestNewMCPProxyRejectsUnknownRunAs(t *testing.T) {
+	_, err := NewMCPProxy(Config{ServerName: "test", CommandPath: "cat", RunAsUID: "no-such-user"})
+	if err == nil || !strings.Contains(err.Error(), "MCP_RUN_AS_UID") {
+		t.Errorf("Expected an invalid MCP_RUN_AS_UID error, got %v", err)
+	}
+}
+
+func TestRunAs(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("Switching user requires root")
+	}
+	proxy, err := NewMCPProxy(Config{
+		ServerName:  "test",
+		CommandPath: "sh",
+		CommandArgs: []string{"-c", `read line; echo "{\"jsonrpc\":\"2.0\",\"id\":1,\"result\":\"$(id -u):$(id -g)\"}"`},
+		WorkDir:     "/",
+		RunAsUID:    "nobody",
+	})
+	if err != nil {
+		t.Skipf("No nobody user: %v", err)
+	}
+	defer proxy.stop()
+
+	u, g, _, _ := runAsIDs("nobody", "")
+	want := fmt.Sprintf(`"result":"%d:%d"`, u, g)
+	if w := ping(proxy); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), want) {
+		t.Errorf("Expected the MCP server to run as %s, got %d: %s", want, w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,19 @@
+//go:build unix
+
+package mcpproxy
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// canRunAs reports that processes can be started as another user.
+const canRunAs = true
+
+// setRunAs makes cmd run as uid and gid, without supplementary groups.
+func setRunAs(cmd *exec.Cmd, uid, gid uint32) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Credential = &syscall.Credential{Uid: uid, Gid: gid, Groups: []uint32{}}
+}
@@ -0,0 +1,45 @@
+package mcpproxy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// maxCPUs bounds MCP_CPU_AFFINITY, as the size of glibc's cpu_set_t does.
+const maxCPUs = 1024
+
+// parseCPUList parses a list of CPUs and ranges such as "0-3,6", as taken by
+// taskset -c.
+func parseCPUList(s string) ([]int, error) {
+	var cpus []int
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		from, to, isRange := strings.Cut(part, "-")
+		first, err := strconv.Atoi(from)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CPU %q", part)
+		}
+		last := first
+		if isRange {
+			if last, err = strconv.Atoi(to); err != nil || last < first {
+				return nil, fmt.Errorf("invalid CPU range %q", part)
+			}
+		}
+		if first < 0 || last >= maxCPUs {
+			return nil, fmt.Errorf("CPU %q out of range 0-%d", part, maxCPUs-1)
+		}
+		for cpu := first; cpu <= last; cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus, nil
+}
+
+// validateNice checks an MCP_NICE value.
+func validateNice(nice int) error {
+	if nice < -20 || nice > 19 {
+		return fmt.Errorf("invalid MCP_NICE %d: must be between -20 and 19", nice)
+	}
+	return nil
+}
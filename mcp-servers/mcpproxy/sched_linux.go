@@ -0,0 +1,55 @@
+package mcpproxy
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+// startScheduled starts cmd with Config.Nice and Config.CPUAffinity. On
+// Linux both belong to a thread and a child inherits them from the thread
+// that forks it, so cmd is started from a thread set up for it and then
+// discarded, rather than adjusting the process once it runs and may already
+// have started threads of its own. The proxy's other threads are unaffected.
+func startScheduled(cmd *exec.Cmd, cfg Config, lg *logger) error {
+	if cfg.Nice == 0 && cfg.CPUAffinity == "" {
+		return cmd.Start()
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		// Never unlocked, so the thread exits along with this goroutine
+		runtime.LockOSThread()
+		if cfg.Nice != 0 {
+			if err := syscall.Setpriority(syscall.PRIO_PROCESS, syscall.Gettid(), cfg.Nice); err != nil {
+				errc <- fmt.Errorf("failed to set MCP_NICE=%d: %w", cfg.Nice, err)
+				return
+			}
+		}
+		if cfg.CPUAffinity != "" {
+			// Validated by NewMCPProxy
+			cpus, _ := parseCPUList(cfg.CPUAffinity)
+			if err := setAffinity(cpus); err != nil {
+				errc <- fmt.Errorf("failed to set MCP_CPU_AFFINITY=%s: %w", cfg.CPUAffinity, err)
+				return
+			}
+		}
+		errc <- cmd.Start()
+	}()
+	return <-errc
+}
+
+// setAffinity restricts the calling thread to cpus.
+func setAffinity(cpus []int) error {
+	var mask [maxCPUs / 64]uint64
+	for _, cpu := range cpus {
+		mask[cpu/64] |= 1 << (cpu % 64)
+	}
+	_, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_SETAFFINITY, 0, unsafe.Sizeof(mask), uintptr(unsafe.Pointer(&mask)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package mcpproxy
+
+import "os/exec"
+
+// startScheduled starts cmd, ignoring Config.Nice and Config.CPUAffinity,
+// which are only supported on Linux.
+func startScheduled(cmd *exec.Cmd, cfg Config, lg *logger) error {
+	if cfg.Nice != 0 || cfg.CPUAffinity != "" {
+		lg.warnf("Ignoring MCP_NICE and MCP_CPU_AFFINITY, which are only supported on Linux")
+	}
+	return cmd.Start()
+}
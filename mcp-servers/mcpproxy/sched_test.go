@@ -0,0 +1,59 @@
+package mcpproxy
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestParseCPUList(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want string
+		err  bool
+	}{
+		{in: "0", want: "[0]"},
+		{in: "0-3,6", want: "[0 1 2 3 6]"},
+		{in: " 2 , 4-5", want: "[2 4 5]"},
+		{in: "", err: true},
+		{in: "a", err: true},
+		{in: "3-1", err: true},
+		{in: "-1", err: true},
+		{in: "1024", err: true},
+	} {
+		got, err := parseCPUList(tc.in)
+		if (err != nil) != tc.err || (!tc.err && fmt.Sprint(got) != tc.want) {
+			t.Errorf("parseCPUList(%q) = %v, %v", tc.in, got, err)
+		}
+	}
+}
+
+func TestInvalidNice(t *testing.T) {
+	_, err := NewMCPProxy(Config{ServerName: "test", CommandPath: "cat", Nice: 20, CPUAffinity: "x"})
+	if err == nil || !strings.Contains(err.Error(), "MCP_NICE") || !strings.Contains(err.Error(), "MCP_CPU_AFFINITY") {
+		t.Errorf("Expected invalid MCP_NICE and MCP_CPU_AFFINITY errors, got %v", err)
+	}
+}
+
+func TestNiceAndCPUAffinity(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("MCP_NICE and MCP_CPU_AFFINITY are Linux only")
+	}
+	proxy, err := NewMCPProxy(Config{
+		ServerName:  "test",
+		CommandPath: "sh",
+		CommandArgs: []string{"-c", `read line; echo "{\"jsonrpc\":\"2.0\",\"id\":1,\"result\":\"$(cut -d' ' -f19 /proc/$$/stat) $(grep Cpus_allowed_list /proc/$$/status | cut -f2)\"}"`},
+		Nice:        7,
+		CPUAffinity: "0",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+
+	if w := ping(proxy); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"result":"7 0"`) {
+		t.Errorf("Expected the MCP server at nice 7 on CPU 0, got %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,260 @@
+package mcpproxy
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// errTooManySessions is returned when every session slot is busy.
+var errTooManySessions = errors.New("too many active sessions")
+
+// sessionPool runs a dedicated MCP server process per session key, for
+// servers whose identity (e.g. an API token) is fixed by their environment.
+// Idle sessions are stopped after Config.SessionIdleTimeout.
+type sessionPool struct {
+	parent *MCPProxy
+
+	mu       sync.Mutex
+	sessions map[string]*session
+
+	quit chan struct{} // closed by stopAll to end reapIdle
+	done chan struct{} // closed once reapIdle has returned
+}
+
+type session struct {
+	proxy    *MCPProxy     // nil until started is closed
+	started  chan struct{} // closed once the process started, or failed to with err
+	err      error
+	client   string // the address of the client the session was started for
+	inflight int
+	lastUsed time.Time
+
+	// retired sessions have been removed from the pool and are stopped once
+	// their last request completes.
+	retired bool
+}
+
+func newSessionPool(parent *MCPProxy) *sessionPool {
+	pool := &sessionPool{
+		parent:   parent,
+		sessions: make(map[string]*session),
+		quit:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go pool.reapIdle()
+	return pool
+}
+
+// sessionFor returns the proxy that should serve r: a session process when
+// Config.SessionFunc assigns r to a session, the least busy instance when
+// running several, or p itself otherwise. The returned release func must be
+// called once the request has completed.
+//
+// A session process started for r inherits r's trace context as TRACEPARENT
+// and TRACESTATE, since stdio has no way to carry headers per message.
+func (p *MCPProxy) sessionFor(r *http.Request) (*MCPProxy, func(), error) {
+	if p.instances != nil {
+		return p.instances.acquire()
+	}
+	if p.sessions == nil {
+		return p, func() {}, nil
+	}
+	key, env := p.config.SessionFunc(r)
+	if key == "" {
+		return p, func() {}, nil
+	}
+	client, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		client = r.RemoteAddr
+	}
+	return p.sessions.acquire(key, client, append(env, traceEnv(r)...))
+}
+
+// acquire returns the session process for key, starting one for client if
+// there is none. With Config.MaxSessionsPerClient, a client at the limit
+// replaces its own least recently used idle session; the pool at
+// Config.MaxSessions replaces anyone's. The process is started without
+// holding sp.mu, so a slow start doesn't hold up other sessions.
+func (sp *sessionPool) acquire(key, client string, env []string) (*MCPProxy, func(), error) {
+	sp.mu.Lock()
+	s := sp.sessions[key]
+	if s != nil && s.proxy != nil && s.proxy.exited.Load() {
+		// Replace a session process that has died
+		sp.parent.logger.warnf("Session process (PID: %d) exited, restarting it", s.proxy.cmd.Process.Pid)
+		sp.retire(key, s)
+		s = nil
+	}
+	starting := s == nil
+	if starting {
+		if max := sp.parent.config.MaxSessionsPerClient; max > 0 && sp.count(client) >= max && !sp.evictOldestIdle(client) {
+			sp.mu.Unlock()
+			return nil, nil, errTooManySessions
+		}
+		if len(sp.sessions) >= sp.parent.config.MaxSessions && !sp.evictOldestIdle("") {
+			sp.mu.Unlock()
+			return nil, nil, errTooManySessions
+		}
+		s = &session{started: make(chan struct{}), client: client}
+		sp.sessions[key] = s
+	}
+	s.inflight++
+	s.lastUsed = time.Now()
+	sp.mu.Unlock()
+
+	if starting {
+		sp.start(key, s, env)
+	}
+	<-s.started
+	if s.err != nil {
+		sp.release(s)
+		return nil, nil, s.err
+	}
+	return s.proxy, func() { sp.release(s) }, nil
+}
+
+// start starts the process of the new session s for key, whose slot acquire
+// has already taken, and closes s.started.
+func (sp *sessionPool) start(key string, s *session, env []string) {
+	defer close(s.started)
+
+	cfg := sp.parent.config
+	cfg.ExtraEnv = append(append([]string(nil), cfg.ExtraEnv...), env...)
+	proxy, err := startProxy(cfg, sp.parent.logger)
+
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	if err != nil {
+		s.err = err
+		if sp.sessions[key] == s {
+			delete(sp.sessions, key)
+		}
+		return
+	}
+	proxy.runtime.Store(sp.parent.current())
+	s.proxy = proxy
+	sp.parent.logger.infof("Started session process (%d active)", len(sp.sessions))
+}
+
+// release ends a request's use of s, stopping its process if s was retired
+// and this was the last request.
+func (sp *sessionPool) release(s *session) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	s.inflight--
+	s.lastUsed = time.Now()
+	if s.retired && s.inflight == 0 && s.proxy != nil {
+		go s.proxy.stop()
+	}
+}
+
+// retireAll removes every session from the pool, so the next request for a
+// key starts a new process. Idle sessions are stopped now and busy ones once
+// their requests complete.
+func (sp *sessionPool) retireAll() {
+	if sp == nil {
+		return
+	}
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	for key, s := range sp.sessions {
+		sp.retire(key, s)
+	}
+}
+
+// stopAll ends the idle reaper and retires every session, for when the parent
+// proxy stops. A nil sp is ignored.
+func (sp *sessionPool) stopAll() {
+	if sp == nil {
+		return
+	}
+	close(sp.quit)
+	<-sp.done
+	sp.retireAll()
+}
+
+// setRuntime applies reloaded settings to every session process.
+func (sp *sessionPool) setRuntime(rc *runtimeConfig) {
+	if sp == nil {
+		return
+	}
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	for _, s := range sp.sessions {
+		// Sessions still starting take the parent's settings once started
+		if s.proxy != nil {
+			s.proxy.runtime.Store(rc)
+		}
+	}
+}
+
+// retire removes a session from the pool and stops its process once no
+// requests are using it. sp.mu must be held.
+func (sp *sessionPool) retire(key string, s *session) {
+	delete(sp.sessions, key)
+	if s.inflight == 0 {
+		go s.proxy.stop()
+	} else {
+		s.retired = true
+	}
+}
+
+// count returns the number of sessions started for client. sp.mu must be held.
+func (sp *sessionPool) count(client string) int {
+	n := 0
+	for _, s := range sp.sessions {
+		if s.client == client {
+			n++
+		}
+	}
+	return n
+}
+
+// evictOldestIdle stops the least recently used session that has no requests
+// in flight, among client's sessions or, if client is "", all of them. It
+// reports whether a session was evicted. sp.mu must be held.
+func (sp *sessionPool) evictOldestIdle(client string) bool {
+	var oldestKey string
+	var oldest *session
+	for key, s := range sp.sessions {
+		if client != "" && s.client != client {
+			continue
+		}
+		if s.inflight == 0 && (oldest == nil || s.lastUsed.Before(oldest.lastUsed)) {
+			oldestKey, oldest = key, s
+		}
+	}
+	if oldest == nil {
+		return false
+	}
+	delete(sp.sessions, oldestKey)
+	go oldest.proxy.stop()
+	return true
+}
+
+// reapIdle periodically stops sessions that have been idle for longer than
+// Config.SessionIdleTimeout, until stopAll is called.
+func (sp *sessionPool) reapIdle() {
+	defer close(sp.done)
+	timeout := sp.parent.config.SessionIdleTimeout
+	ticker := time.NewTicker(timeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-sp.quit:
+			return
+		case <-ticker.C:
+		}
+		sp.mu.Lock()
+		for key, s := range sp.sessions {
+			if s.inflight == 0 && time.Since(s.lastUsed) > timeout {
+				delete(sp.sessions, key)
+				go s.proxy.stop()
+			}
+		}
+		sp.mu.Unlock()
+	}
+}
@@ -0,0 +1,274 @@
+package mcpproxy
+
+import (
+	"bufio"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// tokenEchoServer is a shell MCP server that answers every request with the
+// value of $TOKEN from its environment.
+var tokenEchoServer = Config{
+	ServerName:  "test",
+	CommandPath: "sh",
+	CommandArgs: []string{"-c", `while read line; do echo "{\"jsonrpc\":\"2.0\",\"id\":1,\"result\":{\"token\":\"$TOKEN\"}}"; done`},
+	ExtraEnv:    []string{"TOKEN=ambient"},
+}
+
+func headerSession(r *http.Request) (string, []string) {
+	token := r.Header.Get("X-Token")
+	if token == "" {
+		return "", nil
+	}
+	return token, []string{"TOKEN=" + token}
+}
+
+func TestSessionsUseDedicatedProcesses(t *testing.T) {
+	cfg := tokenEchoServer
+	cfg.SessionFunc = headerSession
+	proxy, err := NewMCPProxy(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+
+	call := func(token string) string {
+		req := httptest.NewRequest("POST", "/", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`))
+		if token != "" {
+			req.Header.Set("X-Token", token)
+		}
+		w := httptest.NewRecorder()
+		proxy.Handle(w, req)
+		return w.Body.String()
+	}
+
+	if got := call(""); !strings.Contains(got, `"token":"ambient"`) {
+		t.Errorf("Expected ambient token without a session, got %s", got)
+	}
+	if got := call("alice"); !strings.Contains(got, `"token":"alice"`) {
+		t.Errorf("Expected alice's session process, got %s", got)
+	}
+	if got := call("bob"); !strings.Contains(got, `"token":"bob"`) {
+		t.Errorf("Expected bob's session process, got %s", got)
+	}
+	if got := call("alice"); !strings.Contains(got, `"token":"alice"`) {
+		t.Errorf("Expected alice's session to be reused, got %s", got)
+	}
+	if n := len(proxy.sessions.sessions); n != 2 {
+		t.Errorf("Expected 2 session processes, got %d", n)
+	}
+}
+
+func TestSessionPoolLimit(t *testing.T) {
+	cfg := tokenEchoServer
+	cfg.SessionFunc = headerSession
+	cfg.MaxSessions = 1
+	proxy, err := NewMCPProxy(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+
+	_, release, err := proxy.sessions.acquire("alice", "192.0.2.1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// alice's session is busy, so there is no room for bob
+	if _, _, err := proxy.sessions.acquire("bob", "192.0.2.2", nil); err != errTooManySessions {
+		t.Fatalf("Expected errTooManySessions, got %v", err)
+	}
+
+	// Once alice's request completes her idle session is evicted for bob
+	release()
+	_, release, err = proxy.sessions.acquire("bob", "192.0.2.2", nil)
+	if err != nil {
+		t.Fatalf("Expected idle session to be evicted, got %v", err)
+	}
+	release()
+
+	if _, ok := proxy.sessions.sessions["alice"]; ok {
+		t.Error("Expected alice's session to be evicted")
+	}
+}
+
+func TestSessionsPerClientLimit(t *testing.T) {
+	cfg := tokenEchoServer
+	cfg.SessionFunc = headerSession
+	cfg.MaxSessions = 3
+	cfg.MaxSessionsPerClient = 2
+	proxy, err := NewMCPProxy(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+
+	call := func(addr, token string) string {
+		req := httptest.NewRequest("POST", "/", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`))
+		req.RemoteAddr = addr
+		req.Header.Set("X-Token", token)
+		w := httptest.NewRecorder()
+		proxy.Handle(w, req)
+		return w.Body.String()
+	}
+
+	if got := call("192.0.2.1:1234", "alice"); !strings.Contains(got, `"token":"alice"`) {
+		t.Fatalf("Expected alice's session process, got %s", got)
+	}
+
+	// A client cycling through tokens only replaces its own sessions
+	for _, token := range []string{"r1", "r2", "r3", "r4", "r5"} {
+		if got := call("192.0.2.66:4321", token); !strings.Contains(got, `"token":"`+token+`"`) {
+			t.Fatalf("Expected a session process for %s, got %s", token, got)
+		}
+	}
+
+	if _, ok := proxy.sessions.sessions["alice"]; !ok {
+		t.Error("Expected alice's idle session to survive another client's token churn")
+	}
+	if n := proxy.sessions.count("192.0.2.66"); n != 2 {
+		t.Errorf("Expected the churning client to hold 2 sessions, got %d", n)
+	}
+	for _, token := range []string{"r4", "r5"} {
+		if _, ok := proxy.sessions.sessions[token]; !ok {
+			t.Errorf("Expected the most recent session %s to be kept", token)
+		}
+	}
+}
+
+func TestSessionsPerClientUnlimitedByDefault(t *testing.T) {
+	cfg := tokenEchoServer
+	cfg.SessionFunc = headerSession
+	proxy, err := NewMCPProxy(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+
+	// Behind a load balancer without TRUSTED_PROXIES every user shares its
+	// address, so their sessions mustn't evict each other
+	for _, token := range []string{"alice", "bob", "carol"} {
+		_, release, err := proxy.sessions.acquire(token, "10.0.0.1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+	}
+	if n := len(proxy.sessions.sessions); n != 3 {
+		t.Errorf("Expected 3 sessions from one address, got %d", n)
+	}
+}
+
+func TestSessionStartedOnceForConcurrentRequests(t *testing.T) {
+	cfg := tokenEchoServer
+	cfg.SessionFunc = headerSession
+	proxy, err := NewMCPProxy(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+
+	got := make(chan *MCPProxy, 5)
+	for i := 0; i < cap(got); i++ {
+		go func() {
+			session, release, err := proxy.sessions.acquire("alice", "192.0.2.1", nil)
+			if err != nil {
+				t.Error(err)
+			}
+			release()
+			got <- session
+		}()
+	}
+	first := <-got
+	for i := 1; i < cap(got); i++ {
+		if session := <-got; session != first {
+			t.Error("Expected concurrent requests to share one session process")
+		}
+	}
+}
+
+func TestStopEndsSessions(t *testing.T) {
+	cfg := tokenEchoServer
+	cfg.SessionFunc = headerSession
+	proxy, err := NewMCPProxy(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session, release, err := proxy.sessions.acquire("alice", "192.0.2.1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	proxy.stop()
+
+	select {
+	case <-proxy.sessions.done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the idle reaper to return once the proxy stopped")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for !session.stopped.Load() {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected alice's session process to be stopped with the proxy")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSessionRestartedAfterExit(t *testing.T) {
+	cfg := tokenEchoServer
+	cfg.SessionFunc = headerSession
+	proxy, err := NewMCPProxy(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+
+	first, release, err := proxy.sessions.acquire("alice", "192.0.2.1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	first.markExited(errors.New("test"))
+
+	second, release, err := proxy.sessions.acquire("alice", "192.0.2.1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	if second == first {
+		t.Error("Expected a new session process after the old one exited")
+	}
+}
+
+func TestSessionRequestRetriedOnNewProcess(t *testing.T) {
+	cfg := tokenEchoServer
+	cfg.SessionFunc = headerSession
+	proxy, err := NewMCPProxy(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+
+	first, release, err := proxy.sessions.acquire("alice", "192.0.2.1", []string{"TOKEN=alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	first.io.Lock()
+	first.writer = bufio.NewWriter(brokenPipe{onWrite: func() {}})
+	first.io.Unlock()
+
+	req := httptest.NewRequest("POST", "/", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`))
+	req.Header.Set("X-Token", "alice")
+	w := httptest.NewRecorder()
+	proxy.Handle(w, req)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"token":"alice"`) {
+		t.Errorf("Expected the request to be retried on a new session process, got %d: %s", w.Code, w.Body.String())
+	}
+}
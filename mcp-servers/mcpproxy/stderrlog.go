@@ -0,0 +1,65 @@
+package mcpproxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// lineBuffer keeps the most recent lines written by the MCP server to stderr
+// so they can be fetched over HTTP after a failure.
+type lineBuffer struct {
+	mu    sync.Mutex
+	lines []string
+	next  int
+	full  bool
+}
+
+func newLineBuffer(size int) *lineBuffer {
+	return &lineBuffer{lines: make([]string, size)}
+}
+
+// add appends a line, overwriting the oldest one once the buffer is full.
+func (b *lineBuffer) add(line string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.lines) == 0 {
+		return
+	}
+	b.lines[b.next] = line
+	b.next = (b.next + 1) % len(b.lines)
+	if b.next == 0 {
+		b.full = true
+	}
+}
+
+// snapshot returns the buffered lines, oldest first.
+func (b *lineBuffer) snapshot() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.full {
+		return append([]string(nil), b.lines[:b.next]...)
+	}
+	return append(append([]string(nil), b.lines[b.next:]...), b.lines[:b.next]...)
+}
+
+// HandleLogs serves the buffered stderr lines as plain text, or as a JSON
+// object when the client asks for application/json.
+func (p *MCPProxy) HandleLogs(w http.ResponseWriter, r *http.Request) {
+	lines := p.stderr.snapshot()
+
+	if strings.Contains(r.Header.Get("Accept"), "application/json") || r.URL.Query().Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"server": p.config.ServerName,
+			"lines":  lines,
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	for _, line := range lines {
+		w.Write([]byte(line + "\n"))
+	}
+}
@@ -0,0 +1,59 @@
+package mcpproxy
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestLineBufferKeepsMostRecent(t *testing.T) {
+	b := newLineBuffer(3)
+	if got := b.snapshot(); len(got) != 0 {
+		t.Errorf("Expected empty snapshot, got %v", got)
+	}
+
+	b.add("one")
+	b.add("two")
+	if got := b.snapshot(); !reflect.DeepEqual(got, []string{"one", "two"}) {
+		t.Errorf("Unexpected snapshot %v", got)
+	}
+
+	b.add("three")
+	b.add("four")
+	b.add("five")
+	if got := b.snapshot(); !reflect.DeepEqual(got, []string{"three", "four", "five"}) {
+		t.Errorf("Expected oldest lines to be dropped, got %v", got)
+	}
+}
+
+func TestHandleLogs(t *testing.T) {
+	proxy := &MCPProxy{
+		config: Config{ServerName: "test"},
+		stderr: newLineBuffer(10),
+	}
+	proxy.stderr.add("ORA-12541: TNS:no listener")
+	proxy.stderr.add("retrying")
+
+	w := httptest.NewRecorder()
+	proxy.HandleLogs(w, httptest.NewRequest("GET", "/logs", nil))
+	if got := w.Body.String(); got != "ORA-12541: TNS:no listener\nretrying\n" {
+		t.Errorf("Unexpected plain text body %q", got)
+	}
+
+	req := httptest.NewRequest("GET", "/logs", nil)
+	req.Header.Set("Accept", "application/json")
+	w = httptest.NewRecorder()
+	proxy.HandleLogs(w, req)
+
+	var resp struct {
+		Server string   `json:"server"`
+		Lines  []string `json:"lines"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode JSON body: %v", err)
+	}
+	if resp.Server != "test" || len(resp.Lines) != 2 {
+		t.Errorf("Unexpected JSON body %+v", resp)
+	}
+}
@@ -0,0 +1,260 @@
+package mcpproxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"strings"
+)
+
+// Ready-made middlewares for Config.RequestMiddlewares and
+// Config.ResponseMiddlewares. The recommended order puts logging and metrics
+// on the outside, so they see messages as the client sent them and responses
+// as the client gets them:
+//
+//	reqLog, respLog := mcpproxy.LoggingMiddleware(log.Printf)
+//	reqMetrics, respMetrics := mcpproxy.MetricsMiddleware("sqlcl")
+//	cfg.RequestMiddlewares = []mcpproxy.RequestMiddleware{reqLog, reqMetrics /* auth, allowlists */}
+//	cfg.ResponseMiddlewares = []mcpproxy.ResponseMiddleware{
+//		/* server-specific rewriting, e.g. error marking */
+//		mcpproxy.RedactionMiddleware(nil, nil),
+//		respMetrics,
+//		respLog,
+//	}
+
+// LoggingMiddleware returns middlewares that log the method, id and size of
+// each message sent to the MCP server and the id, outcome and size of each
+// response, with logf (e.g. log.Printf). Payloads are never logged.
+func LoggingMiddleware(logf func(format string, args ...interface{})) (RequestMiddleware, ResponseMiddleware) {
+	request := func(msg []byte) ([]byte, error) {
+		var m MCPMessage
+		json.Unmarshal(msg, &m)
+		logf("request mcp_method=%s mcp_id=%s bytes=%d", m.Method, formatID(messageID(msg)), len(msg))
+		return msg, nil
+	}
+	response := func(resp []byte) []byte {
+		outcome, code, _ := responseOutcome(resp)
+		if code != 0 {
+			logf("response mcp_id=%s outcome=%s code=%d bytes=%d", formatID(messageID(resp)), outcome, code, len(resp))
+		} else {
+			logf("response mcp_id=%s outcome=%s bytes=%d", formatID(messageID(resp)), outcome, len(resp))
+		}
+		return resp
+	}
+	return request, response
+}
+
+// MetricsMiddleware returns middlewares that count messages sent to the MCP
+// server by method, as <prefix>_middleware_requests_total, and responses by
+// outcome (ok, tool_error or error), as <prefix>_middleware_responses_total,
+// on /metrics. The prefix defaults to "mcp".
+func MetricsMiddleware(prefix string) (RequestMiddleware, ResponseMiddleware) {
+	if prefix == "" {
+		prefix = "mcp"
+	}
+	requests := newCounterVec(prefix+"_middleware_requests_total", "Messages sent to the MCP server by JSON-RPC method.", "method")
+	responses := newCounterVec(prefix+"_middleware_responses_total", "Responses from the MCP server by outcome.", "outcome")
+	defaultRegistry.register(requests.name, requests)
+	defaultRegistry.register(responses.name, responses)
+
+	request := func(msg []byte) ([]byte, error) {
+		var m MCPMessage
+		json.Unmarshal(msg, &m)
+		requests.inc(m.Method)
+		return msg, nil
+	}
+	response := func(resp []byte) []byte {
+		outcome, _, _ := responseOutcome(resp)
+		responses.inc(outcome)
+		return resp
+	}
+	return request, response
+}
+
+// RedactionMiddleware returns a middleware that masks the values of keys in
+// responses, matched like LOG_REDACT_KEYS (nil means the same defaults), and
+// applies redactText, if not nil, to every string in them, so secrets an MCP
+// server returns don't reach the client. Responses that aren't valid JSON
+// are passed on unchanged.
+func RedactionMiddleware(keys []string, redactText func(string) string) ResponseMiddleware {
+	if keys == nil {
+		keys = defaultRedactKeys
+	}
+	return func(resp []byte) []byte {
+		// Keep large integer ids exact
+		var v interface{}
+		dec := json.NewDecoder(bytes.NewReader(resp))
+		dec.UseNumber()
+		if err := dec.Decode(&v); err != nil {
+			return resp
+		}
+		v = redactValue(v, keys)
+		if redactText != nil {
+			v = redactStrings(v, redactText)
+		}
+		out, err := json.Marshal(v)
+		if err != nil {
+			return resp
+		}
+		return out
+	}
+}
+
+// ContentRedactionMiddleware returns a middleware that removes embedded
+// contents from results: the image, audio and resource parts of a tool
+// result's content, and the contents of a resources/read result. A part is
+// removed if its data, blob or text is over maxBytes, when maxBytes > 0, or
+// its mimeType matches one of types, such as "application/pdf" or "image/*".
+// A removed content part is replaced by a text part saying what was removed,
+// and a removed resource's blob or text by such a text; with drop, both are
+// left out instead. Other parts, and responses without any to remove, are
+// passed on unchanged.
+func ContentRedactionMiddleware(maxBytes int, types []string, drop bool) ResponseMiddleware {
+	flagged := func(item map[string]interface{}, payload string) bool {
+		if maxBytes > 0 && len(payload) > maxBytes {
+			return true
+		}
+		mimeType, _ := item["mimeType"].(string)
+		return mimeType != "" && matchesMediaType(mimeType, types)
+	}
+	notice := func(item map[string]interface{}, payload string) string {
+		mimeType, _ := item["mimeType"].(string)
+		if mimeType == "" {
+			mimeType = "unknown type"
+		}
+		return fmt.Sprintf("[content removed by the proxy: %s, %d bytes]", mimeType, len(payload))
+	}
+
+	return func(resp []byte) []byte {
+		if !bytes.Contains(resp, []byte(`"content`)) {
+			return resp
+		}
+		// Keep large integer ids exact
+		var r map[string]interface{}
+		dec := json.NewDecoder(bytes.NewReader(resp))
+		dec.UseNumber()
+		if err := dec.Decode(&r); err != nil {
+			return resp
+		}
+		result, _ := r["result"].(map[string]interface{})
+		if result == nil {
+			return resp
+		}
+
+		changed := false
+		// A tool result's content parts
+		if parts, ok := result["content"].([]interface{}); ok {
+			kept := parts[:0:0]
+			for _, part := range parts {
+				fields, _ := part.(map[string]interface{})
+				if fields == nil {
+					kept = append(kept, part)
+					continue
+				}
+				item, payload := fields, ""
+				switch fields["type"] {
+				case "image", "audio":
+					payload, _ = fields["data"].(string)
+				case "resource":
+					item, _ = fields["resource"].(map[string]interface{})
+					if item == nil {
+						kept = append(kept, part)
+						continue
+					}
+					payload = resourcePayload(item)
+				default:
+					kept = append(kept, part)
+					continue
+				}
+				if !flagged(item, payload) {
+					kept = append(kept, part)
+					continue
+				}
+				changed = true
+				if !drop {
+					kept = append(kept, map[string]interface{}{"type": "text", "text": notice(item, payload)})
+				}
+			}
+			result["content"] = kept
+		}
+		// A resources/read result's contents
+		if contents, ok := result["contents"].([]interface{}); ok {
+			kept := contents[:0:0]
+			for _, c := range contents {
+				item, _ := c.(map[string]interface{})
+				if item == nil || !flagged(item, resourcePayload(item)) {
+					kept = append(kept, c)
+					continue
+				}
+				changed = true
+				if !drop {
+					text := notice(item, resourcePayload(item))
+					delete(item, "blob")
+					item["text"] = text
+					kept = append(kept, item)
+				}
+			}
+			result["contents"] = kept
+		}
+		if !changed {
+			return resp
+		}
+
+		out, err := json.Marshal(r)
+		if err != nil {
+			return resp
+		}
+		return out
+	}
+}
+
+// resourcePayload returns the blob or text of embedded resource contents.
+func resourcePayload(item map[string]interface{}) string {
+	if blob, ok := item["blob"].(string); ok {
+		return blob
+	}
+	text, _ := item["text"].(string)
+	return text
+}
+
+// matchesMediaType reports whether mimeType matches one of types, which may
+// end in /* to match a whole top-level type. Parameters such as charset are
+// ignored.
+func matchesMediaType(mimeType string, types []string) bool {
+	mt, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return false
+	}
+	for _, t := range types {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == mt || (strings.HasSuffix(t, "/*") && strings.HasPrefix(mt, strings.TrimSuffix(t, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// responseOutcome classifies a JSON-RPC response as ok, tool_error (a result
+// with isError set) or error, with the error's code and message.
+func responseOutcome(resp []byte) (outcome string, code int, message string) {
+	var r struct {
+		Result *struct {
+			IsError bool `json:"isError"`
+		} `json:"result"`
+		Error *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	json.Unmarshal(resp, &r)
+	switch {
+	case r.Error != nil:
+		return "error", r.Error.Code, r.Error.Message
+	case r.Result != nil && r.Result.IsError:
+		return "tool_error", 0, ""
+	case r.Result != nil:
+		return "ok", 0, ""
+	}
+	return "error", 0, ""
+}
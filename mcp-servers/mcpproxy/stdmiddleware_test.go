@@ -0,0 +1,165 @@
+package mcpproxy
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLoggingMiddleware(t *testing.T) {
+	var lines []string
+	request, response := LoggingMiddleware(func(format string, args ...interface{}) {
+		lines = append(lines, fmt.Sprintf(format, args...))
+	})
+
+	msg := []byte(`{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"arguments":{"password":"hunter2"}}}`)
+	if out, err := request(msg); err != nil || string(out) != string(msg) {
+		t.Errorf("Expected the request unchanged, got %s, %v", out, err)
+	}
+	resp := []byte(`{"jsonrpc":"2.0","id":7,"error":{"code":-32602,"message":"bad"}}`)
+	if out := response(resp); string(out) != string(resp) {
+		t.Errorf("Expected the response unchanged, got %s", out)
+	}
+
+	want := []string{
+		fmt.Sprintf("request mcp_method=tools/call mcp_id=7 bytes=%d", len(msg)),
+		fmt.Sprintf("response mcp_id=7 outcome=error code=-32602 bytes=%d", len(resp)),
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(lines, "\n"))
+	}
+}
+
+func TestMetricsMiddleware(t *testing.T) {
+	request, response := MetricsMiddleware("test_mw")
+	request([]byte(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`))
+	request([]byte(`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`))
+	request([]byte(`{"jsonrpc":"2.0","id":3,"method":"tools/call"}`))
+	response([]byte(`{"jsonrpc":"2.0","id":1,"result":{}}`))
+	response([]byte(`{"jsonrpc":"2.0","id":3,"result":{"isError":true}}`))
+
+	w := httptest.NewRecorder()
+	defaultRegistry.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	for _, want := range []string{
+		"# TYPE test_mw_middleware_requests_total counter\n",
+		`test_mw_middleware_requests_total{method="tools/call"} 1` + "\n",
+		`test_mw_middleware_requests_total{method="tools/list"} 2` + "\n",
+		`test_mw_middleware_responses_total{outcome="ok"} 1` + "\n",
+		`test_mw_middleware_responses_total{outcome="tool_error"} 1` + "\n",
+	} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("Expected %q in:\n%s", want, w.Body.String())
+		}
+	}
+}
+
+func TestCounterVecBoundsLabels(t *testing.T) {
+	c := newCounterVec("test_total", "Test.", "method")
+	for i := 0; i < maxCounterLabels+5; i++ {
+		c.inc(fmt.Sprintf("m%d", i))
+	}
+	c.inc("quote\"d\nline")
+	if len(c.values) != maxCounterLabels+1 || c.values["other"] != 6 {
+		t.Errorf("Expected the excess counted as other, got %d values, other=%g", len(c.values), c.values["other"])
+	}
+
+	c = newCounterVec("test_total", "Test.", "method")
+	c.inc("quote\"d\nline")
+	var b strings.Builder
+	c.write(&b)
+	if !strings.Contains(b.String(), `test_total{method="quote\"d\nline"} 1`) {
+		t.Errorf("Expected an escaped label value, got:\n%s", b.String())
+	}
+}
+
+func TestRedactionMiddleware(t *testing.T) {
+	redact := RedactionMiddleware(nil, func(s string) string { return strings.ReplaceAll(s, "tiger", "***") })
+
+	resp := `{"jsonrpc":"2.0","id":12345678901234567890,"result":{"content":[{"type":"text","text":"scott/tiger"}],"apiKey":"abc"}}`
+	want := `{"id":12345678901234567890,"jsonrpc":"2.0","result":{"apiKey":"[REDACTED]","content":[{"text":"scott/***","type":"text"}]}}`
+	if got := string(redact([]byte(resp))); got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+	if got := string(redact([]byte("not json"))); got != "not json" {
+		t.Errorf("Expected invalid JSON unchanged, got %s", got)
+	}
+}
+
+func TestContentRedactionMiddleware(t *testing.T) {
+	resp := `{"jsonrpc":"2.0","id":12345678901234567890,"result":{"content":[` +
+		`{"type":"text","text":"a long text part that is kept whatever its size"},` +
+		`{"type":"image","data":"aGVsbG8gd29ybGQ=","mimeType":"image/png"},` +
+		`{"type":"image","data":"aGk=","mimeType":"image/png"},` +
+		`{"type":"resource","resource":{"uri":"file:///etc/key.pem","mimeType":"application/x-pem-file","text":"-----BEGIN"}}` +
+		`],"isError":false}}`
+
+	redact := ContentRedactionMiddleware(10, []string{"application/x-pem-file"}, false)
+	want := `{"id":12345678901234567890,"jsonrpc":"2.0","result":{"content":[` +
+		`{"text":"a long text part that is kept whatever its size","type":"text"},` +
+		`{"text":"[content removed by the proxy: image/png, 16 bytes]","type":"text"},` +
+		`{"data":"aGk=","mimeType":"image/png","type":"image"},` +
+		`{"text":"[content removed by the proxy: application/x-pem-file, 10 bytes]","type":"text"}` +
+		`],"isError":false}}`
+	if got := string(redact([]byte(resp))); got != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, got)
+	}
+
+	drop := ContentRedactionMiddleware(0, []string{"image/*"}, true)
+	want = `{"id":12345678901234567890,"jsonrpc":"2.0","result":{"content":[` +
+		`{"text":"a long text part that is kept whatever its size","type":"text"},` +
+		`{"resource":{"mimeType":"application/x-pem-file","text":"-----BEGIN","uri":"file:///etc/key.pem"},"type":"resource"}` +
+		`],"isError":false}}`
+	if got := string(drop([]byte(resp))); got != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, got)
+	}
+
+	// resources/read contents keep their uri
+	read := `{"jsonrpc":"2.0","id":1,"result":{"contents":[{"uri":"file:///a.pdf","mimeType":"application/pdf","blob":"JVBERi0="}]}}`
+	want = `{"id":1,"jsonrpc":"2.0","result":{"contents":[{"mimeType":"application/pdf","text":"[content removed by the proxy: application/pdf, 8 bytes]","uri":"file:///a.pdf"}]}}`
+	if got := string(ContentRedactionMiddleware(0, []string{"application/pdf"}, false)([]byte(read))); got != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, got)
+	}
+
+	// Responses with nothing to remove are passed on byte for byte
+	for _, unchanged := range []string{resp, `{"jsonrpc":"2.0","id":1,"result":{}}`, "not json"} {
+		if got := string(ContentRedactionMiddleware(1000, []string{"video/*"}, false)([]byte(unchanged))); got != unchanged {
+			t.Errorf("Expected %s unchanged, got %s", unchanged, got)
+		}
+	}
+}
+
+func TestRedactContentConfig(t *testing.T) {
+	proxy, err := NewMCPProxy(Config{
+		ServerName:         "test",
+		CommandPath:        "sh",
+		CommandArgs:        []string{"-c", `read line; echo '{"jsonrpc":"2.0","id":1,"result":{"content":[{"type":"image","data":"aGk=","mimeType":"image/png"}]}}'`},
+		RedactContentTypes: []string{"image/*"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+
+	if w := ping(proxy); strings.Contains(w.Body.String(), "aGk=") || !strings.Contains(w.Body.String(), "content removed by the proxy") {
+		t.Errorf("Expected the image removed, got %s", w.Body.String())
+	}
+}
+
+func TestResponseOutcome(t *testing.T) {
+	tests := []struct {
+		resp    string
+		outcome string
+		code    int
+	}{
+		{`{"jsonrpc":"2.0","id":1,"result":{}}`, "ok", 0},
+		{`{"jsonrpc":"2.0","id":1,"result":{"isError":true}}`, "tool_error", 0},
+		{`{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"no"}}`, "error", -32601},
+		{`garbage`, "error", 0},
+	}
+	for _, tt := range tests {
+		if outcome, code, _ := responseOutcome([]byte(tt.resp)); outcome != tt.outcome || code != tt.code {
+			t.Errorf("responseOutcome(%s) = %s, %d; want %s, %d", tt.resp, outcome, code, tt.outcome, tt.code)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package mcpproxy
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// stopStdinClose is the MCP_STOP_SIGNAL step that only closes the MCP
+// server's stdin, which most stdio servers take as the end of the session.
+const stopStdinClose = "stdin-close"
+
+// stopSignals are the signals MCP_STOP_SIGNAL may name.
+var stopSignals = map[string]syscall.Signal{
+	"SIGTERM": syscall.SIGTERM,
+	"SIGINT":  syscall.SIGINT,
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGQUIT": syscall.SIGQUIT,
+}
+
+// parseStopSignal parses a comma-separated list of the steps taken to stop the
+// MCP server, such as "stdin-close,SIGTERM". Each step but stdin-close, which
+// may only come first, is a signal; a nil signal stands for stdin-close.
+func parseStopSignal(s string) ([]os.Signal, error) {
+	var steps []os.Signal
+	for i, name := range strings.Split(s, ",") {
+		name = strings.ToUpper(strings.TrimSpace(name))
+		if name == strings.ToUpper(stopStdinClose) {
+			if i > 0 {
+				return nil, fmt.Errorf("%s must be the first step", stopStdinClose)
+			}
+			steps = append(steps, nil)
+			continue
+		}
+		if !strings.HasPrefix(name, "SIG") {
+			name = "SIG" + name
+		}
+		sig, ok := stopSignals[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown signal %q", name)
+		}
+		steps = append(steps, sig)
+	}
+	return steps, nil
+}
+
+// terminate closes the MCP server's stdin and takes the steps of
+// Config.StopSignal in turn, giving the server Config.StopTimeout after each
+// to exit before killing it. p.io must be held.
+func (p *MCPProxy) terminate() {
+	p.stdin.Close()
+
+	done := make(chan struct{})
+	go func() {
+		p.cmd.Wait()
+		close(done)
+	}()
+	pid := p.cmd.Process.Pid
+	if !p.stopGracefully(done) {
+		p.logger.warnf("MCP server (PID: %d) did not exit, killing it", pid)
+		p.cmd.Process.Kill()
+		<-done
+	}
+	if p.reaped != nil {
+		close(p.reaped)
+		p.reaped = nil
+	}
+	p.logger.infof("Stopped MCP server (PID: %d)", pid)
+}
+
+// stopGracefully reports whether the MCP server exited, closing done, within
+// the steps of Config.StopSignal.
+func (p *MCPProxy) stopGracefully(done <-chan struct{}) bool {
+	// Validated by NewMCPProxy
+	steps, _ := parseStopSignal(p.config.StopSignal)
+	for _, sig := range steps {
+		if sig != nil {
+			p.logger.debugf("Sending %s to MCP server (PID: %d)", sig, p.cmd.Process.Pid)
+			if err := p.cmd.Process.Signal(sig); err != nil {
+				p.logger.debugf("Failed to signal MCP server: %v", err)
+			}
+		}
+		select {
+		case <-done:
+			return true
+		case <-time.After(p.config.StopTimeout):
+		}
+	}
+	return false
+}
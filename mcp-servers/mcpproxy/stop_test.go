@@ -0,0 +1,78 @@
+package mcpproxy
+
+import (
+	"os"
+	"reflect"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestParseStopSignal(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []os.Signal
+		err  bool
+	}{
+		{"stdin-close,SIGTERM", []os.Signal{nil, syscall.SIGTERM}, false},
+		{"stdin-close", []os.Signal{nil}, false},
+		{"SIGINT", []os.Signal{syscall.SIGINT}, false},
+		{"term, sigkill", nil, true},
+		{"sigterm, quit", []os.Signal{syscall.SIGTERM, syscall.SIGQUIT}, false},
+		{"SIGTERM,stdin-close", nil, true},
+		{"", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parseStopSignal(tt.in)
+		if (err != nil) != tt.err || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseStopSignal(%q) = %v, %v; want %v, error %v", tt.in, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestStopEscalatesToKill(t *testing.T) {
+	// A server that ignores both its stdin closing and SIGTERM
+	proxy, err := NewMCPProxy(Config{
+		ServerName:  "test",
+		CommandPath: "sh",
+		CommandArgs: []string{"-c", `trap "" TERM; while :; do sleep 0.05; done`},
+		StopTimeout: 100 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	proxy.stop()
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("Expected a StopTimeout after closing stdin and after SIGTERM, stopped after %s", elapsed)
+	}
+	status := proxy.cmd.ProcessState.Sys().(syscall.WaitStatus)
+	if !status.Signaled() || status.Signal() != syscall.SIGKILL {
+		t.Errorf("Expected the server to be killed, got %v", proxy.cmd.ProcessState)
+	}
+}
+
+func TestStopSignal(t *testing.T) {
+	// A server that exits on SIGINT, once it has answered a request
+	proxy, err := NewMCPProxy(Config{
+		ServerName:  "test",
+		CommandPath: "sh",
+		CommandArgs: []string{"-c", `trap "exit 3" INT; read line; echo "$line"; while :; do sleep 0.05; done`},
+		StopSignal:  "SIGINT",
+		StopTimeout: 5 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ping(proxy)
+
+	start := time.Now()
+	proxy.stop()
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected SIGINT to be sent without waiting, stopped after %s", elapsed)
+	}
+	if code := proxy.cmd.ProcessState.ExitCode(); code != 3 {
+		t.Errorf("Expected the server to exit on SIGINT with code 3, got %v", proxy.cmd.ProcessState)
+	}
+}
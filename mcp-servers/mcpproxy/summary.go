@@ -0,0 +1,214 @@
+package mcpproxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// configSummary is the effective configuration as logged at startup and
+// served at /config. Secrets are never included: environment values and the
+// admin token are reduced to their names or presence, and command arguments
+// go through the LogRedactor.
+type configSummary struct {
+	ServerName         string   `json:"server_name"`
+	Backend            string   `json:"backend"`
+	UpstreamURL        string   `json:"upstream_url,omitempty"`
+	Command            string   `json:"command"`
+	Args               []string `json:"args"`
+	WorkDir            string   `json:"work_dir,omitempty"`
+	RunAsUID           string   `json:"run_as_uid,omitempty"`
+	RunAsGID           string   `json:"run_as_gid,omitempty"`
+	Nice               int      `json:"nice,omitempty"`
+	CPUAffinity        string   `json:"cpu_affinity,omitempty"`
+	ExtraEnvKeys       []string `json:"extra_env_keys"`
+	ListenAddr         string   `json:"listen_addr"`
+	ListenUnix         string   `json:"listen_unix,omitempty"`
+	CORS               bool     `json:"cors"`
+	Compression        bool     `json:"compression"`
+	H2C                bool     `json:"h2c"`
+	StrictJSONRPC      bool     `json:"strict_jsonrpc"`
+	QueueSize          int      `json:"queue_size"`
+	FastNotifications  bool     `json:"fast_notifications"`
+	MaxRequestBytes    int      `json:"max_request_bytes"`
+	Delimiter          string   `json:"delimiter"`
+	WarnMessageBytes   int      `json:"warn_message_bytes"`
+	WriteTimeout       string   `json:"write_timeout"`
+	RequestTimeout     string   `json:"request_timeout"`
+	MaxRequestTimeout  string   `json:"max_request_timeout"`
+	CancelKillGrace    string   `json:"cancel_kill_grace"`
+	MaxRestarts        int      `json:"max_restarts"`
+	StopSignal         string   `json:"stop_signal"`
+	StopTimeout        string   `json:"stop_timeout"`
+	CrashWebhook       bool     `json:"crash_webhook"` // the URL may embed a token
+	EnableMetrics      bool     `json:"enable_metrics"`
+	ResourceInterval   string   `json:"resource_sample_interval"`
+	MaxRSSBytes        int      `json:"max_rss_bytes"`
+	MaxRSSWindow       string   `json:"max_rss_window"`
+	HealthPingInterval string   `json:"health_ping_interval"`
+	HealthPingTimeout  string   `json:"health_ping_timeout"`
+	HealthPingFailures int      `json:"health_ping_failures"`
+	RateLimitRPS       float64  `json:"rate_limit_rps"`
+	RateLimitBurst     int      `json:"rate_limit_burst"`
+	TrustedProxies     []string `json:"trusted_proxies"`
+	BreakerThreshold   int      `json:"breaker_threshold"`
+	BreakerCooldown    string   `json:"breaker_cooldown"`
+	ToolsCacheTTL      string   `json:"tools_cache_ttl"`
+	AllowedTools       []string `json:"allowed_tools"`
+	DeniedTools        []string `json:"denied_tools"`
+	CoalesceMethods    []string `json:"coalesce_methods"`
+	ResponseRetry      bool     `json:"response_retry"`
+	Sessions           bool     `json:"sessions"`
+	MaxSessions        int      `json:"max_sessions"`
+	MaxPerClient       int      `json:"max_sessions_per_client"`
+	ReadyPattern       string   `json:"ready_pattern,omitempty"`
+	StartupDelay       string   `json:"startup_delay"`
+	ReadyRetries       int      `json:"ready_retries"`
+	ReadyRetryBackoff  string   `json:"ready_retry_backoff"`
+	Instances          int      `json:"instances"`
+	SessionIdle        string   `json:"session_idle_timeout"`
+	LogLevel           string   `json:"log_level"`
+	LogPayloads        bool     `json:"log_payloads"`
+	ClientLogLevel     bool     `json:"respect_client_log_level"`
+	AccessLog          bool     `json:"access_log"`
+	AuditLogFile       string   `json:"audit_log_file,omitempty"`
+	RecentRequests     int      `json:"recent_requests"`
+	RedactContentBytes int      `json:"redact_content_bytes"`
+	RedactContentTypes []string `json:"redact_content_types"`
+	DropRedacted       bool     `json:"redact_content_drop"`
+	Pprof              bool     `json:"pprof"`
+	AdminEndpoints     bool     `json:"admin_endpoints"`
+	AdminAddr          string   `json:"admin_addr,omitempty"`
+	AdminPort          string   `json:"admin_port,omitempty"`
+	Diagnostics        bool     `json:"diagnostics"`
+	SkipNotifications  bool     `json:"skip_notifications"`
+	LogSkipped         string   `json:"log_skipped_notifications"`
+
+	// By tool name, from MCP_TOOL_TIMEOUTS and MCP_TOOL_RATE_LIMITS
+	ToolTimeouts   map[string]string  `json:"tool_timeouts,omitempty"`
+	ToolRateLimits map[string]float64 `json:"tool_rate_limits,omitempty"`
+
+	// By name, from MCP_ERROR_CODES
+	ErrorCodes map[string]int `json:"error_codes,omitempty"`
+}
+
+// summary returns the redacted view of c.
+func (c *Config) summary() configSummary {
+	args := make([]string, len(c.CommandArgs))
+	for i, arg := range c.CommandArgs {
+		args[i] = c.redactText(arg)
+	}
+	envKeys := envKeys(c.ExtraEnv)
+	sort.Strings(envKeys)
+
+	s := configSummary{
+		ServerName:         c.ServerName,
+		Backend:            c.BackendType,
+		Command:            c.CommandPath,
+		Args:               args,
+		WorkDir:            c.WorkDir,
+		RunAsUID:           c.RunAsUID,
+		RunAsGID:           c.RunAsGID,
+		Nice:               c.Nice,
+		CPUAffinity:        c.CPUAffinity,
+		ExtraEnvKeys:       envKeys,
+		ListenAddr:         c.listenAddr(),
+		ListenUnix:         c.ListenUnix,
+		CORS:               c.EnableCORS,
+		Compression:        c.EnableCompression,
+		H2C:                c.EnableH2C,
+		StrictJSONRPC:      c.StrictJSONRPC,
+		QueueSize:          c.QueueSize,
+		FastNotifications:  c.FastNotifications,
+		MaxRequestBytes:    c.MaxRequestBytes,
+		Delimiter:          c.Delimiter,
+		WarnMessageBytes:   c.WarnMessageBytes,
+		WriteTimeout:       c.WriteTimeout.String(),
+		RequestTimeout:     c.RequestTimeout.String(),
+		MaxRequestTimeout:  c.MaxRequestTimeout.String(),
+		CancelKillGrace:    c.CancelKillGrace.String(),
+		MaxRestarts:        c.MaxRestarts,
+		StopSignal:         c.StopSignal,
+		StopTimeout:        c.StopTimeout.String(),
+		CrashWebhook:       c.CrashWebhookURL != "",
+		EnableMetrics:      c.EnableMetrics,
+		ResourceInterval:   c.ResourceSampleInterval.String(),
+		MaxRSSBytes:        c.MaxRSSBytes,
+		MaxRSSWindow:       c.MaxRSSWindow.String(),
+		HealthPingInterval: c.HealthPingInterval.String(),
+		HealthPingTimeout:  c.HealthPingTimeout.String(),
+		HealthPingFailures: c.HealthPingFailures,
+		RateLimitRPS:       c.RateLimitRPS,
+		RateLimitBurst:     c.RateLimitBurst,
+		TrustedProxies:     c.TrustedProxies,
+		BreakerThreshold:   c.BreakerThreshold,
+		BreakerCooldown:    c.BreakerCooldown.String(),
+		ToolsCacheTTL:      c.ToolsCacheTTL.String(),
+		AllowedTools:       c.AllowedTools,
+		DeniedTools:        c.DeniedTools,
+		CoalesceMethods:    c.CoalesceMethods,
+		ResponseRetry:      c.ResponseRetry != nil,
+		Sessions:           c.SessionFunc != nil,
+		MaxSessions:        c.MaxSessions,
+		MaxPerClient:       c.MaxSessionsPerClient,
+		ReadyPattern:       c.ReadyPattern,
+		StartupDelay:       c.StartupDelay.String(),
+		ReadyRetries:       c.ReadyRetries,
+		ReadyRetryBackoff:  c.ReadyRetryBackoff.String(),
+		Instances:          c.Instances,
+		SessionIdle:        c.SessionIdleTimeout.String(),
+		LogLevel:           c.LogLevel,
+		LogPayloads:        c.LogPayloads,
+		ClientLogLevel:     c.RespectClientLogLevel,
+		AccessLog:          c.AccessLog,
+		AuditLogFile:       c.AuditLogFile,
+		RecentRequests:     c.RecentRequests,
+		RedactContentBytes: c.RedactContentBytes,
+		RedactContentTypes: c.RedactContentTypes,
+		DropRedacted:       c.DropRedactedContent,
+		Pprof:              c.EnablePprof,
+		AdminEndpoints:     c.AdminToken != "",
+		AdminPort:          c.AdminPort,
+		Diagnostics:        c.EnableDiagnostics,
+		SkipNotifications:  c.SkipNotifications,
+		LogSkipped:         strings.ToLower(c.skippedNotificationLevel().String()),
+		ToolRateLimits:     c.ToolRateLimits,
+	}
+	if len(c.ToolTimeouts) > 0 {
+		s.ToolTimeouts = make(map[string]string, len(c.ToolTimeouts))
+		for tool, d := range c.ToolTimeouts {
+			s.ToolTimeouts[tool] = d.String()
+		}
+	}
+	for name, code := range errorCodeNames {
+		if to, ok := c.ErrorCodes[code]; ok {
+			if s.ErrorCodes == nil {
+				s.ErrorCodes = make(map[string]int)
+			}
+			s.ErrorCodes[name] = to
+		}
+	}
+	if c.UpstreamURL != "" {
+		s.UpstreamURL = redactURL(c.UpstreamURL)
+	}
+	if c.EnablePprof || c.AdminToken != "" {
+		s.AdminAddr = c.AdminAddr
+	}
+	return s
+}
+
+// logSummary logs the effective configuration as a single JSON line.
+func (c *Config) logSummary(lg *logger) {
+	data, err := json.Marshal(c.summary())
+	if err != nil {
+		return
+	}
+	lg.infof("Effective configuration: %s", data)
+}
+
+// HandleConfig serves the effective configuration, with secrets redacted, as JSON.
+func (p *MCPProxy) HandleConfig(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p.config.summary())
+}
@@ -0,0 +1,42 @@
+package mcpproxy
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestConfigSummaryRedactsSecrets(t *testing.T) {
+	cfg := Config{
+		ServerName:  "sqlcl",
+		CommandPath: "/opt/oracle/sqlcl/bin/sql",
+		CommandArgs: []string{"-mcp", "scott/tiger@db"},
+		ExtraEnv:    []string{"GITHUB_PERSONAL_ACCESS_TOKEN=ghp_abc", "HOME=/sqlcl-home"},
+		AdminToken:  "s3cret",
+		LogRedactor: func(s string) string { return strings.ReplaceAll(s, "tiger", "***") },
+	}
+	cfg.applyDefaults()
+
+	proxy := &MCPProxy{config: cfg}
+	w := httptest.NewRecorder()
+	proxy.HandleConfig(w, httptest.NewRequest("GET", "/config", nil))
+
+	body := w.Body.String()
+	for _, secret := range []string{"ghp_abc", "s3cret", "tiger"} {
+		if strings.Contains(body, secret) {
+			t.Errorf("Secret %q leaked into /config: %s", secret, body)
+		}
+	}
+
+	var got configSummary
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Command != cfg.CommandPath || got.ListenAddr != ":8080" || !got.AdminEndpoints {
+		t.Errorf("Unexpected summary: %+v", got)
+	}
+	if want := []string{"GITHUB_PERSONAL_ACCESS_TOKEN", "HOME"}; strings.Join(got.ExtraEnvKeys, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected env keys %v, got %v", want, got.ExtraEnvKeys)
+	}
+}
@@ -0,0 +1,128 @@
+package mcpproxy
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxRestartDelay caps the delay between consecutive restarts.
+const maxRestartDelay = 30 * time.Second
+
+var (
+	// errStdoutClosed is returned when the MCP server closes its stdout,
+	// usually because it exited.
+	errStdoutClosed = errors.New("MCP server closed its stdout")
+
+	// errStopped is returned when restarting a proxy that has been stopped.
+	errStopped = errors.New("proxy is stopped")
+)
+
+// restartDelay returns how long to wait before the nth consecutive restart:
+// not at all for the first, then 1s, doubling up to maxRestartDelay.
+func restartDelay(n int) time.Duration {
+	if n <= 1 {
+		return 0
+	}
+	if n > 7 {
+		return maxRestartDelay
+	}
+	return min(time.Second<<(n-2), maxRestartDelay)
+}
+
+// superviseExit restarts the MCP server after it exited with err, retrying
+// with growing delays up to Config.MaxRestarts times in a row. Meanwhile p is
+// restarting, so requests wait for the new process rather than fail.
+func (p *MCPProxy) superviseExit(err error) {
+	if !p.supervise || p.stopped.Load() || !p.nextRestart() {
+		p.health.fail(stateDead, err)
+		return
+	}
+	p.health.fail(stateDegraded, err)
+	p.restarting.Store(true)
+	go func() {
+		defer p.restarting.Store(false)
+		for {
+			n := int(p.restarts.Load())
+			delay := restartDelay(n)
+			p.logger.warnf("Restarting MCP server in %s (attempt %d of %d)", delay, n, p.config.MaxRestarts)
+			p.restartDue.Store(time.Now().Add(delay).UnixNano())
+			time.Sleep(delay)
+
+			err := p.respawn()
+			if err == nil || errors.Is(err, errStopped) {
+				return
+			}
+			p.logger.errorf("Restarting MCP server failed: %v", err)
+			if !p.nextRestart() {
+				p.health.fail(stateDead, err)
+				return
+			}
+			p.health.fail(stateDegraded, err)
+		}
+	}()
+}
+
+// nextRestart counts a restart, reporting false once Config.MaxRestarts
+// restarts in a row have been used up.
+func (p *MCPProxy) nextRestart() bool {
+	if n := int(p.restarts.Add(1)); n > p.config.MaxRestarts {
+		p.logger.errorf("MCP server exited after %d restarts in a row, giving up", n-1)
+		return false
+	}
+	return true
+}
+
+// exitMessage is the error message for requests that failed because the MCP
+// server exited. If it is no longer marked exited, it has already been
+// restarted.
+func (p *MCPProxy) exitMessage() string {
+	if p.restarting.Load() || !p.exited.Load() {
+		return errServerRestarting
+	}
+	return errServerExited
+}
+
+// writeUnavailable answers a request that can't be sent because the MCP
+// server exited or is being restarted with HTTP 503. During a restart,
+// Retry-After says when it is expected to be done.
+func (p *MCPProxy) writeUnavailable(w http.ResponseWriter, id interface{}) {
+	if p.restarting.Load() {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(p.restartETA().Seconds()))))
+	}
+	writeJSONRPCError(w, http.StatusServiceUnavailable, id, codeServerUnavailable, p.exitMessage())
+}
+
+// restartETA estimates how long until the restart in progress is done: until
+// the pending respawn begins, plus the average time respawns took. It is at
+// least a second, also before the first respawn.
+func (p *MCPProxy) restartETA() time.Duration {
+	eta := time.Until(time.Unix(0, p.restartDue.Load())) + p.startups.average()
+	return max(eta, time.Second)
+}
+
+// startupTimes keeps the average time it took to replace the MCP server.
+type startupTimes struct {
+	mu    sync.Mutex
+	total time.Duration
+	count int
+}
+
+func (s *startupTimes) add(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.total += d
+	s.count++
+}
+
+func (s *startupTimes) average() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.count == 0 {
+		return 0
+	}
+	return s.total / time.Duration(s.count)
+}
@@ -0,0 +1,142 @@
+package mcpproxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRestartDelay(t *testing.T) {
+	tests := []struct {
+		n    int
+		want time.Duration
+	}{
+		{1, 0},
+		{2, time.Second},
+		{3, 2 * time.Second},
+		{6, 16 * time.Second},
+		{7, maxRestartDelay},
+		{100, maxRestartDelay},
+	}
+	for _, tt := range tests {
+		if got := restartDelay(tt.n); got != tt.want {
+			t.Errorf("restartDelay(%d) = %s, want %s", tt.n, got, tt.want)
+		}
+	}
+}
+
+// closesStdout is an MCP server that answers one request and then closes
+// its stdout while still running.
+var closesStdout = Config{
+	ServerName:  "test",
+	CommandPath: "sh",
+	CommandArgs: []string{"-c", `read line; echo "$line"; exec >&-; read line`},
+}
+
+func ping(p *MCPProxy) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	p.Handle(w, httptest.NewRequest("POST", "/", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`)))
+	return w
+}
+
+func TestRestartAfterStdoutClosed(t *testing.T) {
+	proxy, err := NewMCPProxy(closesStdout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+	firstPID := proxy.pid()
+
+	if w := ping(proxy); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 before stdout is closed, got %d", w.Code)
+	}
+
+	// The request in flight when stdout closes fails with a clear message
+	w := ping(proxy)
+	if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), errServerRestarting) {
+		t.Errorf("Expected 503 saying the server is restarting, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// The next one is answered by the new process
+	waitRestarted(proxy)
+	if w := ping(proxy); w.Code != http.StatusOK {
+		t.Errorf("Expected 200 from the restarted server, got %d: %s", w.Code, w.Body.String())
+	}
+	if proxy.pid() == firstPID {
+		t.Error("Expected a new MCP server process")
+	}
+}
+
+func TestRestartGivesUp(t *testing.T) {
+	proxy, err := NewMCPProxy(Config{
+		ServerName:  "test",
+		CommandPath: "sh",
+		CommandArgs: []string{"-c", `exec >&-; read line`},
+		MaxRestarts: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+
+	// The first request fails and restarts the server, which fails again
+	for i := 0; i < 3; i++ {
+		if w := ping(proxy); w.Code != http.StatusServiceUnavailable {
+			t.Fatalf("Request %d: expected 503, got %d", i, w.Code)
+		}
+	}
+
+	waitRestarted(proxy)
+	if w := ping(proxy); !strings.Contains(w.Body.String(), errServerExited) {
+		t.Errorf("Expected the server to stay down after MaxRestarts, got %s", w.Body.String())
+	}
+	if !proxy.exited.Load() {
+		t.Error("Expected the proxy to be marked as exited")
+	}
+}
+
+func TestHandleDuringRestart(t *testing.T) {
+	proxy, err := NewMCPProxy(Config{ServerName: "test", CommandPath: "cat"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+
+	// Restarts took 2.5s on average and the next one begins in 1s
+	proxy.startups.add(2 * time.Second)
+	proxy.startups.add(3 * time.Second)
+	proxy.restartDue.Store(time.Now().Add(time.Second).UnixNano())
+	proxy.restarting.Store(true)
+
+	w := ping(proxy)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected 503 during the restart, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Retry-After"); got != "4" {
+		t.Errorf("Expected Retry-After: 4, got %q", got)
+	}
+	if !strings.Contains(w.Body.String(), `"code":-32002`) || !strings.Contains(w.Body.String(), "restarting") {
+		t.Errorf("Expected a JSON-RPC error saying the server is restarting, got %s", w.Body.String())
+	}
+
+	proxy.restarting.Store(false)
+	if w := ping(proxy); w.Code != http.StatusOK {
+		t.Errorf("Expected 200 once the restart is done, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRestartETA(t *testing.T) {
+	var proxy MCPProxy
+	proxy.restartDue.Store(time.Now().UnixNano())
+	if got := proxy.restartETA(); got != time.Second {
+		t.Errorf("Expected 1s before any restart was timed, got %s", got)
+	}
+
+	proxy.startups.add(5 * time.Second)
+	proxy.restartDue.Store(time.Now().Add(-2 * time.Second).UnixNano())
+	if got := proxy.restartETA(); got < 2900*time.Millisecond || got > 3*time.Second {
+		t.Errorf("Expected about 3s left of a 5s restart begun 2s ago, got %s", got)
+	}
+}
@@ -0,0 +1,128 @@
+package mcpproxy
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// requestTimeout returns the deadline for r, which carries msg: its
+// X-Request-Timeout header (a duration such as "30s") capped at
+// Config.MaxRequestTimeout, or if the header is missing or malformed the
+// tool's entry in Config.ToolTimeouts for a tools/call, else
+// Config.RequestTimeout. 0 means none.
+func (p *MCPProxy) requestTimeout(r *http.Request, msg json.RawMessage) time.Duration {
+	v := r.Header.Get("X-Request-Timeout")
+	if v == "" {
+		return p.defaultTimeout(msg)
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		p.logger.debugf("Ignoring invalid X-Request-Timeout %q", v)
+		return p.defaultTimeout(msg)
+	}
+	if max := p.config.MaxRequestTimeout; max > 0 && d > max {
+		d = max
+	}
+	return d
+}
+
+// defaultTimeout returns the deadline for msg when its client didn't ask for
+// one: Config.ToolTimeouts for the tool a tools/call names, or its "default"
+// entry, else Config.RequestTimeout.
+func (p *MCPProxy) defaultTimeout(msg json.RawMessage) time.Duration {
+	if len(p.config.ToolTimeouts) == 0 {
+		return p.config.RequestTimeout
+	}
+	tool, ok := calledTool(msg)
+	if !ok {
+		return p.config.RequestTimeout
+	}
+	if d, ok := p.config.ToolTimeouts[tool]; ok {
+		return d
+	}
+	if d, ok := p.config.ToolTimeouts["default"]; ok {
+		return d
+	}
+	return p.config.RequestTimeout
+}
+
+// parseToolTimeouts parses MCP_TOOL_TIMEOUTS, comma-separated tool=duration
+// pairs such as "run_sql=300s,default=30s".
+func parseToolTimeouts(s string) (map[string]time.Duration, error) {
+	pairs, err := parseToolPairs(s)
+	if err != nil {
+		return nil, err
+	}
+	timeouts := make(map[string]time.Duration, len(pairs))
+	for tool, value := range pairs {
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid timeout %q for tool %q: must be a positive duration such as 30s", value, tool)
+		}
+		timeouts[tool] = d
+	}
+	return timeouts, nil
+}
+
+// parseToolPairs splits comma-separated tool=value pairs, trimming spaces.
+func parseToolPairs(s string) (map[string]string, error) {
+	pairs := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		tool, value, ok := strings.Cut(pair, "=")
+		tool = strings.TrimSpace(tool)
+		if !ok || tool == "" {
+			return nil, fmt.Errorf("%q is not a tool=value pair", pair)
+		}
+		pairs[tool] = strings.TrimSpace(value)
+	}
+	return pairs, nil
+}
+
+// cancelRequest tells the MCP server to stop working on msg, whose client
+// stopped waiting for the given cause. If msg timed out, the server is killed
+// unless answered is closed within Config.CancelKillGrace. cmd and w are the
+// process msg was sent to.
+func (p *MCPProxy) cancelRequest(cmd *exec.Cmd, w *bufio.Writer, msg json.RawMessage, cause error, answered <-chan struct{}) {
+	reason := cancelReason(cause)
+	p.logger.warnf("Cancelling request %s: %s", formatID(messageID(msg)), reason)
+	note, _ := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  "notifications/cancelled",
+		"params": map[string]interface{}{
+			"requestId": rawID(msg),
+			"reason":    reason,
+		},
+	})
+
+	// The write may block on a server that stopped reading
+	go func() {
+		p.writeMu.Lock()
+		defer p.writeMu.Unlock()
+		if err := writeLine(w, note, p.delimiter); err != nil {
+			p.logger.debugf("Failed to send cancellation: %v", err)
+		}
+	}()
+
+	// A client that disconnected doesn't get to restart a server other
+	// clients are using
+	grace := p.config.CancelKillGrace
+	if grace <= 0 || !errors.Is(cause, context.DeadlineExceeded) {
+		return
+	}
+	select {
+	case <-answered:
+	case <-time.After(grace):
+		p.logger.errorf("MCP server did not answer timed out request %s within %s, killing it", formatID(messageID(msg)), grace)
+		cmd.Process.Kill()
+	}
+}
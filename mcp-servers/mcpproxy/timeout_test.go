@@ -0,0 +1,202 @@
+package mcpproxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequestTimeout(t *testing.T) {
+	p := &MCPProxy{
+		config: Config{RequestTimeout: 30 * time.Second, MaxRequestTimeout: time.Minute},
+		logger: newLogger("test", "warn"),
+	}
+	tests := []struct {
+		header string
+		want   time.Duration
+	}{
+		{"", 30 * time.Second},
+		{"2s", 2 * time.Second},
+		{"1500ms", 1500 * time.Millisecond},
+		{"1h", time.Minute},
+		{"soon", 30 * time.Second},
+		{"10", 30 * time.Second},
+		{"-5s", 30 * time.Second},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("POST", "/", nil)
+		if tt.header != "" {
+			r.Header.Set("X-Request-Timeout", tt.header)
+		}
+		if got := p.requestTimeout(r, nil); got != tt.want {
+			t.Errorf("X-Request-Timeout %q: got %s, want %s", tt.header, got, tt.want)
+		}
+	}
+
+	// A negative maximum allows any timeout
+	p.config.MaxRequestTimeout = -1
+	r := httptest.NewRequest("POST", "/", nil)
+	r.Header.Set("X-Request-Timeout", "1h")
+	if got := p.requestTimeout(r, nil); got != time.Hour {
+		t.Errorf("Expected an uncapped timeout, got %s", got)
+	}
+}
+
+func postWithTimeout(p *MCPProxy, body, timeout string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/", strings.NewReader(body))
+	r.Header.Set("X-Request-Timeout", timeout)
+	p.Handle(w, r)
+	return w
+}
+
+func TestHandleRequestTimeoutCancels(t *testing.T) {
+	// Answers the first request only once it is cancelled, then echoes
+	proxy, err := NewMCPProxy(Config{
+		ServerName:  "test",
+		CommandPath: "sh",
+		CommandArgs: []string{"-c", `read req; read cancel; echo "$cancel" >&2; echo '{"jsonrpc":"2.0","id":1,"error":{"code":-32800,"message":"cancelled"}}'; exec cat`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+
+	start := time.Now()
+	w := postWithTimeout(proxy, `{"jsonrpc":"2.0","id":1,"method":"tools/call"}`, "100ms")
+	if w.Code != http.StatusGatewayTimeout || !strings.Contains(w.Body.String(), `"code":-32003`) {
+		t.Errorf("Expected 504 with code -32003, got %d: %s", w.Code, w.Body.String())
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the request to time out after 100ms, took %s", elapsed)
+	}
+
+	// The late answer is consumed, so the next request gets its own response
+	if w := ping(proxy); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"method":"ping"`) {
+		t.Errorf("Expected the echoed ping, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// The stderr reader may not have stored the line yet
+	var lines string
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		lines = strings.Join(proxy.stderr.snapshot(), "\n")
+		if strings.Contains(lines, `"method":"notifications/cancelled"`) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !strings.Contains(lines, `"method":"notifications/cancelled"`) || !strings.Contains(lines, `"requestId":1`) {
+		t.Errorf("Expected the server to be sent a cancellation for request 1, got %q", lines)
+	}
+}
+
+func TestHandleRequestTimeoutKillsUnresponsiveServer(t *testing.T) {
+	proxy, err := NewMCPProxy(Config{
+		ServerName:      "test",
+		CommandPath:     "sh",
+		CommandArgs:     []string{"-c", `read req; exec sleep 60`},
+		MaxRestarts:     -1,
+		CancelKillGrace: 100 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+
+	if w := postWithTimeout(proxy, `{"jsonrpc":"2.0","id":1,"method":"tools/call"}`, "100ms"); w.Code != http.StatusGatewayTimeout {
+		t.Errorf("Expected 504, got %d: %s", w.Code, w.Body.String())
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !proxy.exited.Load() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !proxy.exited.Load() {
+		t.Error("Expected a server that ignores the cancellation to be killed")
+	}
+}
+
+func TestHandleDisconnectDoesNotKillServer(t *testing.T) {
+	// Answers the cancelled request after the kill grace has passed
+	proxy, err := NewMCPProxy(Config{
+		ServerName:      "test",
+		CommandPath:     "sh",
+		CommandArgs:     []string{"-c", `read req; read cancel; sleep 0.5; echo '{"jsonrpc":"2.0","id":1,"result":{}}'; exec cat`},
+		MaxRestarts:     -1,
+		CancelKillGrace: 100 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	r := httptest.NewRequest("POST", "/", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/call"}`)).WithContext(ctx)
+	proxy.Handle(httptest.NewRecorder(), r)
+
+	time.Sleep(time.Second)
+	if proxy.exited.Load() {
+		t.Error("Expected a client disconnecting not to kill the server")
+	}
+}
+
+func TestHandleWithoutTimeoutWaits(t *testing.T) {
+	proxy, err := NewMCPProxy(Config{
+		ServerName:  "test",
+		CommandPath: "sh",
+		CommandArgs: []string{"-c", `read req; sleep 0.3; echo "$req"`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+
+	if w := ping(proxy); w.Code != http.StatusOK {
+		t.Errorf("Expected a slow answer to arrive without a timeout, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestParseToolTimeouts(t *testing.T) {
+	got, err := parseToolTimeouts(" run_sql=300s, default=30s ,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got["run_sql"] != 300*time.Second || got["default"] != 30*time.Second {
+		t.Errorf("Unexpected timeouts %v", got)
+	}
+	for _, bad := range []string{"run_sql", "=30s", "run_sql=soon", "run_sql=-1s", "run_sql=0s"} {
+		if _, err := parseToolTimeouts(bad); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
+
+func TestHandleToolTimeouts(t *testing.T) {
+	t.Setenv("MCP_TOOL_TIMEOUTS", "run_sql=5s,default=100ms")
+	cfg := slowEchoServer
+	cfg.RequestTimeout = 100 * time.Millisecond
+	proxy, err := NewMCPProxy(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+
+	// The server takes 300ms to answer
+	call := func(tool string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		body := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"` + tool + `"}}`
+		proxy.Handle(w, httptest.NewRequest("POST", "/", strings.NewReader(body)))
+		return w
+	}
+	if w := call("run_sql"); w.Code != http.StatusOK {
+		t.Errorf("Expected the slow tool to finish within its timeout, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := call("list_tables"); w.Code != http.StatusGatewayTimeout {
+		t.Errorf("Expected the other tool to time out, got %d: %s", w.Code, w.Body.String())
+	}
+}
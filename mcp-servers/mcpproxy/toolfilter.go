@@ -0,0 +1,114 @@
+package mcpproxy
+
+import (
+	"encoding/json"
+)
+
+// toolFilter restricts which tools clients may see and call.
+// A nil toolFilter allows everything.
+type toolFilter struct {
+	allowed map[string]bool
+	denied  map[string]bool
+}
+
+// newToolFilter returns a filter for the given allow and deny lists,
+// or nil if both are empty.
+func newToolFilter(allowed, denied []string) *toolFilter {
+	if len(allowed) == 0 && len(denied) == 0 {
+		return nil
+	}
+	f := &toolFilter{denied: toSet(denied)}
+	if len(allowed) > 0 {
+		f.allowed = toSet(allowed)
+	}
+	return f
+}
+
+func toSet(names []string) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[name] = true
+	}
+	return set
+}
+
+// allows reports whether the named tool may be listed and called.
+// The deny list always wins; when an allow list is set, only its tools are permitted.
+func (f *toolFilter) allows(name string) bool {
+	if f == nil {
+		return true
+	}
+	if f.denied[name] {
+		return false
+	}
+	return f.allowed == nil || f.allowed[name]
+}
+
+// blockedTool returns the tool name if msg is a tools/call for a tool
+// that isn't allowed, or "" if the message may be forwarded.
+func (f *toolFilter) blockedTool(msg json.RawMessage) string {
+	if f == nil {
+		return ""
+	}
+	var call struct {
+		Method string `json:"method"`
+		Params struct {
+			Name string `json:"name"`
+		} `json:"params"`
+	}
+	if err := json.Unmarshal(msg, &call); err != nil || call.Method != "tools/call" {
+		return ""
+	}
+	if f.allows(call.Params.Name) {
+		return ""
+	}
+	return call.Params.Name
+}
+
+// filterList removes disallowed tools from a tools/list response.
+// Responses that don't have the expected shape are returned unchanged.
+func (f *toolFilter) filterList(response json.RawMessage) json.RawMessage {
+	if f == nil {
+		return response
+	}
+
+	var resp map[string]json.RawMessage
+	if err := json.Unmarshal(response, &resp); err != nil || resp["result"] == nil {
+		return response
+	}
+	var result map[string]json.RawMessage
+	if err := json.Unmarshal(resp["result"], &result); err != nil || result["tools"] == nil {
+		return response
+	}
+	var tools []json.RawMessage
+	if err := json.Unmarshal(result["tools"], &tools); err != nil {
+		return response
+	}
+
+	kept := make([]json.RawMessage, 0, len(tools))
+	for _, tool := range tools {
+		var t struct {
+			Name string `json:"name"`
+		}
+		json.Unmarshal(tool, &t)
+		if f.allows(t.Name) {
+			kept = append(kept, tool)
+		}
+	}
+	if len(kept) == len(tools) {
+		return response
+	}
+
+	var err error
+	if result["tools"], err = json.Marshal(kept); err != nil {
+		return response
+	}
+	if resp["result"], err = json.Marshal(result); err != nil {
+		return response
+	}
+	filtered, err := json.Marshal(resp)
+	if err != nil {
+		return response
+	}
+	return filtered
+}
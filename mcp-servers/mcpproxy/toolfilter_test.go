@@ -0,0 +1,131 @@
+package mcpproxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestToolFilterAllows(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		denied  []string
+		tool    string
+		want    bool
+	}{
+		{"no lists", nil, nil, "anything", true},
+		{"allowed", []string{"read_file"}, nil, "read_file", true},
+		{"not in allow list", []string{"read_file"}, nil, "delete_repository", false},
+		{"denied", nil, []string{"delete_repository"}, "delete_repository", false},
+		{"not denied", nil, []string{"delete_repository"}, "read_file", true},
+		{"deny wins over allow", []string{"run_sql"}, []string{"run_sql"}, "run_sql", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newToolFilter(tt.allowed, tt.denied)
+			if got := f.allows(tt.tool); got != tt.want {
+				t.Errorf("allows(%q) = %v, want %v", tt.tool, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleBlocksDeniedToolCall(t *testing.T) {
+	proxy := &MCPProxy{
+		config:   Config{ServerName: "test", DeniedTools: []string{"delete_repository"}},
+		requests: make(chan *request, 1),
+	}
+
+	body := `{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"delete_repository","arguments":{}}}`
+	req := httptest.NewRequest("POST", "/", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	proxy.Handle(w, req)
+
+	if len(proxy.requests) != 0 {
+		t.Fatal("Expected blocked call not to be forwarded to the MCP server")
+	}
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 with JSON-RPC error, got %d", w.Code)
+	}
+
+	var resp struct {
+		Error struct {
+			Code int `json:"code"`
+		} `json:"error"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Error.Code != CodeNotAllowed {
+		t.Errorf("Expected error code %d, got %d", CodeNotAllowed, resp.Error.Code)
+	}
+}
+
+func TestHandleFiltersToolsList(t *testing.T) {
+	proxy := &MCPProxy{
+		config: Config{
+			ServerName:   "test",
+			AllowedTools: []string{"read_file", "delete_repository"},
+			DeniedTools:  []string{"delete_repository"},
+		},
+		requests: make(chan *request, 1),
+	}
+	drainRequests(proxy, func(json.RawMessage) json.RawMessage {
+		return json.RawMessage(`{"jsonrpc":"2.0","id":1,"result":{"tools":[{"name":"read_file"},{"name":"delete_repository"},{"name":"write_file"}]}}`)
+	})
+	defer close(proxy.requests)
+
+	req := httptest.NewRequest("POST", "/", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`))
+	w := httptest.NewRecorder()
+	proxy.Handle(w, req)
+
+	var resp struct {
+		Result struct {
+			Tools []struct {
+				Name string `json:"name"`
+			} `json:"tools"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response %q: %v", w.Body.String(), err)
+	}
+
+	var names []string
+	for _, tool := range resp.Result.Tools {
+		names = append(names, tool.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"read_file"}) {
+		t.Errorf("Expected only read_file to be listed, got %v", names)
+	}
+}
+
+func TestFilterListLeavesOtherShapes(t *testing.T) {
+	f := newToolFilter(nil, []string{"x"})
+	for _, resp := range []string{
+		`{"jsonrpc":"2.0","id":1,"error":{"code":-1,"message":"boom"}}`,
+		`{"jsonrpc":"2.0","id":1,"result":{"resources":[]}}`,
+		`not json`,
+	} {
+		if got := string(f.filterList(json.RawMessage(resp))); got != resp {
+			t.Errorf("filterList(%q) = %q, want unchanged", resp, got)
+		}
+	}
+}
+
+func TestConfigToolListsFromEnv(t *testing.T) {
+	os.Setenv("MCP_ALLOWED_TOOLS", "read_file, list_issues,")
+	defer os.Unsetenv("MCP_ALLOWED_TOOLS")
+
+	cfg := Config{}
+	cfg.applyDefaults()
+	if !reflect.DeepEqual(cfg.AllowedTools, []string{"read_file", "list_issues"}) {
+		t.Errorf("Unexpected allowed tools %v", cfg.AllowedTools)
+	}
+	if cfg.DeniedTools != nil {
+		t.Errorf("Expected no denied tools, got %v", cfg.DeniedTools)
+	}
+}
@@ -0,0 +1,101 @@
+package mcpproxy
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// toolsCache holds the last tools/list result from one MCP server process.
+// Each process (and so each session) has its own cache, so servers whose
+// tool set depends on their credentials are never served another session's
+// list. The cache is dropped when the server announces
+// notifications/tools/list_changed.
+//
+// A nil *toolsCache caches nothing.
+type toolsCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu       sync.Mutex
+	response json.RawMessage
+	expires  time.Time
+}
+
+// newToolsCache returns a cache, or nil if ttl disables caching.
+func newToolsCache(ttl time.Duration) *toolsCache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &toolsCache{ttl: ttl, now: time.Now}
+}
+
+// get returns the cached response, or nil if there is none or it expired.
+func (c *toolsCache) get() json.RawMessage {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.response == nil || !c.now().Before(c.expires) {
+		return nil
+	}
+	return c.response
+}
+
+// put caches response if it is a successful result.
+func (c *toolsCache) put(response json.RawMessage) {
+	if c == nil {
+		return
+	}
+	var resp struct {
+		Result json.RawMessage `json:"result"`
+		Error  json.RawMessage `json:"error"`
+	}
+	if err := json.Unmarshal(response, &resp); err != nil || resp.Result == nil || resp.Error != nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.response = response
+	c.expires = c.now().Add(c.ttl)
+}
+
+func (c *toolsCache) invalidate() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.response = nil
+}
+
+// cacheableToolsList reports whether msg is a tools/list request for the
+// first page; paginated requests with a cursor always go to the server.
+func cacheableToolsList(msg json.RawMessage) bool {
+	var req struct {
+		Method string `json:"method"`
+		Params struct {
+			Cursor string `json:"cursor"`
+		} `json:"params"`
+	}
+	if err := json.Unmarshal(msg, &req); err != nil {
+		return false
+	}
+	return req.Method == "tools/list" && req.Params.Cursor == ""
+}
+
+// withID returns response with its id replaced, so a cached response can
+// answer a request with a different id.
+func withID(response, id json.RawMessage) json.RawMessage {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(response, &members); err != nil {
+		return response
+	}
+	members["id"] = id
+	out, err := json.Marshal(members)
+	if err != nil {
+		return response
+	}
+	return out
+}
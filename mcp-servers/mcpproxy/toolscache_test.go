@@ -0,0 +1,99 @@
+package mcpproxy
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestToolsCacheHitAndExpiry(t *testing.T) {
+	now := time.Now()
+	c := newToolsCache(time.Minute)
+	c.now = func() time.Time { return now }
+
+	if c.get() != nil {
+		t.Fatal("Expected an empty cache")
+	}
+	c.put(json.RawMessage(`{"jsonrpc":"2.0","id":1,"result":{"tools":[]}}`))
+	if c.get() == nil {
+		t.Fatal("Expected a cache hit")
+	}
+
+	now = now.Add(time.Minute)
+	if c.get() != nil {
+		t.Error("Expected the entry to expire after the TTL")
+	}
+}
+
+func TestToolsCacheSkipsErrors(t *testing.T) {
+	c := newToolsCache(time.Minute)
+	c.put(json.RawMessage(`{"jsonrpc":"2.0","id":1,"error":{"code":-32603,"message":"boom"}}`))
+	if c.get() != nil {
+		t.Error("Expected error responses not to be cached")
+	}
+}
+
+func TestCacheableToolsList(t *testing.T) {
+	tests := map[string]bool{
+		`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`:                           true,
+		`{"jsonrpc":"2.0","id":1,"method":"tools/list","params":{}}`:               true,
+		`{"jsonrpc":"2.0","id":1,"method":"tools/list","params":{"cursor":"abc"}}`: false,
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call"}`:                           false,
+	}
+	for msg, want := range tests {
+		if got := cacheableToolsList(json.RawMessage(msg)); got != want {
+			t.Errorf("cacheableToolsList(%s) = %v, want %v", msg, got, want)
+		}
+	}
+}
+
+func TestHandleServesToolsListFromCache(t *testing.T) {
+	var calls atomic.Int32
+	proxy := &MCPProxy{
+		config:     Config{ServerName: "test"},
+		requests:   make(chan *request, 1),
+		toolsCache: newToolsCache(time.Minute),
+	}
+	drainRequests(proxy, func(json.RawMessage) json.RawMessage {
+		calls.Add(1)
+		return json.RawMessage(`{"jsonrpc":"2.0","id":1,"result":{"tools":[{"name":"run-sql"}]}}`)
+	})
+	defer close(proxy.requests)
+
+	list := func(id string) string {
+		w := httptest.NewRecorder()
+		proxy.Handle(w, httptest.NewRequest("POST", "/", strings.NewReader(`{"jsonrpc":"2.0","id":`+id+`,"method":"tools/list"}`)))
+		return w.Body.String()
+	}
+
+	list("1")
+	got := list(`"second"`)
+	if calls.Load() != 1 {
+		t.Errorf("Expected the second tools/list to be served from cache, server called %d times", calls.Load())
+	}
+	if !strings.Contains(got, `"id":"second"`) || !strings.Contains(got, "run-sql") {
+		t.Errorf("Expected the cached tools with the request's id, got %s", got)
+	}
+}
+
+func TestListChangedInvalidatesCache(t *testing.T) {
+	stdout := `{"jsonrpc":"2.0","method":"notifications/tools/list_changed"}` + "\n" +
+		`{"jsonrpc":"2.0","id":2,"result":{}}` + "\n"
+	proxy := &MCPProxy{
+		config:     Config{ServerName: "test", SkipNotifications: true},
+		stdout:     bufio.NewReader(strings.NewReader(stdout)),
+		toolsCache: newToolsCache(time.Minute),
+	}
+	proxy.toolsCache.put(json.RawMessage(`{"jsonrpc":"2.0","id":1,"result":{"tools":[]}}`))
+
+	if _, err := proxy.readResponse(json.RawMessage(`{"jsonrpc":"2.0","id":2,"method":"tools/call"}`), ""); err != nil {
+		t.Fatal(err)
+	}
+	if proxy.toolsCache.get() != nil {
+		t.Error("Expected notifications/tools/list_changed to invalidate the cache")
+	}
+}
@@ -0,0 +1,55 @@
+package mcpproxy
+
+import (
+	"net/http"
+	"regexp"
+	"time"
+)
+
+// traceparentPattern matches a W3C trace context traceparent header:
+// version-traceid-parentid-flags in lowercase hex.
+var traceparentPattern = regexp.MustCompile(`^[0-9a-f]{2}-[0-9a-f]{32}-[0-9a-f]{16}-[0-9a-f]{2}$`)
+
+// maxTracestateLen bounds the tracestate passed on, as recommended by the spec.
+const maxTracestateLen = 512
+
+// traceContext returns the request's traceparent and tracestate headers, or
+// empty strings if the traceparent is missing or malformed. A tracestate is
+// only meaningful alongside a valid traceparent.
+func traceContext(r *http.Request) (traceparent, tracestate string) {
+	traceparent = r.Header.Get("traceparent")
+	if !traceparentPattern.MatchString(traceparent) || traceparent[:2] == "ff" ||
+		traceparent[3:35] == "00000000000000000000000000000000" || traceparent[36:52] == "0000000000000000" {
+		return "", ""
+	}
+	tracestate = r.Header.Get("tracestate")
+	if len(tracestate) > maxTracestateLen {
+		tracestate = ""
+	}
+	return traceparent, tracestate
+}
+
+// traceEnv returns the request's trace context as TRACEPARENT and TRACESTATE
+// environment entries, for MCP server processes started on its behalf.
+func traceEnv(r *http.Request) []string {
+	traceparent, tracestate := traceContext(r)
+	if traceparent == "" {
+		return nil
+	}
+	env := []string{"TRACEPARENT=" + traceparent}
+	if tracestate != "" {
+		env = append(env, "TRACESTATE="+tracestate)
+	}
+	return env
+}
+
+// logSpan logs a span-like record of a handled message so operators can
+// correlate proxy logs with the caller's trace.
+func (p *MCPProxy) logSpan(r *http.Request, method string, id interface{}, start time.Time) {
+	if !p.logger.enabled(levelDebug) {
+		return
+	}
+	traceparent, _ := traceContext(r)
+	p.logger.debugf("span mcp_method=%s mcp_id=%s duration=%s traceparent=%s",
+		method, formatID(id), time.Since(start).Round(time.Microsecond), traceparent)
+}
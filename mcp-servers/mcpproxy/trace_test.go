@@ -0,0 +1,96 @@
+package mcpproxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestTraceEnv(t *testing.T) {
+	tests := []struct {
+		name        string
+		traceparent string
+		tracestate  string
+		want        []string
+	}{
+		{"none", "", "", nil},
+		{"traceparent only", testTraceparent, "", []string{"TRACEPARENT=" + testTraceparent}},
+		{"with tracestate", testTraceparent, "rojo=00f067aa0ba902b7", []string{"TRACEPARENT=" + testTraceparent, "TRACESTATE=rojo=00f067aa0ba902b7"}},
+		{"malformed", "00-abc-def-01", "rojo=1", nil},
+		{"uppercase hex", strings.ToUpper(testTraceparent), "", nil},
+		{"invalid version", "ff" + testTraceparent[2:], "", nil},
+		{"zero trace id", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", "", nil},
+		{"oversized tracestate", testTraceparent, strings.Repeat("a", maxTracestateLen+1), []string{"TRACEPARENT=" + testTraceparent}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/", nil)
+			if tt.traceparent != "" {
+				r.Header.Set("traceparent", tt.traceparent)
+			}
+			if tt.tracestate != "" {
+				r.Header.Set("tracestate", tt.tracestate)
+			}
+			if got := traceEnv(r); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("traceEnv() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSessionInheritsTraceContext(t *testing.T) {
+	cfg := Config{
+		ServerName:  "test",
+		CommandPath: "sh",
+		CommandArgs: []string{"-c", `while read line; do echo "{\"jsonrpc\":\"2.0\",\"id\":1,\"result\":{\"traceparent\":\"$TRACEPARENT\"}}"; done`},
+		SessionFunc: headerSession,
+	}
+	proxy, err := NewMCPProxy(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+
+	req := httptest.NewRequest("POST", "/", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`))
+	req.Header.Set("X-Token", "alice")
+	req.Header.Set("traceparent", testTraceparent)
+	w := httptest.NewRecorder()
+	proxy.Handle(w, req)
+
+	if !strings.Contains(w.Body.String(), testTraceparent) {
+		t.Errorf("Expected session process to see TRACEPARENT, got %s", w.Body.String())
+	}
+}
+
+func TestHandleLogsSpan(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	proxy := &MCPProxy{
+		config:   Config{ServerName: "test"},
+		logger:   newLogger("test", "debug"),
+		requests: make(chan *request, 1),
+	}
+	drainRequests(proxy, func(json.RawMessage) json.RawMessage {
+		return json.RawMessage(`{"jsonrpc":"2.0","id":7,"result":{}}`)
+	})
+	defer close(proxy.requests)
+
+	req := httptest.NewRequest("POST", "/", strings.NewReader(`{"jsonrpc":"2.0","id":7,"method":"tools/list"}`))
+	req.Header.Set("traceparent", testTraceparent)
+	proxy.Handle(httptest.NewRecorder(), req)
+
+	want := "mcp_method=tools/list mcp_id=7 "
+	if !strings.Contains(buf.String(), want) || !strings.Contains(buf.String(), "traceparent="+testTraceparent) {
+		t.Errorf("Expected span log with %q and the traceparent, got:\n%s", want, buf.String())
+	}
+}
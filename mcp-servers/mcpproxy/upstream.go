@@ -0,0 +1,217 @@
+package mcpproxy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Backend types for Config.BackendType.
+const (
+	backendStdio = "stdio"
+	backendHTTP  = "http"
+)
+
+// upstream forwards messages to an MCP server that speaks streamable HTTP,
+// for Config.BackendType "http". Like a stdio server, it is shared by every
+// client, so the session id it assigns is sent with every message.
+type upstream struct {
+	url    string
+	client *http.Client
+
+	mu        sync.Mutex
+	sessionID string
+}
+
+func newUpstream(rawURL string) *upstream {
+	return &upstream{url: rawURL, client: &http.Client{}}
+}
+
+// validateHTTPURL checks that rawURL, the value of the setting name, is an
+// absolute http(s) URL, so a typo fails at startup rather than on first use.
+func validateHTTPURL(name, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		// The error quotes the URL, which may contain a password
+		return fmt.Errorf("invalid %s: not a valid URL", name)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid %s %q: must be an http or https URL", name, u.Redacted())
+	}
+	return nil
+}
+
+// redactURL returns rawURL with any password masked, for logging.
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Redacted()
+}
+
+// send posts msg to the upstream server and returns its response, or nil for
+// a notification. Server notifications streamed before the response are
+// passed to observe.
+func (u *upstream) send(ctx context.Context, msg json.RawMessage, observe func(json.RawMessage)) (json.RawMessage, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.url, bytes.NewReader(msg))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	u.mu.Lock()
+	if u.sessionID != "" {
+		req.Header.Set("Mcp-Session-Id", u.sessionID)
+	}
+	u.mu.Unlock()
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if id := resp.Header.Get("Mcp-Session-Id"); id != "" {
+		u.mu.Lock()
+		u.sessionID = id
+		u.mu.Unlock()
+	}
+	if resp.StatusCode == http.StatusNotFound && req.Header.Get("Mcp-Session-Id") != "" {
+		// The upstream forgot the session; the next initialize starts a new one
+		u.reset()
+		return nil, errors.New("upstream MCP session expired")
+	}
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("upstream MCP server returned %s", resp.Status)
+	}
+	if !hasID(msg) {
+		return nil, nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == "text/event-stream" {
+		return readEventStream(resp.Body, observe)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading upstream response: %w", err)
+	}
+	return bytes.TrimSpace(body), nil
+}
+
+// readEventStream returns the first message with an id from a Server-Sent
+// Events stream, passing notifications before it to observe.
+func readEventStream(r io.Reader, observe func(json.RawMessage)) (json.RawMessage, error) {
+	br := bufio.NewReader(r)
+	var data []byte
+	for {
+		line, err := br.ReadBytes('\n')
+		line = bytes.TrimRight(line, "\r\n")
+		switch {
+		case bytes.HasPrefix(line, []byte("data:")):
+			data = append(data, bytes.TrimPrefix(bytes.TrimPrefix(line, []byte("data:")), []byte(" "))...)
+		case len(line) == 0 && len(data) > 0:
+			msg := json.RawMessage(data)
+			if hasID(msg) {
+				return msg, nil
+			}
+			observe(msg)
+			data = nil
+		}
+		if err != nil {
+			return nil, errors.New("upstream event stream ended without a response")
+		}
+	}
+}
+
+// reset forgets the upstream session.
+func (u *upstream) reset() {
+	u.mu.Lock()
+	u.sessionID = ""
+	u.mu.Unlock()
+}
+
+// forwardRequests sends queued messages to the upstream server. Unlike a
+// stdio pipe it can handle several at once.
+func (p *MCPProxy) forwardRequests() {
+	for req := range p.requests {
+		go p.forward(req)
+	}
+}
+
+// forward sends one message to the upstream server and delivers its response.
+// A failed request gets req.err instead.
+func (p *MCPProxy) forward(req *request) {
+	defer close(req.response)
+
+	msg, rejection, rejected := p.config.applyRequestMiddlewares(req.msg)
+	if rejected {
+		p.logger.debugf("[%s] Request middleware rejected message %s", req.correlationID, formatID(messageID(req.msg)))
+		if rejection != nil {
+			req.response <- rejection
+		}
+		return
+	}
+	if p.logger.enabled(levelDebug) {
+		p.logger.debugf("[%s] Sending: %s", req.correlationID, p.payloadForLog(msg))
+	}
+
+	ctx := req.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if ctx.Err() != nil {
+		p.logger.debugf("[%s] Dropping request %s: the client stopped waiting", req.correlationID, formatID(messageID(msg)))
+		return
+	}
+
+	response, err := p.upstream.send(ctx, msg, p.observeNotification)
+	if err != nil {
+		if ctx.Err() != nil {
+			go p.cancelUpstream(msg, context.Cause(ctx))
+			return
+		}
+		p.logger.errorf("[%s] Upstream MCP request failed: %v", req.correlationID, err)
+		req.err = err
+		return
+	}
+	if !req.isRequest {
+		return
+	}
+	if p.logger.enabled(levelDebug) {
+		p.logger.debugf("[%s] Received: %s", req.correlationID, p.payloadForLog(response))
+	}
+	p.observeInitialize(msg, response)
+	req.response <- p.config.applyResponseMiddlewares(response)
+}
+
+// cancelUpstream tells the upstream server to stop working on msg, whose
+// client stopped waiting for the given cause.
+func (p *MCPProxy) cancelUpstream(msg json.RawMessage, cause error) {
+	reason := cancelReason(cause)
+	p.logger.warnf("Cancelling request %s: %s", formatID(messageID(msg)), reason)
+	note, _ := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  "notifications/cancelled",
+		"params": map[string]interface{}{
+			"requestId": rawID(msg),
+			"reason":    reason,
+		},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := p.upstream.send(ctx, note, p.observeNotification); err != nil {
+		p.logger.debugf("Failed to send cancellation: %v", err)
+	}
+}
@@ -0,0 +1,185 @@
+package mcpproxy
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Problem is a configuration problem an adapter found in its own settings,
+// with a hint on how to fix it, for Config.Problems.
+type Problem struct {
+	Err  error
+	Hint string
+}
+
+// configError is a configuration problem found at startup, with a hint on
+// how to fix it.
+type configError struct {
+	err  error
+	hint string
+}
+
+// configErrors collects every configuration problem found at startup, so
+// they can be fixed in one go rather than one restart at a time.
+type configErrors []configError
+
+// add records err, if not nil, with a hint on how to fix it.
+func (e *configErrors) add(err error, hint string) {
+	if err != nil {
+		*e = append(*e, configError{err: err, hint: hint})
+	}
+}
+
+// err returns e as an error, or nil if no problem was found.
+func (e configErrors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e configErrors) Error() string {
+	if len(e) == 1 {
+		return fmt.Sprintf("%v (%s)", e[0].err, e[0].hint)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d configuration problems:", len(e))
+	for _, ce := range e {
+		fmt.Fprintf(&b, "\n  - %v (%s)", ce.err, ce.hint)
+	}
+	return b.String()
+}
+
+// Unwrap returns the underlying errors, for errors.Is and errors.As.
+func (e configErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, ce := range e {
+		errs[i] = ce.err
+	}
+	return errs
+}
+
+// validate resolves the settings of c that NewMCPProxy parses from the
+// environment, the command, its arguments and extra environment, the
+// per-tool settings and the trusted proxies, and checks the rest. It returns
+// every problem found. c must already have its defaults applied.
+func (c *Config) validate() (trustedProxies, configErrors) {
+	errs := append(configErrors(nil), c.envErrs...)
+	for _, p := range c.Problems {
+		errs.add(p.Err, p.Hint)
+	}
+	// Read when the command is started or the env file loaded, but checked here
+	envBool(&errs, "MCP_EXPAND_ENV", false)
+	envBool(&errs, "MCP_ENV_FILE_EXPORT", false)
+
+	if c.ListenUnix == "" {
+		errs.add(validateListenAddr(c.listenAddr()), "set LISTEN_ADDR to host:port or PORT to a port number, e.g. :8080")
+	}
+	if c.AdminPort != "" {
+		if err := validateListenAddr(":" + c.AdminPort); err != nil {
+			errs.add(fmt.Errorf("invalid ADMIN_PORT: %w", err), "set ADMIN_PORT to a port number, e.g. 9090")
+		}
+	}
+
+	// Check for path override from environment
+	cmdPath := commandPath(*c)
+
+	// Fail with a clear message rather than a generic error from cmd.Start()
+	switch c.BackendType {
+	case backendStdio:
+		hint := "install the MCP server in the image or set MCP_COMMAND to its path"
+		if os.Getenv("MCP_COMMAND") == "" && c.PathEnvVar != "" {
+			hint = fmt.Sprintf("install the MCP server in the image or set %s or MCP_COMMAND to its path", c.PathEnvVar)
+		}
+		errs.add(validateCommand(cmdPath), hint)
+	case backendHTTP:
+		errs.add(validateHTTPURL("MCP_UPSTREAM_URL", c.UpstreamURL), "set MCP_UPSTREAM_URL to the server's MCP endpoint, e.g. http://mcp-server:8000/mcp")
+	default:
+		errs.add(fmt.Errorf("invalid MCP_BACKEND_TYPE %q: must be %q or %q", c.BackendType, backendStdio, backendHTTP), "unset it to run the MCP server as a process")
+	}
+
+	args, err := commandArgs(*c)
+	if err != nil {
+		errs.add(fmt.Errorf("invalid MCP_ARGS: %w", err), `close every quote, or give MCP_ARGS as a JSON array such as ["--db","sales"]`)
+	}
+	if v := os.Getenv("MCP_EXTRA_ENV"); v != "" {
+		extra, err := parseExtraEnv(v)
+		if err != nil {
+			errs.add(fmt.Errorf("invalid MCP_EXTRA_ENV: %w", err), "give comma-separated KEY=VALUE pairs or @/path/to/file")
+		}
+		c.ExtraEnv = append(c.ExtraEnv, extra...)
+	}
+
+	if v := os.Getenv("MCP_TOOL_TIMEOUTS"); v != "" {
+		if c.ToolTimeouts, err = parseToolTimeouts(v); err != nil {
+			errs.add(fmt.Errorf("invalid MCP_TOOL_TIMEOUTS: %w", err), "give tool=duration pairs, e.g. run_sql=2m,default=30s")
+		}
+	}
+	if v := os.Getenv("MCP_TOOL_RATE_LIMITS"); v != "" {
+		if c.ToolRateLimits, err = parseToolRateLimits(v); err != nil {
+			errs.add(fmt.Errorf("invalid MCP_TOOL_RATE_LIMITS: %w", err), "give tool=requests-per-second pairs, e.g. run_sql=0.5")
+		}
+	}
+
+	if v := os.Getenv("MCP_ERROR_CODES"); v != "" {
+		if c.ErrorCodes, err = parseErrorCodes(v); err != nil {
+			errs.add(fmt.Errorf("invalid MCP_ERROR_CODES: %w", err), "give name=code pairs, e.g. rate_limited=-32029")
+		}
+	}
+
+	errs.add(validateWorkDir(c.WorkDir), "create the directory in the image or fix MCP_CWD")
+	if _, _, _, err := runAsIDs(c.RunAsUID, c.RunAsGID); err != nil {
+		errs.add(fmt.Errorf("invalid MCP_RUN_AS_UID or MCP_RUN_AS_GID: %w", err), "use a user and group that exist in the image, and run the proxy as root")
+	}
+	errs.add(validateNice(c.Nice), "use a niceness between -20 and 19, e.g. 10")
+	if c.CPUAffinity != "" {
+		if _, err := parseCPUList(c.CPUAffinity); err != nil {
+			errs.add(fmt.Errorf("invalid MCP_CPU_AFFINITY: %w", err), "list CPUs and ranges, e.g. 0-3,6")
+		}
+	}
+
+	if _, err := parseDelimiter(c.Delimiter); err != nil {
+		errs.add(fmt.Errorf("invalid MCP_DELIMITER: %w", err), `use characters or escapes such as \n, \0, \r\n or \x1e`)
+	}
+	if _, err := parseStopSignal(c.StopSignal); err != nil {
+		errs.add(fmt.Errorf("invalid MCP_STOP_SIGNAL: %w", err), "give steps such as stdin-close,SIGTERM")
+	}
+	if _, err := regexp.Compile(c.ReadyPattern); err != nil {
+		errs.add(fmt.Errorf("invalid MCP_READY_PROBE: %w", err), "use Go regular expression syntax, e.g. ^Server ready")
+	}
+	if c.CrashWebhookURL != "" {
+		errs.add(validateHTTPURL("CRASH_WEBHOOK_URL", c.CrashWebhookURL), "set CRASH_WEBHOOK_URL to an http or https URL")
+	}
+	if c.LogSkippedNotifications != "" {
+		if _, err := parseLogLevel(c.LogSkippedNotifications); err != nil {
+			errs.add(fmt.Errorf("invalid LOG_SKIPPED_NOTIFICATIONS: %w", err), "use debug, info, warn or error")
+		}
+	}
+	if c.RedactContentBytes < 0 {
+		errs.add(fmt.Errorf("invalid MCP_REDACT_CONTENT_BYTES %d: must not be negative", c.RedactContentBytes), "set it to 0 to remove contents by type only")
+	}
+	for _, t := range c.RedactContentTypes {
+		if _, _, err := mime.ParseMediaType(t); err != nil {
+			errs.add(fmt.Errorf("invalid MCP_REDACT_CONTENT_TYPES entry %q: %w", t, err), "list media types, e.g. application/pdf,image/*")
+		}
+	}
+	if c.RecentRequests < 0 {
+		errs.add(fmt.Errorf("invalid MCP_RECENT_REQUESTS %d: must not be negative", c.RecentRequests), "set it to 0 to keep no requests")
+	} else if c.RecentRequests > 0 && c.AdminToken == "" {
+		errs.add(errors.New("MCP_RECENT_REQUESTS requires ADMIN_TOKEN"), "set ADMIN_TOKEN, since /debug/recent shows request payloads")
+	}
+	if c.EnableDiagnostics && c.AdminPort == "" && c.AdminToken == "" {
+		errs.add(errors.New("ENABLE_DIAGNOSTICS requires ADMIN_TOKEN"), "set ADMIN_TOKEN, since /logs and /config show stderr and configuration, or set ADMIN_PORT to serve them there")
+	}
+	trusted, err := parseTrustedProxies(c.TrustedProxies)
+	errs.add(err, "list IP addresses or CIDR ranges, e.g. 10.0.0.0/8")
+
+	// Keep the resolved command so session processes and diagnostics use it
+	c.CommandPath = cmdPath
+	c.CommandArgs = args
+	return trusted, errs
+}
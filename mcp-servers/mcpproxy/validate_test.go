@@ -0,0 +1,66 @@
+package mcpproxy
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestNewMCPProxyReportsAllProblems(t *testing.T) {
+	t.Setenv("MCP_TOOL_TIMEOUTS", "run_sql=soon")
+	t.Setenv("MCP_QUEUE_SIZE", "lots")
+	t.Setenv("ENABLE_CORS", "sometimes")
+	t.Setenv("MCP_STOP_TIMEOUT", "5")
+	_, err := NewMCPProxy(Config{
+		ServerName:     "test",
+		CommandPath:    "no-such-mcp-server",
+		PathEnvVar:     "TEST_SERVER_PATH",
+		WorkDir:        "/no/such/dir",
+		Delimiter:      `\q`,
+		ReadyPattern:   "(",
+		TrustedProxies: []string{"not-an-ip"},
+		AdminPort:      "port",
+		Problems:       []Problem{{Err: errors.New("invalid TEST_ADAPTER_SETTING"), Hint: "fix the adapter setting"}},
+	})
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+
+	msg := err.Error()
+	if !strings.HasPrefix(msg, "11 configuration problems:") {
+		t.Errorf("Expected all 11 problems to be counted, got:\n%s", msg)
+	}
+	for _, want := range []string{
+		"invalid ADMIN_PORT",
+		"(install the MCP server in the image or set TEST_SERVER_PATH or MCP_COMMAND to its path)",
+		"invalid MCP_TOOL_TIMEOUTS",
+		`"/no/such/dir"`,
+		"invalid MCP_DELIMITER",
+		"invalid MCP_READY_PROBE",
+		"invalid TRUSTED_PROXIES",
+		`invalid MCP_QUEUE_SIZE "lots": not a whole number (use a whole number, e.g. 10)`,
+		`invalid ENABLE_CORS "sometimes": not a boolean (use true or false)`,
+		`invalid MCP_STOP_TIMEOUT "5": not a duration`,
+		"invalid TEST_ADAPTER_SETTING (fix the adapter setting)",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("Expected %q among the problems, got:\n%s", want, msg)
+		}
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Error("Expected the missing working directory to be found with errors.Is")
+	}
+}
+
+func TestConfigErrorsSingleProblem(t *testing.T) {
+	var errs configErrors
+	if errs.err() != nil {
+		t.Error("Expected no error without problems")
+	}
+	errs.add(nil, "ignored")
+	errs.add(errors.New("invalid X"), "set X to a number")
+	if got := errs.err().Error(); got != "invalid X (set X to a number)" {
+		t.Errorf("Unexpected message %q", got)
+	}
+}
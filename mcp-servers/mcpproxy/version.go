@@ -0,0 +1,108 @@
+package mcpproxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// Version and Commit identify the build on /version. Set them with
+//
+//	go build -ldflags "-X github.com/rh-ai-kickstart/ai-architecture-charts/mcp-servers/mcpproxy.Version=1.2.3 -X github.com/rh-ai-kickstart/ai-architecture-charts/mcp-servers/mcpproxy.Commit=abc123"
+//
+// Otherwise they are taken from the binary's build information, if any.
+var (
+	Version string
+	Commit  string
+)
+
+// serverVersion is what the MCP server said about itself in its last
+// initialize result.
+type serverVersion struct {
+	ProtocolVersion string          `json:"protocol_version"`
+	ServerInfo      json.RawMessage `json:"server_info,omitempty"`
+}
+
+// versionBody is the JSON body of /version.
+type versionBody struct {
+	Version   string         `json:"version"`
+	Commit    string         `json:"commit"`
+	GoVersion string         `json:"go_version"`
+	Server    *serverVersion `json:"server"` // null until the MCP server has been initialized
+}
+
+// buildVersion returns Version and Commit, falling back to the version of
+// this module and the VCS revision recorded in the binary.
+func buildVersion() (version, commit string) {
+	version, commit = Version, Commit
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return version, commit
+	}
+	if version == "" {
+		module := reflect.TypeOf(MCPProxy{}).PkgPath()
+		if info.Main.Path == module {
+			version = info.Main.Version
+		}
+		for _, dep := range info.Deps {
+			if dep.Path == module {
+				version = dep.Version
+			}
+		}
+	}
+	for _, s := range info.Settings {
+		if s.Key == "vcs.revision" && commit == "" {
+			commit = s.Value
+		}
+	}
+	return version, commit
+}
+
+// observeInitialize records the protocol version and server info from the
+// MCP server's answer to an initialize request, and warns if it isn't the
+// version the client asked for.
+func (p *MCPProxy) observeInitialize(msg, response json.RawMessage) {
+	if !bytes.Contains(msg, []byte(`"initialize"`)) {
+		return
+	}
+	var req struct {
+		Method string `json:"method"`
+		Params struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		} `json:"params"`
+	}
+	if json.Unmarshal(msg, &req) != nil || req.Method != "initialize" {
+		return
+	}
+	var resp struct {
+		Result *struct {
+			ProtocolVersion string          `json:"protocolVersion"`
+			ServerInfo      json.RawMessage `json:"serverInfo"`
+		} `json:"result"`
+	}
+	if json.Unmarshal(response, &resp) != nil || resp.Result == nil {
+		return
+	}
+
+	v := &serverVersion{
+		ProtocolVersion: strings.TrimSpace(resp.Result.ProtocolVersion),
+		ServerInfo:      resp.Result.ServerInfo,
+	}
+	p.serverVersion.Store(v)
+	if requested := strings.TrimSpace(req.Params.ProtocolVersion); requested != "" && requested != v.ProtocolVersion {
+		p.logger.warnf("Client asked for MCP protocol version %q, the MCP server answered with %q", requested, v.ProtocolVersion)
+	}
+}
+
+// HandleVersion serves the proxy's build information and the MCP protocol
+// version the MCP server last agreed to.
+func (p *MCPProxy) HandleVersion(w http.ResponseWriter, r *http.Request) {
+	body := versionBody{GoVersion: runtime.Version(), Server: p.serverVersion.Load()}
+	body.Version, body.Commit = buildVersion()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,60 @@
+package mcpproxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func getVersion(t *testing.T, p *MCPProxy) versionBody {
+	t.Helper()
+	w := httptest.NewRecorder()
+	p.newMux().ServeHTTP(w, httptest.NewRequest("GET", "/version", nil))
+	var body versionBody
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Invalid /version body %q: %v", w.Body.String(), err)
+	}
+	return body
+}
+
+func TestHandleVersion(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	defer func(version, commit string) { Version, Commit = version, commit }(Version, Commit)
+	Version, Commit = "1.2.3", "abc123"
+
+	proxy, err := NewMCPProxy(Config{
+		ServerName:  "test",
+		CommandPath: "sh",
+		CommandArgs: []string{"-c", `while read -r line; do echo '{"jsonrpc":"2.0","id":1,"result":{"protocolVersion":" 2025-03-26 ","serverInfo":{"name":"fake","version":"0.1"}}}'; done`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+
+	body := getVersion(t, proxy)
+	if body.Version != "1.2.3" || body.Commit != "abc123" || !strings.HasPrefix(body.GoVersion, "go") {
+		t.Errorf("Expected the build information, got %+v", body)
+	}
+	if body.Server != nil {
+		t.Errorf("Expected no server version before initialize, got %+v", body.Server)
+	}
+
+	w := httptest.NewRecorder()
+	proxy.Handle(w, httptest.NewRequest("POST", "/", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18"}}`)))
+
+	body = getVersion(t, proxy)
+	if body.Server == nil || body.Server.ProtocolVersion != "2025-03-26" || string(body.Server.ServerInfo) != `{"name":"fake","version":"0.1"}` {
+		t.Errorf("Expected the server's protocol version and info, got %+v", body.Server)
+	}
+	if !strings.Contains(buf.String(), `Client asked for MCP protocol version "2025-06-18", the MCP server answered with "2025-03-26"`) {
+		t.Errorf("Expected a warning about the version mismatch, got:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,60 @@
+package mcpproxy
+
+import (
+	"regexp"
+	"sync"
+	"time"
+)
+
+// warmup tracks whether an MCP server process has warmed up: it printed a line
+// matching Config.ReadyPattern on stderr, if set, and Config.StartupDelay has
+// passed since it was started. Until then readiness checks fail without
+// sending it anything.
+type warmup struct {
+	pattern *regexp.Regexp
+	seen    chan struct{} // closed once pattern matched or stderr closed
+	once    sync.Once
+	done    chan struct{} // closed once warmed up
+}
+
+// newWarmup starts tracking the warmup of a process about to be started.
+// cfg.ReadyPattern must already be validated by NewMCPProxy.
+func newWarmup(cfg Config, lg *logger) *warmup {
+	w := &warmup{
+		seen: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	if cfg.ReadyPattern != "" {
+		w.pattern = regexp.MustCompile(cfg.ReadyPattern)
+	} else {
+		close(w.seen)
+	}
+
+	start := time.Now()
+	go func() {
+		<-w.seen
+		if wait := cfg.StartupDelay - time.Since(start); wait > 0 {
+			time.Sleep(wait)
+		}
+		if cfg.ReadyPattern != "" || cfg.StartupDelay > 0 {
+			lg.infof("MCP server warmed up after %s", time.Since(start).Round(time.Millisecond))
+		}
+		close(w.done)
+	}()
+	return w
+}
+
+// observe checks a stderr line against the ready pattern.
+func (w *warmup) observe(line string) {
+	if w.pattern != nil && w.pattern.MatchString(line) {
+		w.once.Do(func() { close(w.seen) })
+	}
+}
+
+// stderrClosed stops waiting for the ready pattern once the process can no
+// longer print it.
+func (w *warmup) stderrClosed() {
+	if w.pattern != nil {
+		w.once.Do(func() { close(w.seen) })
+	}
+}
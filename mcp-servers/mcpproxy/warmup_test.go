@@ -0,0 +1,88 @@
+package mcpproxy
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func warmedUp(w *warmup) bool {
+	select {
+	case <-w.done:
+		return true
+	case <-time.After(50 * time.Millisecond):
+		return false
+	}
+}
+
+func TestWarmupPattern(t *testing.T) {
+	w := newWarmup(Config{ReadyPattern: `listening on \w+`}, newLogger("test", "warn"))
+	w.observe("loading configuration")
+	if warmedUp(w) {
+		t.Fatal("Expected no warmup before the ready line")
+	}
+	w.observe("listening on stdio")
+	w.observe("listening on stdio")
+	if !warmedUp(w) {
+		t.Error("Expected warmup after the ready line")
+	}
+
+	// A process that exits without printing it stops the wait
+	w = newWarmup(Config{ReadyPattern: "ready"}, newLogger("test", "warn"))
+	w.stderrClosed()
+	if !warmedUp(w) {
+		t.Error("Expected closing stderr to end the wait")
+	}
+}
+
+func TestWarmupStartupDelay(t *testing.T) {
+	start := time.Now()
+	w := newWarmup(Config{StartupDelay: 200 * time.Millisecond}, newLogger("test", "warn"))
+	<-w.done
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("Expected the startup delay to pass first, took %s", elapsed)
+	}
+
+	if w := newWarmup(Config{}, newLogger("test", "warn")); !warmedUp(w) {
+		t.Error("Expected no wait without a pattern or delay")
+	}
+}
+
+func TestReadyWaitsForStderrPattern(t *testing.T) {
+	// The server reads requests at once but only reports ready after a while
+	cfg := mcpServer
+	cfg.CommandArgs = []string{"-c", `(sleep 0.3; echo "server ready" >&2) &
+while read -r line; do
+	echo "got $line" >&2
+	case "$line" in *'"id"'*) ;; *) continue ;; esac
+	id=$(printf '%s' "$line" | sed -E 's/.*"id":("[^"]*"|[0-9]+).*/\1/')
+	case "$line" in
+	*'"tools/list"'*) echo "{\"jsonrpc\":\"2.0\",\"id\":$id,\"result\":{\"tools\":[]}}" ;;
+	*) echo "{\"jsonrpc\":\"2.0\",\"id\":$id,\"result\":{\"protocolVersion\":\"2025-03-26\"}}" ;;
+	esac
+done`}
+	cfg.ReadyPattern = "^server ready$"
+	proxy, err := NewMCPProxy(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.stop()
+
+	if code, body := getReady(t, proxy); code != http.StatusOK {
+		t.Fatalf("Expected 200 after the ready line, got %d %+v", code, body)
+	}
+	if lines := proxy.stderr.snapshot(); len(lines) == 0 || lines[0] != "server ready" {
+		t.Errorf("Expected no request before the ready line, got %q", lines)
+	} else if !strings.Contains(strings.Join(lines, "\n"), "initialize") {
+		t.Errorf("Expected initialize after the ready line, got %q", lines)
+	}
+}
+
+func TestInvalidReadyPattern(t *testing.T) {
+	cfg := mcpServer
+	cfg.ReadyPattern = "("
+	if _, err := NewMCPProxy(cfg); err == nil || !strings.Contains(err.Error(), "MCP_READY_PROBE") {
+		t.Errorf("Expected an error naming MCP_READY_PROBE, got %v", err)
+	}
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/rh-ai-kickstart/ai-architecture-charts/mcp-servers/mcpproxy"
+)
+
+// oracleErrorPattern matches Oracle, SQL*Plus and Oracle Net error codes
+// such as ORA-00942, SP2-0552 and TNS-12541. MCP_ORA_ERROR_PATTERN replaces it.
+var oracleErrorPattern = regexp.MustCompile(`\b(?:ORA|SP2|TNS|PLS)-\d{4,5}\b`)
+
+// oracleWarningPattern matches advisory messages that are reported without
+// failing the statement: PL/SQL compiler warnings and an expiring password.
+// Codes it matches are not treated as errors. MCP_ORA_WARNING_PATTERN replaces
+// it, and an empty value disables warnings.
+var oracleWarningPattern = regexp.MustCompile(`\b(?:PLW-\d{5}|ORA-28002)\b`)
+
+// oraHints are short recovery hints for frequent error codes, appended to the
+// error text when MCP_ORA_HINTS=true. MCP_ORA_HINTS_FILE can extend or override them.
+var oraHints = map[string]string{
+	"ORA-00001": "A unique constraint was violated; the row already exists.",
+	"ORA-00904": "A column name is invalid; check spelling and use DESCRIBE on the table.",
+	"ORA-00933": "The SQL statement is malformed; remove any trailing semicolon and check clause order.",
+	"ORA-00942": "The table or view does not exist or isn't visible; check the schema prefix and the user's privileges.",
+	"ORA-01017": "The database rejected the username or password; check the saved connection's credentials.",
+	"ORA-01031": "The connected user lacks the privilege for this operation.",
+	"ORA-12514": "The listener doesn't know the requested service; check the service name (e.g. FREEPDB1) and that the database is open.",
+	"ORA-12541": "No listener is reachable at the host and port; check that the database is running.",
+}
+
+// countOracleError counts flagged responses by their first error code.
+var countOracleError = mcpproxy.RegisterCounter("mcp_oracle_errors_total",
+	"Responses flagged with an Oracle error, by the first error code.", "code")
+
+// oracleError is the structured form of the first error code in a response,
+// so clients can branch on the code without scanning the text. Message is the
+// line the code was found on, unchanged.
+type oracleError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// oracleSettings are the error marking settings, read from the environment
+// once by newOracleSettings.
+type oracleSettings struct {
+	errorPattern   *regexp.Regexp
+	warningPattern *regexp.Regexp // nil disables warnings
+	hints          bool           // MCP_ORA_HINTS
+}
+
+// defaultOracleSettings returns the settings with the built-in patterns.
+func defaultOracleSettings() oracleSettings {
+	return oracleSettings{errorPattern: oracleErrorPattern, warningPattern: oracleWarningPattern}
+}
+
+// findOracleError returns the first error code in text that isn't a warning,
+// and the line it is on, or nil if there is none.
+func (s oracleSettings) findOracleError(text string) *oracleError {
+	for _, loc := range s.errorPattern.FindAllStringIndex(text, -1) {
+		code := text[loc[0]:loc[1]]
+		if s.isOracleWarning(code) {
+			continue
+		}
+		start := strings.LastIndexByte(text[:loc[0]], '\n') + 1
+		end := len(text)
+		if i := strings.IndexByte(text[loc[1]:], '\n'); i >= 0 {
+			end = loc[1] + i
+		}
+		return &oracleError{Code: code, Message: strings.TrimSpace(text[start:end])}
+	}
+	return nil
+}
+
+// isOracleWarning reports whether text contains a warning.
+func (s oracleSettings) isOracleWarning(text string) bool {
+	return s.warningPattern != nil && s.warningPattern.MatchString(text)
+}
+
+// newOracleSettings reads MCP_ORA_ERROR_PATTERN, MCP_ORA_WARNING_PATTERN and
+// MCP_ORA_HINTS over the built-in settings, adding invalid values to
+// cfg.Problems, and reports false when MCP_MARK_ORA_ERRORS=false turns marking off.
+func newOracleSettings(cfg *mcpproxy.Config) (oracleSettings, bool) {
+	s := defaultOracleSettings()
+	enabled := true
+	if v := os.Getenv("MCP_MARK_ORA_ERRORS"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			problem(cfg, fmt.Errorf("invalid MCP_MARK_ORA_ERRORS %q: not a boolean", v), "use true or false")
+		} else {
+			enabled = b
+		}
+	}
+	if v := os.Getenv("MCP_ORA_HINTS"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			problem(cfg, fmt.Errorf("invalid MCP_ORA_HINTS %q: not a boolean", v), "use true or false")
+		}
+		s.hints = b
+	}
+	if v := os.Getenv("MCP_ORA_ERROR_PATTERN"); v != "" {
+		re, err := regexp.Compile(v)
+		if err != nil {
+			problem(cfg, fmt.Errorf("invalid MCP_ORA_ERROR_PATTERN: %w", err), `use Go regular expression syntax, e.g. \bORA-\d{5}\b`)
+		} else {
+			s.errorPattern = re
+		}
+	}
+	if v, ok := os.LookupEnv("MCP_ORA_WARNING_PATTERN"); ok {
+		s.warningPattern = nil
+		if v != "" {
+			re, err := regexp.Compile(v)
+			if err != nil {
+				problem(cfg, fmt.Errorf("invalid MCP_ORA_WARNING_PATTERN: %w", err), `use Go regular expression syntax, e.g. \bPLW-\d{5}\b, or leave it empty`)
+			} else {
+				s.warningPattern = re
+			}
+		}
+	}
+	return s, enabled
+}
+
+// markOracleErrors returns a response middleware that flags tool results
+// whose text contains an Oracle error code with isError, since SQLcl reports
+// failed SQL as ordinary text, and adds the first code as
+// result._meta.oracleError. Results with a warning get result._meta.warning
+// instead, or as well. JSON-RPC errors mentioning a code get it as
+// error.data.oracleError. With MCP_ORA_HINTS=true a hint for the code is
+// appended to the text. Results already flagged isError, non-text content
+// and malformed responses are left unchanged.
+func markOracleErrors(s oracleSettings) mcpproxy.ResponseMiddleware {
+	return s.markErrors
+}
+
+func (s oracleSettings) markErrors(response []byte) []byte {
+	var resp map[string]json.RawMessage
+	if err := json.Unmarshal(response, &resp); err != nil {
+		return response
+	}
+	if resp["error"] != nil {
+		return s.markRPCError(response, resp)
+	}
+	if resp["result"] == nil {
+		return response
+	}
+	var result map[string]interface{}
+	if err := json.Unmarshal(resp["result"], &result); err != nil {
+		return response
+	}
+	if isErr, _ := result["isError"].(bool); isErr {
+		return response
+	}
+	content, _ := result["content"].([]interface{})
+
+	var found *oracleError
+	warning := false
+	for _, c := range content {
+		part, _ := c.(map[string]interface{})
+		text, ok := part["text"].(string)
+		if part["type"] != "text" || !ok {
+			continue
+		}
+		warning = warning || s.isOracleWarning(text)
+		if found != nil {
+			continue
+		}
+		if found = s.findOracleError(text); found != nil {
+			code := found.Code
+			if s.hints && oraHints[code] != "" {
+				part["text"] = fmt.Sprintf("%s\nHint (%s): %s", text, code, oraHints[code])
+			}
+		}
+	}
+	if found == nil && !warning {
+		return response
+	}
+	meta, _ := result["_meta"].(map[string]interface{})
+	if meta == nil {
+		meta = map[string]interface{}{}
+	}
+	if warning {
+		meta["warning"] = true
+	}
+	if found != nil {
+		meta["oracleError"] = found
+		result["isError"] = true
+		countOracleError(found.Code)
+	}
+	result["_meta"] = meta
+
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return response
+	}
+	resp["result"] = raw
+	out, err := json.Marshal(resp)
+	if err != nil {
+		return response
+	}
+	return out
+}
+
+// markRPCError adds the first error code in the message of the JSON-RPC error
+// in resp as error.data.oracleError. Errors whose data isn't an object are
+// left unchanged.
+func (s oracleSettings) markRPCError(response []byte, resp map[string]json.RawMessage) []byte {
+	var rpcErr map[string]interface{}
+	if err := json.Unmarshal(resp["error"], &rpcErr); err != nil {
+		return response
+	}
+	message, _ := rpcErr["message"].(string)
+	found := s.findOracleError(message)
+	if found == nil {
+		return response
+	}
+	data, isObject := rpcErr["data"].(map[string]interface{})
+	if rpcErr["data"] != nil && !isObject {
+		return response
+	}
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	data["oracleError"] = found
+	rpcErr["data"] = data
+	countOracleError(found.Code)
+
+	raw, err := json.Marshal(rpcErr)
+	if err != nil {
+		return response
+	}
+	resp["error"] = raw
+	out, err := json.Marshal(resp)
+	if err != nil {
+		return response
+	}
+	return out
+}
+
+// loadHints merges the hints in a JSON file ({"ORA-00942": "..."}) over the
+// built-in ones.
+func loadHints(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var hints map[string]string
+	if err := json.Unmarshal(data, &hints); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	for code, hint := range hints {
+		oraHints[code] = hint
+	}
+	return nil
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/rh-ai-kickstart/ai-architecture-charts/mcp-servers/mcpproxy"
+)
+
+func TestMarkOracleErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		in          string
+		wantIsError bool
+		unchanged   bool
+	}{
+		{
+			name:        "ORA code in text",
+			in:          `{"jsonrpc":"2.0","id":1,"result":{"content":[{"type":"text","text":"ORA-00942: table or view does not exist"}]}}`,
+			wantIsError: true,
+		},
+		{
+			name:        "SP2 code after other output",
+			in:          `{"jsonrpc":"2.0","id":1,"result":{"content":[{"type":"text","text":"ok"},{"type":"text","text":"SP2-0552: Bind variable not declared."}]}}`,
+			wantIsError: true,
+		},
+		{
+			name:        "TNS code",
+			in:          `{"jsonrpc":"2.0","id":1,"result":{"content":[{"type":"text","text":"TNS-12541: no listener"}]}}`,
+			wantIsError: true,
+		},
+		{
+			name:      "already isError",
+			in:        `{"jsonrpc":"2.0","id":1,"result":{"isError":true,"content":[{"type":"text","text":"ORA-00942: table or view does not exist"}]}}`,
+			unchanged: true,
+		},
+		{
+			name:      "non-text content",
+			in:        `{"jsonrpc":"2.0","id":1,"result":{"content":[{"type":"resource","text":"ORA-00942"}]}}`,
+			unchanged: true,
+		},
+		{
+			name:      "no error code",
+			in:        `{"jsonrpc":"2.0","id":1,"result":{"content":[{"type":"text","text":"FORA-123 and ORA-12 are not codes"}]}}`,
+			unchanged: true,
+		},
+		{
+			name:      "JSON-RPC error without a code",
+			in:        `{"jsonrpc":"2.0","id":1,"error":{"code":-32603,"message":"Internal error"}}`,
+			unchanged: true,
+		},
+		{
+			name:      "malformed JSON",
+			in:        `{"jsonrpc":"2.0","id":1,"result":{"content":[{"type":"text","text":"ORA-00942"`,
+			unchanged: true,
+		},
+		{
+			name:      "result is not an object",
+			in:        `{"jsonrpc":"2.0","id":1,"result":"ORA-00942"}`,
+			unchanged: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := markOracleErrors(defaultOracleSettings())([]byte(tt.in))
+			if tt.unchanged {
+				if string(got) != tt.in {
+					t.Errorf("Expected response unchanged, got %s", got)
+				}
+				return
+			}
+
+			var out struct {
+				ID     int `json:"id"`
+				Result struct {
+					IsError bool `json:"isError"`
+					Content []struct {
+						Text string `json:"text"`
+					} `json:"content"`
+				} `json:"result"`
+			}
+			if err := json.Unmarshal(got, &out); err != nil {
+				t.Fatalf("Expected valid JSON, got %s: %v", got, err)
+			}
+			if out.Result.IsError != tt.wantIsError {
+				t.Errorf("isError = %v, want %v", out.Result.IsError, tt.wantIsError)
+			}
+			if out.ID != 1 || len(out.Result.Content) == 0 {
+				t.Errorf("Expected the rest of the response to be kept, got %s", got)
+			}
+		})
+	}
+}
+
+func TestOracleErrorCode(t *testing.T) {
+	in := `{"jsonrpc":"2.0","id":1,"result":{"_meta":{"truncated":true},"content":[{"type":"text","text":"Error starting at line 1\nORA-06550: line 1, column 7:\nPLS-00201: identifier 'FOO' must be declared\nORA-06550: line 1, column 7"}]}}`
+	var out struct {
+		Result struct {
+			IsError bool `json:"isError"`
+			Meta    struct {
+				Truncated   bool        `json:"truncated"`
+				OracleError oracleError `json:"oracleError"`
+			} `json:"_meta"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(markOracleErrors(defaultOracleSettings())([]byte(in)), &out); err != nil {
+		t.Fatal(err)
+	}
+	want := oracleError{Code: "ORA-06550", Message: "ORA-06550: line 1, column 7:"}
+	if !out.Result.IsError || out.Result.Meta.OracleError != want {
+		t.Errorf("Expected isError and the first code %+v, got %+v", want, out.Result)
+	}
+	if !out.Result.Meta.Truncated {
+		t.Error("Expected existing _meta fields to be kept")
+	}
+}
+
+func TestOracleErrorCodeInRPCError(t *testing.T) {
+
+	in := `{"jsonrpc":"2.0","id":1,"error":{"code":-32603,"message":"TNS-12541: no listener, then ORA-12514"}}`
+	var out struct {
+		Error struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+			Data    struct {
+				OracleError oracleError `json:"oracleError"`
+			} `json:"data"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(markOracleErrors(defaultOracleSettings())([]byte(in)), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Error.Code != -32603 || out.Error.Message != "TNS-12541: no listener, then ORA-12514" {
+		t.Errorf("Expected the error to be kept, got %+v", out.Error)
+	}
+	if out.Error.Data.OracleError.Code != "TNS-12541" {
+		t.Errorf("Expected code TNS-12541 in error.data, got %+v", out.Error.Data)
+	}
+
+	in = `{"jsonrpc":"2.0","id":1,"error":{"code":-32603,"message":"ORA-00942","data":"details"}}`
+	if got := string(markOracleErrors(defaultOracleSettings())([]byte(in))); got != in {
+		t.Errorf("Expected an error with non-object data unchanged, got %s", got)
+	}
+}
+
+func TestOracleWarnings(t *testing.T) {
+
+	type meta struct {
+		Warning     bool         `json:"warning"`
+		OracleError *oracleError `json:"oracleError"`
+	}
+	tests := []struct {
+		name        string
+		text        string
+		wantIsError bool
+		wantCode    string
+	}{
+		{"warning only", "Procedure created.\nPLW-06009: procedure \"P\" OTHERS handler does not end in RAISE", false, ""},
+		{"advisory ORA code", "ORA-28002: the password will expire within 7 days\nConnected.", false, ""},
+		{"error and warning", "ORA-28002: the password will expire within 7 days\nORA-00942: table or view does not exist", true, "ORA-00942"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, _ := json.Marshal(tt.text)
+			in := `{"jsonrpc":"2.0","id":1,"result":{"content":[{"type":"text","text":` + string(text) + `}]}}`
+			var out struct {
+				Result struct {
+					IsError bool `json:"isError"`
+					Meta    meta `json:"_meta"`
+				} `json:"result"`
+			}
+			if err := json.Unmarshal(markOracleErrors(defaultOracleSettings())([]byte(in)), &out); err != nil {
+				t.Fatal(err)
+			}
+			if !out.Result.Meta.Warning {
+				t.Error("Expected _meta.warning to be set")
+			}
+			if out.Result.IsError != tt.wantIsError {
+				t.Errorf("isError = %v, want %v", out.Result.IsError, tt.wantIsError)
+			}
+			if got := out.Result.Meta.OracleError; (got == nil) != (tt.wantCode == "") || got != nil && got.Code != tt.wantCode {
+				t.Errorf("Expected code %q, got %+v", tt.wantCode, got)
+			}
+		})
+	}
+}
+
+func TestNewOracleSettings(t *testing.T) {
+	t.Setenv("MCP_ORA_ERROR_PATTERN", `\bERR-\d+\b`)
+	t.Setenv("MCP_ORA_WARNING_PATTERN", `\bWARN-\d+\b`)
+	t.Setenv("MCP_ORA_HINTS", "true")
+	var cfg mcpproxy.Config
+	s, mark := newOracleSettings(&cfg)
+	if !mark || len(cfg.Problems) != 0 {
+		t.Fatalf("Expected marking on without problems, got %v, %v", mark, cfg.Problems)
+	}
+	if !s.hints {
+		t.Error("Expected MCP_ORA_HINTS=true to enable hints")
+	}
+	if s.findOracleError("ERR-1: failed") == nil || s.findOracleError("ORA-00942") != nil {
+		t.Error("Expected MCP_ORA_ERROR_PATTERN to replace the error pattern")
+	}
+	if !s.isOracleWarning("WARN-2: careful") || s.isOracleWarning("PLW-06009") {
+		t.Error("Expected MCP_ORA_WARNING_PATTERN to replace the warning pattern")
+	}
+	if defaultOracleSettings().findOracleError("ERR-1: failed") != nil {
+		t.Error("Expected the built-in patterns to be left alone")
+	}
+
+	t.Setenv("MCP_ORA_WARNING_PATTERN", "")
+	if s, _ = newOracleSettings(&cfg); s.isOracleWarning("WARN-2: careful") {
+		t.Error("Expected an empty MCP_ORA_WARNING_PATTERN to disable warnings")
+	}
+
+	t.Setenv("MCP_MARK_ORA_ERRORS", "false")
+	if _, mark = newOracleSettings(&cfg); mark {
+		t.Error("Expected MCP_MARK_ORA_ERRORS=false to turn marking off")
+	}
+
+	t.Setenv("MCP_MARK_ORA_ERRORS", "maybe")
+	t.Setenv("MCP_ORA_ERROR_PATTERN", `(`)
+	t.Setenv("MCP_ORA_WARNING_PATTERN", `[`)
+	t.Setenv("MCP_ORA_HINTS", "sure")
+	cfg = mcpproxy.Config{}
+	newOracleSettings(&cfg)
+	if len(cfg.Problems) != 4 {
+		t.Errorf("Expected a problem for each invalid setting, got %v", cfg.Problems)
+	}
+}
+
+func TestOracleHints(t *testing.T) {
+	settings := defaultOracleSettings()
+	settings.hints = true
+
+	in := `{"jsonrpc":"2.0","id":1,"result":{"content":[{"type":"text","text":"ORA-00942: table or view does not exist"}]}}`
+	var out toolResult
+	if err := json.Unmarshal(markOracleErrors(settings)([]byte(in)), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	text := out.Result.Content[0].Text
+	if !strings.HasPrefix(text, "ORA-00942: table or view does not exist\n") {
+		t.Errorf("Expected original error text to be kept, got %q", text)
+	}
+	if !strings.Contains(text, "Hint (ORA-00942): "+oraHints["ORA-00942"]) {
+		t.Errorf("Expected hint to be appended, got %q", text)
+	}
+}
+
+func TestOracleHintsDisabled(t *testing.T) {
+	in := `{"jsonrpc":"2.0","id":1,"result":{"content":[{"type":"text","text":"ORA-01017: invalid username/password; logon denied"}]}}`
+	if got := string(markOracleErrors(defaultOracleSettings())([]byte(in))); strings.Contains(got, "Hint") {
+		t.Errorf("Expected no hint without MCP_ORA_HINTS, got %q", got)
+	}
+}
+
+func TestOracleHintsUnknownCode(t *testing.T) {
+	settings := defaultOracleSettings()
+	settings.hints = true
+
+	in := `{"jsonrpc":"2.0","id":1,"result":{"content":[{"type":"text","text":"ORA-99999: something odd"}]}}`
+	var out toolResult
+	json.Unmarshal(markOracleErrors(settings)([]byte(in)), &out)
+	if out.Result.Content[0].Text != "ORA-99999: something odd" {
+		t.Errorf("Expected text unchanged for a code without a hint, got %q", out.Result.Content[0].Text)
+	}
+}
+
+func TestLoadHints(t *testing.T) {
+	saved := oraHints["ORA-00942"]
+	defer func() {
+		oraHints["ORA-00942"] = saved
+		delete(oraHints, "ORA-20001")
+	}()
+
+	file := t.TempDir() + "/hints.json"
+	os.WriteFile(file, []byte(`{"ORA-00942":"Use the SALES schema.","ORA-20001":"Application error raised by a trigger."}`), 0o600)
+	if err := loadHints(file); err != nil {
+		t.Fatal(err)
+	}
+	if oraHints["ORA-00942"] != "Use the SALES schema." {
+		t.Errorf("Expected built-in hint to be overridden, got %q", oraHints["ORA-00942"])
+	}
+	if oraHints["ORA-20001"] == "" || oraHints["ORA-01017"] == "" {
+		t.Error("Expected file hints to be merged with built-in ones")
+	}
+
+	os.WriteFile(file, []byte(`not json`), 0o600)
+	if err := loadHints(file); err == nil {
+		t.Error("Expected error for an invalid hints file")
+	}
+}
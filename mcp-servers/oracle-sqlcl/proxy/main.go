@@ -1,18 +1,66 @@
 package main
 
 import (
+	"fmt"
 	"log"
+	"os"
+	"strconv"
 
 	"github.com/rh-ai-kickstart/ai-architecture-charts/mcp-servers/mcpproxy"
 )
 
 func main() {
-	if err := mcpproxy.Run(mcpproxy.Config{
+	if err := mcpproxy.Run(newConfig()); err != nil {
+		log.Fatalf("Failed to run proxy: %v", err)
+	}
+}
+
+// newConfig builds the proxy configuration from the SQLcl-specific
+// environment. Invalid settings are added to cfg.Problems, so mcpproxy
+// reports them together with its own.
+func newConfig() mcpproxy.Config {
+	cfg := mcpproxy.Config{
 		ServerName:  "sqlcl",
 		CommandPath: "/opt/oracle/sqlcl/bin/sql",
 		CommandArgs: []string{"-mcp"},
-		PathEnvVar:  "SQL_PATH",
-	}); err != nil {
-		log.Fatalf("Failed to run proxy: %v", err)
+		PathEnvVar:  sqlclPathVar(),
+
+		LogRedactor: redactCredentials,
+	}
+
+	// Keep huge result sets from flooding the client's context
+	if v := os.Getenv("MCP_MAX_RESULT_BYTES"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			problem(&cfg, fmt.Errorf("invalid MCP_MAX_RESULT_BYTES %q: must be a positive integer", v), "set it to a size in bytes, e.g. 65536, or unset it")
+		} else {
+			cfg.ResponseMiddlewares = append(cfg.ResponseMiddlewares, truncateResults(limit))
+		}
 	}
+
+	// Flag SQL failures reported as plain text, after truncation so hints aren't cut
+	settings, mark := newOracleSettings(&cfg)
+	if path := os.Getenv("MCP_ORA_HINTS_FILE"); path != "" {
+		if err := loadHints(path); err != nil {
+			problem(&cfg, fmt.Errorf("failed to load MCP_ORA_HINTS_FILE: %w", err), `mount a JSON object of codes and hints, e.g. {"ORA-00942": "..."}`)
+		}
+	}
+	if mark {
+		cfg.ResponseMiddlewares = append(cfg.ResponseMiddlewares, markOracleErrors(settings))
+	}
+	return cfg
+}
+
+// sqlclPathVar names the variable that overrides the SQLcl command:
+// SQLCL_PATH, or SQL_PATH, its older name, when only that is set.
+func sqlclPathVar() string {
+	if os.Getenv("SQLCL_PATH") == "" && os.Getenv("SQL_PATH") != "" {
+		return "SQL_PATH"
+	}
+	return "SQLCL_PATH"
+}
+
+// problem adds a configuration problem to cfg.Problems.
+func problem(cfg *mcpproxy.Config, err error, hint string) {
+	cfg.Problems = append(cfg.Problems, mcpproxy.Problem{Err: err, Hint: hint})
 }
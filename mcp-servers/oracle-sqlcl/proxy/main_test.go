@@ -0,0 +1,34 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rh-ai-kickstart/ai-architecture-charts/mcp-servers/mcpproxy"
+)
+
+func TestSQLclPathVar(t *testing.T) {
+	for _, tc := range []struct {
+		sqlclPath, sqlPath, want string
+	}{
+		{"", "", "SQLCL_PATH"},
+		{"/usr/local/bin/sql", "", "SQLCL_PATH"},
+		{"", "/usr/local/bin/sql", "SQL_PATH"},
+		{"/usr/local/bin/sql", "/opt/sql", "SQLCL_PATH"},
+	} {
+		t.Setenv("SQLCL_PATH", tc.sqlclPath)
+		t.Setenv("SQL_PATH", tc.sqlPath)
+		if got := newConfig().PathEnvVar; got != tc.want {
+			t.Errorf("SQLCL_PATH=%q SQL_PATH=%q: PathEnvVar = %q, want %q", tc.sqlclPath, tc.sqlPath, got, tc.want)
+		}
+	}
+}
+
+func TestSQLclPathHint(t *testing.T) {
+	t.Setenv("SQLCL_PATH", "/no/such/sql")
+	t.Setenv("SQL_PATH", "")
+	_, err := mcpproxy.NewMCPProxy(newConfig())
+	if err == nil || !strings.Contains(err.Error(), "set SQLCL_PATH or MCP_COMMAND") {
+		t.Errorf("Expected a hint naming SQLCL_PATH, got %v", err)
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import "regexp"
+
+// Credentials can appear in free text that key-based redaction can't see,
+// e.g. SQL passed to run-sql or connect strings in SQLcl output.
+var credentialPatterns = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	// CREATE/ALTER USER ... IDENTIFIED BY secret
+	{regexp.MustCompile(`(?i)(identified\s+by\s+)(\\?"[^"\\]*\\?"|[^\s"\\;]+)`), `${1}***`},
+	// password=secret, pwd=secret in connect descriptors and URLs
+	{regexp.MustCompile(`(?i)((?:password|pwd)\s*=\s*)([^\s;,&"\\)]+)`), `${1}***`},
+	// user/secret@host:port/service connect strings
+	{regexp.MustCompile(`([\w$#]+/)([^\s@/"\\]+)(@)`), `${1}***${3}`},
+}
+
+// redactCredentials masks Oracle passwords in text destined for the logs.
+func redactCredentials(s string) string {
+	for _, p := range credentialPatterns {
+		s = p.re.ReplaceAllString(s, p.repl)
+	}
+	return s
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/rh-ai-kickstart/ai-architecture-charts/mcp-servers/mcpproxy"
+)
+
+func TestRedactCredentials(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"identified by", "CREATE USER scott IDENTIFIED BY tiger", "CREATE USER scott IDENTIFIED BY ***"},
+		{"identified by quoted", `ALTER USER scott identified by \"ti ger\" ACCOUNT UNLOCK`, `ALTER USER scott identified by *** ACCOUNT UNLOCK`},
+		{"password param", "jdbc:oracle:thin:@db?user=scott&password=tiger&ssl=true", "jdbc:oracle:thin:@db?user=scott&password=***&ssl=true"},
+		{"connect string", "connect scott/tiger@oracle:1521/FREEPDB1", "connect scott/***@oracle:1521/FREEPDB1"},
+		{"no credentials", "SELECT * FROM customers", "SELECT * FROM customers"},
+		{"path without at sign", "/opt/oracle/sqlcl/bin/sql", "/opt/oracle/sqlcl/bin/sql"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := redactCredentials(tt.in); got != tt.want {
+				t.Errorf("redactCredentials(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPasswordNeverLogged(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	// cat echoes each request back as its response, so the password
+	// appears in both the request and response payload logs
+	proxy, err := mcpproxy.NewMCPProxy(mcpproxy.Config{
+		ServerName:  "sqlcl",
+		CommandPath: "cat",
+		LogLevel:    "debug",
+		LogPayloads: true,
+		LogRedactor: redactCredentials,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, body := range []string{
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"run-sql","arguments":{"sql":"CREATE USER app IDENTIFIED BY Sup3rSecret"}}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"connect","arguments":{"connection":"app/Sup3rSecret@oracle:1521/FREEPDB1"}}}`,
+	} {
+		w := httptest.NewRecorder()
+		proxy.Handle(w, httptest.NewRequest("POST", "/", strings.NewReader(body)))
+		if w.Code != 200 {
+			t.Fatalf("Unexpected status %d: %s", w.Code, w.Body.String())
+		}
+	}
+
+	if !strings.Contains(buf.String(), "run-sql") {
+		t.Fatalf("Expected payloads in debug logs, got:\n%s", buf.String())
+	}
+	if strings.Contains(buf.String(), "Sup3rSecret") {
+		t.Errorf("Password leaked into logs:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+)
+
+// truncateResults returns a response middleware that limits the text content of
+// tool results to limit bytes in total. Text beyond the limit is cut and a
+// "[truncated N bytes]" marker appended, and result._meta.truncated is set.
+// Non-text content parts and responses without a content array are left intact.
+func truncateResults(limit int) func([]byte) []byte {
+	return func(response []byte) []byte {
+		var resp map[string]json.RawMessage
+		if err := json.Unmarshal(response, &resp); err != nil || resp["result"] == nil {
+			return response
+		}
+		var result map[string]json.RawMessage
+		if err := json.Unmarshal(resp["result"], &result); err != nil || result["content"] == nil {
+			return response
+		}
+		var content []map[string]interface{}
+		if err := json.Unmarshal(result["content"], &content); err != nil {
+			return response
+		}
+
+		remaining := limit
+		truncated := false
+		for _, part := range content {
+			text, ok := part["text"].(string)
+			if part["type"] != "text" || !ok {
+				continue
+			}
+			if len(text) <= remaining {
+				remaining -= len(text)
+				continue
+			}
+
+			// Cut on a rune boundary so the JSON stays valid UTF-8
+			cut := remaining
+			for cut > 0 && !utf8.RuneStart(text[cut]) {
+				cut--
+			}
+			part["text"] = fmt.Sprintf("%s\n[truncated %d bytes]", text[:cut], len(text)-cut)
+			remaining = 0
+			truncated = true
+		}
+		if !truncated {
+			return response
+		}
+
+		var meta map[string]interface{}
+		json.Unmarshal(result["_meta"], &meta)
+		if meta == nil {
+			meta = map[string]interface{}{}
+		}
+		meta["truncated"] = true
+
+		var err error
+		if result["content"], err = json.Marshal(content); err != nil {
+			return response
+		}
+		if result["_meta"], err = json.Marshal(meta); err != nil {
+			return response
+		}
+		if resp["result"], err = json.Marshal(result); err != nil {
+			return response
+		}
+		out, err := json.Marshal(resp)
+		if err != nil {
+			return response
+		}
+		return out
+	}
+}
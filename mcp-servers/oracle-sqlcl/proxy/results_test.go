@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/rh-ai-kickstart/ai-architecture-charts/mcp-servers/mcpproxy"
+)
+
+type toolResult struct {
+	Result struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+			Data string `json:"data"`
+		} `json:"content"`
+		Meta struct {
+			Truncated bool `json:"truncated"`
+		} `json:"_meta"`
+	} `json:"result"`
+}
+
+func TestTruncateResultsUnderLimit(t *testing.T) {
+	in := `{"jsonrpc":"2.0","id":1,"result":{"content":[{"type":"text","text":"ID,NAME\n1,SMITH"}]}}`
+	if got := string(truncateResults(100)([]byte(in))); got != in {
+		t.Errorf("Expected response under the limit to be unchanged, got %q", got)
+	}
+}
+
+func TestTruncateResultsOverLimit(t *testing.T) {
+	rows := strings.Repeat("1,SMITH\n", 100)
+	in, _ := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"result": map[string]interface{}{
+			"content": []map[string]string{{"type": "text", "text": rows}},
+		},
+	})
+
+	var out toolResult
+	if err := json.Unmarshal(truncateResults(80)(in), &out); err != nil {
+		t.Fatal(err)
+	}
+	text := out.Result.Content[0].Text
+	if !strings.HasPrefix(text, rows[:80]) {
+		t.Errorf("Expected first 80 bytes to be kept, got %q", text)
+	}
+	if !strings.HasSuffix(text, "[truncated 720 bytes]") {
+		t.Errorf("Expected truncation marker, got %q", text)
+	}
+	if !out.Result.Meta.Truncated {
+		t.Error("Expected _meta.truncated to be set")
+	}
+}
+
+func TestTruncateResultsSharedBudget(t *testing.T) {
+	in := `{"jsonrpc":"2.0","id":1,"result":{"content":[{"type":"text","text":"aaaaa"},{"type":"text","text":"bbbbb"}]}}`
+
+	var out toolResult
+	if err := json.Unmarshal(truncateResults(7)([]byte(in)), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Result.Content[0].Text != "aaaaa" {
+		t.Errorf("Expected first part intact, got %q", out.Result.Content[0].Text)
+	}
+	if out.Result.Content[1].Text != "bb\n[truncated 3 bytes]" {
+		t.Errorf("Expected second part cut to the remaining budget, got %q", out.Result.Content[1].Text)
+	}
+}
+
+func TestTruncateResultsRuneBoundary(t *testing.T) {
+	in := `{"jsonrpc":"2.0","id":1,"result":{"content":[{"type":"text","text":"ééé"}]}}`
+
+	var out toolResult
+	if err := json.Unmarshal(truncateResults(3)([]byte(in)), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Result.Content[0].Text != "é\n[truncated 4 bytes]" {
+		t.Errorf("Expected cut on a rune boundary, got %q", out.Result.Content[0].Text)
+	}
+}
+
+func TestTruncateResultsNonText(t *testing.T) {
+	for _, in := range []string{
+		`{"jsonrpc":"2.0","id":1,"result":{"content":[{"type":"image","data":"` + strings.Repeat("A", 500) + `","mimeType":"image/png"}]}}`,
+		`{"jsonrpc":"2.0","id":1,"result":{"tools":[{"name":"run-sql","description":"` + strings.Repeat("x", 500) + `"}]}}`,
+		`{"jsonrpc":"2.0","id":1,"error":{"code":-32603,"message":"` + strings.Repeat("x", 500) + `"}}`,
+		`not json`,
+	} {
+		if got := string(truncateResults(10)([]byte(in))); got != in {
+			t.Errorf("Expected %.40q... to be unchanged", in)
+		}
+	}
+}
+
+// applyResponseMiddlewares runs response through cfg's middlewares as the
+// proxy does.
+func applyResponseMiddlewares(cfg mcpproxy.Config, response []byte) []byte {
+	for _, mw := range cfg.ResponseMiddlewares {
+		response = mw(response)
+	}
+	return response
+}
+
+func TestNewConfigMaxResultBytes(t *testing.T) {
+	long := `{"jsonrpc":"2.0","id":1,"result":{"content":[{"type":"text","text":"` + strings.Repeat("x", 100) + `"}]}}`
+
+	t.Setenv("MCP_MAX_RESULT_BYTES", "")
+	cfg := newConfig()
+	if got := string(applyResponseMiddlewares(cfg, []byte(long))); got != long {
+		t.Errorf("Expected no truncation when unset, got %q", got)
+	}
+
+	t.Setenv("MCP_MAX_RESULT_BYTES", "10")
+	cfg = newConfig()
+	if got := string(applyResponseMiddlewares(cfg, []byte(long))); !strings.Contains(got, "[truncated 90 bytes]") {
+		t.Errorf("Expected truncation, got %q", got)
+	}
+
+	t.Setenv("MCP_MAX_RESULT_BYTES", "lots")
+	if cfg = newConfig(); len(cfg.Problems) != 1 {
+		t.Errorf("Expected a problem with an invalid limit, got %v", cfg.Problems)
+	}
+}